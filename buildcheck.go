@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// checkBuild parses the generated files, keyed by file name, as one package and
+// returns an error listing any syntax errors, identifiers that aren't declared
+// in the package, its imports or the universe, and names declared more than
+// once. It's a cheap stand-in for compiling the output that catches most
+// generator bugs without needing a Go toolchain.
+func checkBuild(files map[string][]byte) error {
+	fset := token.NewFileSet()
+
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	var problems []string
+	var parsed []*ast.File
+	declared := make(map[string]token.Pos)
+	for _, fileName := range fileNames {
+		f, err := parser.ParseFile(fset, fileName, files[fileName], 0)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		parsed = append(parsed, f)
+
+		for _, obj := range f.Scope.Objects {
+			ident, ok := declIdent(obj)
+			if !ok {
+				continue
+			}
+			if prev, ok := declared[obj.Name]; ok {
+				problems = append(problems, fmt.Sprintf("%s: %s redeclared (previous declaration at %s)", fset.Position(ident.Pos()), obj.Name, fset.Position(prev)))
+				continue
+			}
+			declared[obj.Name] = ident.Pos()
+		}
+	}
+
+	for _, f := range parsed {
+		imported := make(map[string]bool, len(f.Imports))
+		for _, imp := range f.Imports {
+			if imp.Name != nil {
+				imported[imp.Name.Name] = true
+				continue
+			}
+			importPath, _ := strconv.Unquote(imp.Path.Value)
+			imported[path.Base(importPath)] = true
+		}
+
+		for _, ident := range f.Unresolved {
+			if _, ok := declared[ident.Name]; ok || imported[ident.Name] || gotypes.Universe.Lookup(ident.Name) != nil {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s: undefined: %s", fset.Position(ident.Pos()), ident.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// declIdent returns the identifier declaring obj.
+func declIdent(obj *ast.Object) (*ast.Ident, bool) {
+	switch decl := obj.Decl.(type) {
+	case *ast.TypeSpec:
+		return decl.Name, true
+	case *ast.FuncDecl:
+		return decl.Name, true
+	case *ast.ValueSpec:
+		for _, name := range decl.Names {
+			if name.Name == obj.Name {
+				return name, true
+			}
+		}
+	}
+	return nil, false
+}
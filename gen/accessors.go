@@ -0,0 +1,56 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+const (
+	accessorsNone    = "none"
+	accessorsZero    = "zero"
+	accessorsCommaOK = "commaok"
+)
+
+// printAccessors writes a getter for each pointer field of gt. With the zero
+// style the getter returns the zero value for an unset field; with the commaok
+// style it also returns whether the field was set.
+func (gt goType) printAccessors(buf *bytes.Buffer, style string) {
+	if gt.TypePrefix != typeStruct || style == accessorsNone {
+		return
+	}
+
+	fieldNames := make(map[string]bool, len(gt.Fields))
+	for _, sf := range gt.Fields {
+		fieldNames[sf.Name] = true
+	}
+
+	sort.Stable(gt.Fields)
+	for _, sf := range gt.Fields {
+		typeStr, isPtr := sf.typeString()
+		if sf.Embedded || !isPtr {
+			continue
+		}
+		valueType := typeStr[1:]
+
+		getter := "Get" + sf.Name
+		if fieldNames[getter] {
+			continue
+		}
+
+		buf.WriteString("\n")
+		if style == accessorsCommaOK {
+			buf.WriteString(fmt.Sprintf("// %s returns the value of %s and whether it is set.\n", getter, sf.Name))
+			buf.WriteString(fmt.Sprintf("func (t %s) %s() (%s, bool) {\n", gt.Name, getter, valueType))
+			buf.WriteString(fmt.Sprintf("if t.%s == nil {\n", sf.Name))
+			buf.WriteString(fmt.Sprintf("var zero %s\nreturn zero, false\n}\n", valueType))
+			buf.WriteString(fmt.Sprintf("return *t.%s, true\n}\n", sf.Name))
+		} else {
+			buf.WriteString(fmt.Sprintf("// %s returns the value of %s, or the zero value if it isn't set.\n", getter, sf.Name))
+			buf.WriteString(fmt.Sprintf("func (t %s) %s() %s {\n", gt.Name, getter, valueType))
+			buf.WriteString(fmt.Sprintf("if t.%s == nil {\n", sf.Name))
+			buf.WriteString(fmt.Sprintf("var zero %s\nreturn zero\n}\n", valueType))
+			buf.WriteString(fmt.Sprintf("return *t.%s\n}\n", sf.Name))
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// rootBaseURI is the base URI of a document whose root schema has no $id.
+// Refs resolved against it that don't name another schema's $id stay local.
+const rootBaseURI = "schematyper:///root.json"
+
+// Keywords whose values are subschemas, a list of them or a map of them.
+var (
+	subschemaKeywords = []string{
+		"items", "additionalItems", "additionalProperties", "not", "if", "then", "else",
+		"contains", "propertyNames", "unevaluatedItems", "unevaluatedProperties", "contentSchema",
+	}
+	subschemaListKeywords = []string{"items", "prefixItems", "allOf", "anyOf", "oneOf"}
+	subschemaMapKeywords  = []string{"definitions", "$defs", "properties", "patternProperties", "dependentSchemas", "dependencies"}
+)
+
+// walkSchemas calls visit for node, a schema at path, and for each schema
+// nested in it, with the base URI of each. A schema's $id, or id before
+// draft-06, is resolved against the base URI of the schema around it and is
+// the base URI of it and the schemas in it.
+func walkSchemas(node interface{}, path string, base *url.URL, visit func(schema map[string]interface{}, path string, base *url.URL)) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	id, ok := schema["$id"].(string)
+	if !ok {
+		id, _ = schema["id"].(string)
+	}
+	// an id that's only a fragment names the schema rather than setting a base
+	if id != "" && !strings.HasPrefix(id, "#") {
+		if idURL, err := base.Parse(id); err == nil {
+			idURL.Fragment = ""
+			base = idURL
+		}
+	}
+	visit(schema, path, base)
+
+	for _, keyword := range subschemaKeywords {
+		walkSchemas(schema[keyword], path+"/"+keyword, base, visit)
+	}
+	for _, keyword := range subschemaListKeywords {
+		if list, ok := schema[keyword].([]interface{}); ok {
+			for i, child := range list {
+				walkSchemas(child, path+"/"+keyword+"/"+strconv.Itoa(i), base, visit)
+			}
+		}
+	}
+	for _, keyword := range subschemaMapKeywords {
+		if children, ok := schema[keyword].(map[string]interface{}); ok {
+			for name, child := range children {
+				walkSchemas(child, path+"/"+keyword+"/"+name, base, visit)
+			}
+		}
+	}
+}
+
+// schemaAnchor returns the plain name that schema can be referred to by, as
+// the fragment of a ref: its $anchor, or an $id (id before draft-06) that's
+// only a fragment.
+func schemaAnchor(schema map[string]interface{}) string {
+	if anchor, ok := schema["$anchor"].(string); ok {
+		return anchor
+	}
+	id, ok := schema["$id"].(string)
+	if !ok {
+		id, _ = schema["id"].(string)
+	}
+	if strings.HasPrefix(id, "#") {
+		return id[1:]
+	}
+	return ""
+}
+
+// resolveBaseURIs rewrites each $ref in doc that, resolved against the base
+// URI of the schema it's in, points into a schema with an $id in doc, or to a
+// schema with an $anchor, to the path of the schema it points to, like the
+// refs that point into the root. This is how refs in bundled schemas, which
+// are relative to the $id of the schema they were bundled from, are followed.
+// Other refs are left as they are.
+func resolveBaseURIs(doc interface{}) {
+	root, _ := url.Parse(rootBaseURI)
+
+	idPaths := make(map[string]string)
+	anchorPaths := make(map[string]string)
+	walkSchemas(doc, "#", root, func(schema map[string]interface{}, path string, base *url.URL) {
+		if _, ok := idPaths[base.String()]; !ok {
+			idPaths[base.String()] = path
+		}
+		if anchor := schemaAnchor(schema); anchor != "" {
+			anchorPaths[base.String()+"#"+anchor] = path
+		}
+	})
+
+	walkSchemas(doc, "#", root, func(schema map[string]interface{}, path string, base *url.URL) {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return
+		}
+		refURL, err := base.Parse(ref)
+		if err != nil {
+			return
+		}
+		if anchorPath, ok := anchorPaths[refURL.String()]; ok {
+			schema["$ref"] = anchorPath
+			return
+		}
+		fragment := refURL.Fragment
+		refURL.Fragment = ""
+		idPath, ok := idPaths[refURL.String()]
+		if !ok || (fragment != "" && !strings.HasPrefix(fragment, "/")) {
+			return
+		}
+		schema["$ref"] = idPath + fragment
+	})
+}
+
+// decodeSchema parses the schema document data, with its refs resolved by
+// resolveBaseURIs, the schemas in other files they refer to bundled by
+// bundleExternalRefs, draft-03 required flags hoisted by hoistBooleanRequired,
+// 2020-12 prefixItems moved by movePrefixItems, the property dependencies of
+// draft-04 split off by splitDependencies and anyOf and oneOf enums merged by
+// mergeEnumBranches, into s and returns the document as well.
+func decodeSchema(data []byte, s *metaSchema) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	resolveBaseURIs(doc)
+	if err := bundleExternalRefs(doc); err != nil {
+		return nil, err
+	}
+	hoistBooleanRequired(doc)
+	movePrefixItems(doc)
+	splitDependencies(doc)
+	mergeEnumBranches(doc)
+
+	resolved, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(resolved, s); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
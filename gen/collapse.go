@@ -0,0 +1,80 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// collapseWrapper turns gt, if it's a struct with a single property, into a
+// type of that property's value. The wrapping object is still used in JSON:
+// printWrapperJSON writes the methods that add and remove it. It returns false
+// if gt can't be collapsed, which a union or tuple of one item can't: it has
+// JSON methods of its own.
+func (gt *goType) collapseWrapper() bool {
+	if gt.TypePrefix != typeStruct || len(gt.Fields) != 1 || gt.union != "" || gt.tuple {
+		return false
+	}
+	sf := gt.Fields[0]
+	if sf.Embedded || sf.Recursive || sf.FixedLen > 0 || sf.Variant || sf.Position > 0 {
+		return false
+	}
+
+	// methods can't be declared on an interface type
+	underlying := sf.TypePrefix
+	for ref := sf.TypeRef; underlying == ""; ref = types[ref].TypeRef {
+		refType, ok := types[ref]
+		if !ok {
+			return false
+		}
+		underlying = refType.TypePrefix
+	}
+	if underlying == typeEmptyInterface {
+		return false
+	}
+
+	gt.TypePrefix, gt.TypeRef = sf.TypePrefix, sf.TypeRef
+	gt.constraints = sf.constraints
+	gt.wrappedField = &sf
+	gt.Fields = nil
+
+	comment := fmt.Sprintf("%s is the value of the %q property of a JSON object.", gt.Name, sf.PropertyName)
+	if gt.Comment != "" {
+		comment = gt.Comment + "\n\n" + comment
+	}
+	gt.Comment = comment
+	return true
+}
+
+// printWrapperJSON writes the methods that marshal a collapsed wrapper as the
+// object it was collapsed from.
+func (gt goType) printWrapperJSON(buf *bytes.Buffer, imports stringset.StringSet) {
+	sf := gt.wrappedField
+	if sf == nil {
+		return
+	}
+
+	valueType := sf.TypePrefix
+	if refType, ok := types[sf.TypeRef]; ok {
+		valueType += refType.Name
+	}
+	var omitOption string
+	if sf.omitsEmpty() {
+		omitOption = "omitempty"
+	}
+	tag := "`" + sf.tag(omitOption) + "`"
+	wrapper := fmt.Sprintf("struct {\n%s %s %s\n}", sf.Name, valueType, tag)
+
+	imports.Add("encoding/json")
+	buf.WriteString("\n")
+	buf.WriteString(fmt.Sprintf("func (t %s) MarshalJSON() ([]byte, error) {\n", gt.Name))
+	buf.WriteString(fmt.Sprintf("return json.Marshal(%s{%s(t)})\n", wrapper, valueType))
+	buf.WriteString("}\n\n")
+	buf.WriteString(fmt.Sprintf("func (t *%s) UnmarshalJSON(data []byte) error {\n", gt.Name))
+	buf.WriteString(fmt.Sprintf("var v %s\n", wrapper))
+	buf.WriteString("if err := json.Unmarshal(data, &v); err != nil {\nreturn err\n}\n")
+	buf.WriteString(fmt.Sprintf("*t = %s(v.%s)\n", gt.Name, sf.Name))
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+}
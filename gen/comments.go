@@ -0,0 +1,52 @@
+package gen
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// sidecarComment is a description for a schema path from a --comments-from
+// file. In the file it can be given as just the description string, or as an
+// object that can also override a description from the schema.
+type sidecarComment struct {
+	Description string `json:"description"`
+	Override    bool   `json:"override"`
+}
+
+func (c *sidecarComment) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.Description); err == nil {
+		return nil
+	}
+
+	type plainComment sidecarComment
+	return json.Unmarshal(b, (*plainComment)(c))
+}
+
+var sidecarComments = make(map[string]sidecarComment)
+
+// loadComments parses a --comments-from file, which maps schema paths (JSON
+// pointers, with or without the leading "#") to descriptions.
+func loadComments(data []byte) (map[string]sidecarComment, error) {
+	var raw map[string]sidecarComment
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]sidecarComment, len(raw))
+	for path, comment := range raw {
+		if !strings.HasPrefix(path, "#") {
+			path = "#" + path
+		}
+		comments[path] = comment
+	}
+	return comments, nil
+}
+
+// applySidecarComment returns the comment for the type at path, taking the
+// sidecar description if the schema has none or if it's marked as an override.
+func applySidecarComment(comment, path string) string {
+	if c, ok := sidecarComments[path]; ok && (comment == "" || c.Override) {
+		return c.Description
+	}
+	return comment
+}
@@ -0,0 +1,387 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// conditional holds what the Validate method of a struct checks of its
+// schema's if/then/else: the properties that the if schema requires to be set
+// or to have a value, and those that the then and else schemas require or
+// constrain.
+type conditional struct {
+	ifFields []conditionField
+	then     []conditionField
+	els      []conditionField
+}
+
+// conditionField is a check of the property held by the struct field named
+// field.
+type conditionField struct {
+	field        string
+	propertyName string
+	// value is the value that the if schema requires the property to have, if
+	// hasValue is set
+	value       interface{}
+	hasValue    bool
+	required    bool
+	constraints constraints
+}
+
+// addBranchProperties adds to props the properties of the then and else
+// schemas of s that it doesn't define itself, so the struct has a field for
+// every property it can have whichever of them applies, and returns props and
+// the paths of the properties it added. The first branch that defines a
+// property gives its type.
+func addBranchProperties(s *metaSchema, props map[string]*metaSchema, path string) (map[string]*metaSchema, map[string]string) {
+	if s.If == nil {
+		return props, nil
+	}
+	defined := definedProperties(s)
+	paths := make(map[string]string)
+	branches := []struct {
+		keyword string
+		schema  *metaSchema
+	}{{"then", s.Then}, {"else", s.Else}}
+	for _, branch := range branches {
+		if branch.schema == nil {
+			continue
+		}
+		names, _ := stringset.FromMapKeys(branch.schema.Properties)
+		for _, name := range names.Sorted() {
+			if _, ok := paths[name]; ok || defined.Has(name) {
+				continue
+			}
+			if props == nil {
+				props = make(map[string]*metaSchema)
+			}
+			propSchema := branch.schema.Properties[name]
+			props[name] = &propSchema
+			paths[name] = path + "/" + branch.keyword + "/properties/" + name
+		}
+	}
+	return props, paths
+}
+
+// conditionValue returns the value that s, a property of an if schema,
+// requires, with const or an enum of one value, if that's all it does.
+func conditionValue(s metaSchema) (interface{}, bool) {
+	value := s.Const
+	if value == nil && len(s.Enum) == 1 {
+		value = s.Enum[0]
+	}
+	s.Const, s.Enum, s.Type, s.Title, s.Description = nil, nil, nil, "", ""
+	if value == nil || !isEmptyMetaSchema(&s) {
+		return nil, false
+	}
+	return value, true
+}
+
+// fieldByProperty returns the struct field in fields holding the property
+// name.
+func fieldByProperty(fields structFields, name string) (structField, bool) {
+	for _, sf := range fields {
+		if !sf.Embedded && sf.PropertyName == name {
+			return sf, true
+		}
+	}
+	return structField{}, false
+}
+
+// branchFields returns the checks of branch, the then or else schema of a
+// struct with the given fields: the properties it requires, and the
+// constraints of its properties.
+func branchFields(branch *metaSchema, fields structFields) ([]conditionField, error) {
+	if branch == nil {
+		return nil, nil
+	}
+	checked, _ := stringset.FromMapKeys(branch.Properties)
+	for _, req := range branch.Required {
+		checked.Add(string(req))
+	}
+
+	var checks []conditionField
+	for _, name := range checked.Sorted() {
+		sf, ok := fieldByProperty(fields, name)
+		if !ok {
+			return nil, fmt.Errorf("%q isn't a property", name)
+		}
+		check := conditionField{field: sf.Name, propertyName: name}
+		for _, req := range branch.Required {
+			if string(req) == name {
+				if _, ok := sf.absentCheck(); !ok {
+					return nil, fmt.Errorf("whether %q is set can't be told", name)
+				}
+				check.required = true
+			}
+		}
+		if propSchema, ok := branch.Properties[name]; ok {
+			check.constraints = getConstraints(&propSchema)
+		}
+		if check.required || check.constraints.appliesTo(sf.TypePrefix) {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// getConditional returns the checks of the if/then/else of s, the schema of
+// a struct with the given fields. Only an if schema that requires properties
+// to be set, or to have a const value, can be checked; another one is
+// ignored, with a warning.
+func getConditional(s *metaSchema, fields structFields, path string) *conditional {
+	c, err := parseConditional(s, fields)
+	if err != nil {
+		if *validate || *validateAll {
+			log.Printf("Ignoring if/then/else of %s: %s\n", path, err)
+		}
+		return nil
+	}
+	return c
+}
+
+func parseConditional(s *metaSchema, fields structFields) (*conditional, error) {
+	rest := *s.If
+	rest.Properties, rest.Required = nil, nil
+	if jsonType, _ := rest.Type.(string); jsonType == typeObject {
+		rest.Type = nil
+	}
+	if !isEmptyMetaSchema(&rest) {
+		return nil, fmt.Errorf("the if schema can only require properties or their values")
+	}
+
+	c := &conditional{}
+	checked, _ := stringset.FromMapKeys(s.If.Properties)
+	for _, req := range s.If.Required {
+		checked.Add(string(req))
+	}
+	for _, name := range checked.Sorted() {
+		sf, ok := fieldByProperty(fields, name)
+		if !ok {
+			return nil, fmt.Errorf("%q isn't a property", name)
+		}
+		check := conditionField{field: sf.Name, propertyName: name}
+		if propSchema, ok := s.If.Properties[name]; ok {
+			value, ok := conditionValue(propSchema)
+			if !ok {
+				return nil, fmt.Errorf("the if schema of %q can only be a const", name)
+			}
+			if _, ok := valueLiteral(value, sf.scalarType()); !ok {
+				return nil, fmt.Errorf("%q can't be compared with %v", name, value)
+			}
+			check.value, check.hasValue = value, true
+		}
+		for _, req := range s.If.Required {
+			if string(req) == name {
+				check.required = true
+			}
+		}
+		if _, ok := sf.absentCheck(); !ok && (check.required || !sf.Required) {
+			return nil, fmt.Errorf("whether %q is set can't be told", name)
+		}
+		c.ifFields = append(c.ifFields, check)
+	}
+
+	var err error
+	if c.then, err = branchFields(s.Then, fields); err != nil {
+		return nil, err
+	}
+	if c.els, err = branchFields(s.Else, fields); err != nil {
+		return nil, err
+	}
+	if len(c.then) == 0 && len(c.els) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// scalarType returns the Go type of the value of sf, or of the type it refers
+// to, such as an enum.
+func (sf structField) scalarType() string {
+	if sf.TypePrefix != "" {
+		return sf.TypePrefix
+	}
+	return types[sf.TypeRef].TypePrefix
+}
+
+// absentCheck returns the condition that sf is unset: nil if it's a pointer,
+// slice, map or interface, and otherwise the zero value, which is what an
+// absent property unmarshals to.
+func (sf structField) absentCheck() (string, bool) {
+	expr := "t." + sf.Name
+	if _, isPtr := sf.typeString(); isPtr {
+		return expr + " == nil", true
+	}
+	if sf.NullSlice || sf.FixedLen > 0 {
+		return "", false
+	}
+	switch ts := sf.scalarType(); {
+	case ts == typeString:
+		return expr + ` == ""`, true
+	case ts == typeInt || ts == typeFloat64:
+		return expr + " == 0", true
+	case ts == typeBool:
+		return "!" + expr, true
+	case isSliceType(ts) || strings.HasPrefix(ts, "map[") || ts == typeEmptyInterface:
+		return expr + " == nil", true
+	}
+	return "", false
+}
+
+// presentCheck returns the condition that sf is set, the opposite of
+// absentCheck.
+func (sf structField) presentCheck() string {
+	absent, _ := sf.absentCheck()
+	if strings.HasPrefix(absent, "!") {
+		return absent[1:]
+	}
+	return strings.Replace(absent, " == ", " != ", 1)
+}
+
+// valueLiteral returns the Go literal of value, a const of JSON, as a value
+// of the Go type ts, and whether it is one: an integer has to fit in an
+// int64.
+func valueLiteral(value interface{}, ts string) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v), ts == typeString
+	case bool:
+		return strconv.FormatBool(v), ts == typeBool
+	case json.Number:
+		if i, err := v.Int64(); err == nil && ts == typeInt {
+			return strconv.FormatInt(i, 10), true
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return "", false
+		}
+		return valueLiteral(f, ts)
+	case float64:
+		if ts == typeInt {
+			return formatNumber(v), v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64
+		}
+		return formatNumber(v), ts == typeFloat64
+	}
+	return "", false
+}
+
+// conditionDescription describes the checks of the if schema, for the
+// messages of the checks of then and else.
+func conditionDescription(checks []conditionField) string {
+	descs := make([]string, len(checks))
+	for i, check := range checks {
+		if check.hasValue {
+			value, _ := json.Marshal(check.value)
+			descs[i] = fmt.Sprintf("%s is %s", check.propertyName, value)
+		} else {
+			descs[i] = check.propertyName + " is set"
+		}
+	}
+	return strings.Join(descs, " and ")
+}
+
+// writeConditionalChecks writes the checks of the if/then/else of gt, a
+// struct: those of then if the if schema matches, and those of else if not.
+// A property that the if schema gives a value to matches if it's absent, as
+// it does in JSON Schema, unless the if schema also requires it. The checks of
+// fields that gt doesn't have, like those left out of a --split-rw variant,
+// are skipped, and so are all of them if the if schema needs one.
+func (w validateWriter) writeConditionalChecks(gt goType) {
+	fields := make(map[string]structField)
+	for _, sf := range gt.Fields {
+		fields[sf.Name] = sf
+	}
+
+	var conds []string
+	for _, check := range gt.conditional.ifFields {
+		sf, ok := fields[check.field]
+		if !ok {
+			return
+		}
+		if !check.hasValue {
+			conds = append(conds, sf.presentCheck())
+			continue
+		}
+		expr := "t." + sf.Name
+		if _, isPtr := sf.typeString(); isPtr {
+			expr = "*" + expr
+		}
+		literal, _ := valueLiteral(check.value, sf.scalarType())
+		cond := expr + " == " + literal
+		switch {
+		case check.required:
+			cond = sf.presentCheck() + " && " + cond
+		case !sf.Required:
+			absent, _ := sf.absentCheck()
+			cond = "(" + absent + " || " + cond + ")"
+		}
+		conds = append(conds, cond)
+	}
+	condition := strings.Join(conds, " && ")
+	if condition == "" {
+		condition = "true"
+	}
+	desc := conditionDescription(gt.conditional.ifFields)
+
+	var then, els bytes.Buffer
+	thenWriter, elsWriter := w, w
+	thenWriter.buf, elsWriter.buf = &then, &els
+	thenWriter.writeBranchChecks(gt.conditional.then, fields, "then", "required when "+desc)
+	elsWriter.writeBranchChecks(gt.conditional.els, fields, "else", "required unless "+desc)
+
+	switch {
+	case then.Len() > 0 && els.Len() > 0:
+		fmt.Fprintf(w.buf, "if %s {\n", condition)
+		w.buf.Write(then.Bytes())
+		w.buf.WriteString("} else {\n")
+		w.buf.Write(els.Bytes())
+		w.buf.WriteString("}\n")
+	case then.Len() > 0:
+		fmt.Fprintf(w.buf, "if %s {\n", condition)
+		w.buf.Write(then.Bytes())
+		w.buf.WriteString("}\n")
+	case els.Len() > 0:
+		fmt.Fprintf(w.buf, "if !(%s) {\n", condition)
+		w.buf.Write(els.Bytes())
+		w.buf.WriteString("}\n")
+	}
+}
+
+// writeBranchChecks writes the checks of the then or else schema, named
+// keyword, of the struct with the given fields: that the properties it
+// requires are set, failing with requiredMsg, and the constraints of its
+// properties.
+func (w validateWriter) writeBranchChecks(checks []conditionField, fields map[string]structField, keyword, requiredMsg string) {
+	for _, check := range checks {
+		sf, ok := fields[check.field]
+		if !ok {
+			continue
+		}
+		if check.required {
+			absent, _ := sf.absentCheck()
+			fmt.Fprintf(w.buf, "if %s {\n", absent)
+			w.writeFail(check.propertyName, requiredMsg)
+			w.buf.WriteString("}\n")
+		}
+		if !check.constraints.appliesTo(sf.TypePrefix) {
+			continue
+		}
+		expr := "t." + sf.Name
+		_, isPtr := sf.typeString()
+		if isPtr {
+			fmt.Fprintf(w.buf, "if %s != nil {\n", expr)
+			expr = "*" + expr
+		}
+		w.writeConstraintChecks(check.constraints, expr, sf.TypePrefix, check.propertyName, sf.Name+" "+keyword)
+		if isPtr {
+			w.buf.WriteString("}\n")
+		}
+	}
+}
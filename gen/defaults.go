@@ -0,0 +1,179 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultStatement returns the statement that sets sf, a field of the struct
+// expr, to the default of its property, and whether the default can be set:
+// a scalar, including the base64 string of a []byte, or an empty array or
+// object. An empty object is the zero value of a struct, so it doesn't need a
+// statement.
+func (sf structField) defaultStatement(expr string) (string, bool) {
+	target := expr + "." + sf.Name
+	typeStr, isPtr := sf.typeString()
+	if sf.NullSlice || sf.Position > 0 || sf.Variant {
+		return "", false
+	}
+
+	switch value := sf.defaultValue.(type) {
+	case []interface{}:
+		// a Go array can't be empty
+		if len(value) > 0 || isPtr || sf.FixedLen > 0 || !strings.HasPrefix(typeStr, "[") {
+			return "", false
+		}
+		return fmt.Sprintf("%s = %s{}\n", target, typeStr), true
+	case map[string]interface{}:
+		switch {
+		case len(value) > 0 || isPtr:
+			return "", false
+		case strings.HasPrefix(typeStr, "map["):
+			return fmt.Sprintf("%s = %s{}\n", target, typeStr), true
+		case types[sf.TypeRef].TypePrefix == typeStruct && sf.TypePrefix == "":
+			return "", true
+		}
+		return "", false
+	}
+
+	literal, ok := valueLiteral(sf.defaultValue, sf.scalarType())
+	if value, isString := sf.defaultValue.(string); isString && sf.scalarType() == typeBytes {
+		literal, ok = bytesLiteral(value)
+	}
+	if !ok {
+		return "", false
+	}
+	if !isPtr {
+		return fmt.Sprintf("%s = %s\n", target, literal), true
+	}
+	return fmt.Sprintf("{\nv := %s(%s)\n%s = &v\n}\n", strings.TrimPrefix(typeStr, "*"), literal, target), true
+}
+
+// bytesLiteral returns a []byte literal of the bytes that value, a base64
+// string as encoding/json encodes them, decodes to, and whether it's valid.
+func bytesLiteral(value string) (string, bool) {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%#v", b), true
+}
+
+// warnIgnoredDefaults warns about the defaults of the fields of gt, at path,
+// that New functions can't set with --defaults, and forgets them.
+func (gt *goType) warnIgnoredDefaults(path string) {
+	for i, sf := range gt.Fields {
+		if sf.defaultValue == nil {
+			continue
+		}
+		if _, ok := sf.defaultStatement("t"); !ok {
+			log.Printf("Ignoring default of %s/properties/%s: only scalars that fit their type and empty arrays and objects are set\n", path, sf.PropertyName)
+			gt.Fields[i].defaultValue = nil
+		}
+	}
+}
+
+// defaultedStruct returns the struct type that sf, a field of a struct, holds
+// as a value rather than through a pointer, whose defaults its parent's New
+// function sets too, and the name of the field.
+func (sf structField) defaultedStruct() (goType, string, bool) {
+	refType, ok := types[sf.TypeRef]
+	if !ok || sf.TypePrefix != "" || refType.TypePrefix != typeStruct {
+		return goType{}, "", false
+	}
+	if _, isPtr := sf.typeString(); isPtr {
+		return goType{}, "", false
+	}
+	if sf.Embedded {
+		return refType, refType.Name, true
+	}
+	return refType, sf.Name, true
+}
+
+// findDefaultedTypes returns the paths of the structs that get a New function
+// with --defaults: those with fields with defaults, and those with a field
+// holding a struct that gets one.
+func findDefaultedTypes() map[string]bool {
+	defaulted := make(map[string]bool)
+	for path, gt := range types {
+		if gt.TypePrefix != typeStruct {
+			continue
+		}
+		for _, sf := range gt.Fields {
+			if stmt, ok := sf.defaultStatement("t"); ok && stmt != "" && sf.defaultValue != nil {
+				defaulted[path] = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for path, gt := range types {
+			if defaulted[path] || gt.TypePrefix != typeStruct {
+				continue
+			}
+			for _, sf := range gt.Fields {
+				if _, _, ok := sf.defaultedStruct(); ok && defaulted[sf.TypeRef] {
+					defaulted[path] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return defaulted
+}
+
+// constructorName returns the name of the New function of gt, unexported if
+// gt is.
+func constructorName(gt goType) string {
+	first, size := utf8.DecodeRuneInString(gt.Name)
+	if unicode.IsLower(first) {
+		return "new" + string(unicode.ToUpper(first)) + gt.Name[size:]
+	}
+	return "New" + gt.Name
+}
+
+// writeDefaults writes the statements that set the fields of expr, a value of
+// gt, to their defaults, and the fields holding structs to theirs, calling
+// their New functions, or setting their fields if they're anonymous structs.
+func writeDefaults(buf *bytes.Buffer, expr string, gt goType, defaulted map[string]bool) {
+	sort.Stable(gt.Fields)
+	for _, sf := range gt.Fields {
+		if sf.defaultValue != nil {
+			stmt, _ := sf.defaultStatement(expr)
+			buf.WriteString(stmt)
+		}
+		refType, name, ok := sf.defaultedStruct()
+		if !ok || !defaulted[sf.TypeRef] {
+			continue
+		}
+		if refType.Inline {
+			writeDefaults(buf, expr+"."+name, refType, defaulted)
+			continue
+		}
+		fmt.Fprintf(buf, "%s.%s = %s()\n", expr, name, constructorName(refType))
+	}
+}
+
+// printConstructor writes a New function for gt, a struct with defaults,
+// that returns it with them set.
+func (gt goType) printConstructor(buf *bytes.Buffer, defaulted map[string]bool) {
+	if !defaulted[gt.path] {
+		return
+	}
+	name := constructorName(gt)
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "// %s returns a new %s with the defaults of its schema set.\n", name, gt.Name)
+	fmt.Fprintf(buf, "func %s() %s {\n", name, gt.Name)
+	fmt.Fprintf(buf, "var t %s\n", gt.Name)
+	writeDefaults(buf, "t", gt, defaulted)
+	buf.WriteString("return t\n")
+	buf.WriteString("}\n")
+}
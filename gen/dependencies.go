@@ -0,0 +1,148 @@
+package gen
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// addDependentProperties adds to props the properties of the dependent
+// schemas of s that it doesn't define itself, or that the then or else
+// schemas of its if/then/else haven't added, whose paths are in paths, so the
+// struct has a field for every property it can have whichever properties are
+// set. It returns props and paths, with the paths of the properties it
+// added, and the property that each of them depends on. The first property,
+// by name, whose dependent schema defines a property gives its type.
+func addDependentProperties(s *metaSchema, props map[string]*metaSchema, paths map[string]string, path string) (map[string]*metaSchema, map[string]string, map[string]string) {
+	if len(s.DependentSchemas) == 0 {
+		return props, paths, nil
+	}
+	defined := definedProperties(s)
+	dependsOn := make(map[string]string)
+	dependencies, _ := stringset.FromMapKeys(s.DependentSchemas)
+	for _, dependency := range dependencies.Sorted() {
+		dependentSchema := s.DependentSchemas[dependency]
+		names, _ := stringset.FromMapKeys(dependentSchema.Properties)
+		for _, name := range names.Sorted() {
+			if _, ok := paths[name]; ok || defined.Has(name) {
+				continue
+			}
+			if props == nil {
+				props = make(map[string]*metaSchema)
+			}
+			if paths == nil {
+				paths = make(map[string]string)
+			}
+			propSchema := dependentSchema.Properties[name]
+			props[name] = &propSchema
+			paths[name] = path + "/dependentSchemas/" + dependency + "/properties/" + name
+			dependsOn[name] = dependency
+		}
+	}
+	return props, paths, dependsOn
+}
+
+// dependentRequirement is a property that requires others to be set when
+// it's set, with dependentRequired, checked by the Validate method of the
+// struct holding them.
+type dependentRequirement struct {
+	propertyName string
+	field        string
+	// required holds the property names and fields of the properties it
+	// requires
+	required []dependentRequirement
+}
+
+// getDependentRequired returns the properties of s, the schema of a struct
+// with the given fields, that require others with dependentRequired, in the
+// order of their names. A property that's missing, or whose field can't tell
+// whether it's set, is ignored, with a warning.
+func getDependentRequired(s *metaSchema, fields structFields, path string) []dependentRequirement {
+	names, _ := stringset.FromMapKeys(s.DependentRequired)
+	var deps []dependentRequirement
+	for _, name := range names.Sorted() {
+		dep, err := parseDependentRequired(name, s.DependentRequired[name], fields)
+		if err != nil {
+			if *validate || *validateAll {
+				log.Printf("Ignoring dependentRequired %q of %s: %s\n", name, path, err)
+			}
+			continue
+		}
+		if len(dep.required) > 0 {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+func parseDependentRequired(name string, required metaStringArray, fields structFields) (dependentRequirement, error) {
+	field := func(name string) (string, error) {
+		sf, ok := fieldByProperty(fields, name)
+		if !ok {
+			return "", fmt.Errorf("%q isn't a property", name)
+		}
+		if _, ok := sf.absentCheck(); !ok {
+			return "", fmt.Errorf("whether %q is set can't be told", name)
+		}
+		return sf.Name, nil
+	}
+
+	dep := dependentRequirement{propertyName: name}
+	var err error
+	if dep.field, err = field(name); err != nil {
+		return dep, err
+	}
+	seen := stringset.New()
+	for _, req := range required {
+		reqName := string(req)
+		if seen.Has(reqName) || reqName == name {
+			continue
+		}
+		seen.Add(reqName)
+		reqField, err := field(reqName)
+		if err != nil {
+			return dep, err
+		}
+		dep.required = append(dep.required, dependentRequirement{propertyName: reqName, field: reqField})
+	}
+	return dep, nil
+}
+
+// writeDependentRequired writes the checks that the properties of gt, a
+// struct, that are set have the properties they require. Those of fields
+// that gt doesn't have, like those left out of a --split-rw variant, are
+// skipped.
+func (w validateWriter) writeDependentRequired(gt goType) {
+	fields := make(map[string]structField)
+	for _, sf := range gt.Fields {
+		fields[sf.Name] = sf
+	}
+
+	for _, dep := range gt.dependentRequired {
+		sf, ok := fields[dep.field]
+		if !ok {
+			continue
+		}
+		var checks []structField
+		var names []string
+		for _, req := range dep.required {
+			if reqField, ok := fields[req.field]; ok {
+				checks = append(checks, reqField)
+				names = append(names, req.propertyName)
+			}
+		}
+		if len(checks) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w.buf, "if %s {\n", sf.presentCheck())
+		for i, reqField := range checks {
+			absent, _ := reqField.absentCheck()
+			fmt.Fprintf(w.buf, "if %s {\n", absent)
+			w.writeFail(names[i], "required when "+dep.propertyName+" is set")
+			w.buf.WriteString("}\n")
+		}
+		w.buf.WriteString("}\n")
+	}
+}
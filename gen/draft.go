@@ -0,0 +1,186 @@
+package gen
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The JSON Schema versions that --schema-version accepts.
+const (
+	draft04   = "draft-04"
+	draft06   = "draft-06"
+	draft07   = "draft-07"
+	draft2019 = "2019-09"
+	draft2020 = "2020-12"
+)
+
+var schemaVersions = []string{draft04, draft06, draft07, draft2019, draft2020}
+
+// draft is the version the schema being generated is interpreted as.
+var draft = draft07
+
+// detectSchemaVersion returns the version named by the $schema URI, or
+// draft-07 if it's empty or unknown.
+func detectSchemaVersion(uri string) string {
+	switch {
+	case strings.Contains(uri, "draft-03"), strings.Contains(uri, "draft-04"):
+		return draft04
+	case strings.Contains(uri, "draft-06"):
+		return draft06
+	case strings.Contains(uri, "draft/2019-09"):
+		return draft2019
+	case strings.Contains(uri, "draft/2020-12"):
+		return draft2020
+	default:
+		return draft07
+	}
+}
+
+// setSchemaVersion sets draft from --schema-version if it's given and
+// from the $schema URI otherwise.
+func setSchemaVersion(uri string) {
+	if *schemaVersion != "" {
+		draft = *schemaVersion
+		return
+	}
+	draft = detectSchemaVersion(uri)
+}
+
+// formatVocabulary is false if the schema's $vocabulary leaves out the format
+// vocabulary, or doesn't require it, so formats don't change types.
+var formatVocabulary = true
+
+// setVocabulary sets formatVocabulary from the $vocabulary of the root
+// schema. Without one, all the standard vocabularies are in use.
+func setVocabulary(vocabulary map[string]bool) {
+	formatVocabulary = true
+	if vocabulary == nil {
+		return
+	}
+	formatVocabulary = false
+	for uri, required := range vocabulary {
+		// 2019-09 has vocab/format, 2020-12 vocab/format-annotation and
+		// vocab/format-assertion
+		if strings.Contains(uri, "/vocab/format") && required {
+			formatVocabulary = true
+		}
+	}
+}
+
+// hoistBooleanRequired moves the required flags of draft-03, a boolean on each
+// property's schema, to the required array of the schema with the properties,
+// as it's given since draft-04, so the schema decodes the same either way.
+func hoistBooleanRequired(doc interface{}) {
+	root, _ := url.Parse(rootBaseURI)
+	walkSchemas(doc, "#", root, func(schema map[string]interface{}, path string, base *url.URL) {
+		// a flag left on this schema isn't on a property, so it means nothing
+		if _, ok := schema["required"].(bool); ok {
+			delete(schema, "required")
+		}
+
+		props, _ := schema["properties"].(map[string]interface{})
+		var names []string
+		for name, prop := range props {
+			propSchema, ok := prop.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if required, ok := propSchema["required"].(bool); ok {
+				delete(propSchema, "required")
+				if required {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return
+		}
+
+		sort.Strings(names)
+		required, _ := schema["required"].([]interface{})
+		for _, name := range names {
+			required = append(required, name)
+		}
+		schema["required"] = required
+	})
+}
+
+// movePrefixItems moves the tuple items of 2020-12, given by prefixItems with
+// items for the ones after them, to items and additionalItems, as they're
+// given before 2020-12, so the schema decodes the same either way.
+func movePrefixItems(doc interface{}) {
+	root, _ := url.Parse(rootBaseURI)
+	walkSchemas(doc, "#", root, func(schema map[string]interface{}, path string, base *url.URL) {
+		prefixItems, ok := schema["prefixItems"].([]interface{})
+		if !ok {
+			return
+		}
+		if items, ok := schema["items"]; ok {
+			schema["additionalItems"] = items
+		}
+		schema["items"] = prefixItems
+		delete(schema, "prefixItems")
+	})
+}
+
+// splitDependencies moves the dependencies of draft-04, given by
+// dependencies with a list of property names or a schema, to
+// dependentRequired and dependentSchemas, as they're given since 2019-09, so
+// the schema decodes the same either way. A boolean dependent schema, which
+// has no properties to add, is dropped.
+func splitDependencies(doc interface{}) {
+	root, _ := url.Parse(rootBaseURI)
+	walkSchemas(doc, "#", root, func(schema map[string]interface{}, path string, base *url.URL) {
+		dependencies, _ := schema["dependencies"].(map[string]interface{})
+		dependentRequired, _ := schema["dependentRequired"].(map[string]interface{})
+		dependentSchemas, _ := schema["dependentSchemas"].(map[string]interface{})
+		for name, dependency := range dependencies {
+			switch dependency := dependency.(type) {
+			case []interface{}:
+				if dependentRequired == nil {
+					dependentRequired = make(map[string]interface{})
+				}
+				// a name given by both keeps the names of both
+				if other, ok := dependentRequired[name].([]interface{}); ok {
+					dependency = append(other, dependency...)
+				}
+				dependentRequired[name] = dependency
+			case map[string]interface{}, bool:
+				if dependentSchemas == nil {
+					dependentSchemas = make(map[string]interface{})
+				}
+				if _, ok := dependentSchemas[name]; !ok {
+					dependentSchemas[name] = dependency
+				}
+			default:
+				continue
+			}
+			delete(dependencies, name)
+		}
+		for name, dependentSchema := range dependentSchemas {
+			if _, ok := dependentSchema.(bool); ok {
+				delete(dependentSchemas, name)
+			}
+		}
+
+		if dependencies != nil && len(dependencies) == 0 {
+			delete(schema, "dependencies")
+		}
+		if dependentRequired != nil {
+			schema["dependentRequired"] = dependentRequired
+		}
+		if len(dependentSchemas) > 0 {
+			schema["dependentSchemas"] = dependentSchemas
+		} else {
+			delete(schema, "dependentSchemas")
+		}
+	})
+}
+
+// numericExclusiveBounds returns true if exclusiveMinimum and exclusiveMaximum
+// are bounds of their own, as they are since draft-06, rather than booleans
+// that make minimum and maximum exclusive.
+func numericExclusiveBounds() bool {
+	return draft != draft04
+}
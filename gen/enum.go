@@ -0,0 +1,405 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+const (
+	enumNamingTypeValue  = "type-value"
+	enumNamingValue      = "value"
+	enumNamingUpperSnake = "upper-snake"
+)
+
+type enumConst struct {
+	Name    string
+	Value   string
+	Comment string
+}
+
+// enumStrings returns the values of s's enum if they are all strings. A null
+// value is skipped since it's covered by the type being nullable.
+func enumStrings(s *metaSchema) []string {
+	var vals []string
+	for _, val := range s.Enum {
+		switch val := val.(type) {
+		case string:
+			vals = append(vals, val)
+		case nil:
+		default:
+			return nil
+		}
+	}
+	return vals
+}
+
+// enumInts returns the values of s's enum as Go literals if they are all
+// integers. As with enumStrings, a null value is skipped.
+func enumInts(s *metaSchema) []string {
+	var vals []string
+	for _, val := range s.Enum {
+		switch val := val.(type) {
+		case float64:
+			if val != math.Trunc(val) {
+				return nil
+			}
+			vals = append(vals, strconv.FormatInt(int64(val), 10))
+		case nil:
+		default:
+			return nil
+		}
+	}
+	return vals
+}
+
+// enumBranchKeywords are the keywords an anyOf or oneOf schema can have and
+// still be merged by mergeEnumBranches.
+var enumBranchKeywords = stringset.New("enum", "const", "type", "title", "description", "$comment")
+
+// enumBranchValues returns the values of branch, a schema decoded with
+// UseNumber, and their JSON type, if it's nothing but an enum or const of
+// strings or integers, which may include null.
+func enumBranchValues(branch interface{}) (vals []interface{}, jsonType string, ok bool) {
+	schema, ok := branch.(map[string]interface{})
+	if !ok {
+		return nil, "", false
+	}
+	for keyword := range schema {
+		if !enumBranchKeywords.Has(keyword) {
+			return nil, "", false
+		}
+	}
+
+	if constVal, ok := schema["const"]; ok {
+		vals = []interface{}{constVal}
+	} else if vals, ok = schema["enum"].([]interface{}); !ok {
+		return nil, "", false
+	}
+	for _, val := range vals {
+		var valType string
+		switch val := val.(type) {
+		case nil:
+			continue
+		case string:
+			valType = typeString
+		case json.Number:
+			if _, err := val.Int64(); err != nil {
+				return nil, "", false
+			}
+			valType = typeInteger
+		default:
+			return nil, "", false
+		}
+		if jsonType != "" && valType != jsonType {
+			return nil, "", false
+		}
+		jsonType = valType
+	}
+	if t, ok := schema["type"]; ok && t != jsonType {
+		return nil, "", false
+	}
+	return vals, jsonType, jsonType != ""
+}
+
+// mergeEnumBranches replaces each anyOf or oneOf in doc whose schemas are all
+// enums of the same type, like [{"enum": ["a"]}, {"enum": ["b", "c"]}], with
+// one enum of their values, without duplicates, so it's generated as one enum
+// type rather than interface{}.
+func mergeEnumBranches(doc interface{}) {
+	root, _ := url.Parse(rootBaseURI)
+	walkSchemas(doc, "#", root, func(schema map[string]interface{}, path string, base *url.URL) {
+		_, hasEnum := schema["enum"]
+		_, hasConst := schema["const"]
+		anyOf, hasAnyOf := schema["anyOf"].([]interface{})
+		oneOf, hasOneOf := schema["oneOf"].([]interface{})
+		if hasEnum || hasConst || hasAnyOf == hasOneOf {
+			return
+		}
+		keyword, branches := "anyOf", anyOf
+		if hasOneOf {
+			keyword, branches = "oneOf", oneOf
+		}
+		if len(branches) == 0 {
+			return
+		}
+
+		var merged []interface{}
+		var mergedType string
+		seen := stringset.New()
+		for _, branch := range branches {
+			vals, jsonType, ok := enumBranchValues(branch)
+			if !ok || (mergedType != "" && jsonType != mergedType) {
+				return
+			}
+			mergedType = jsonType
+			for _, val := range vals {
+				key, _ := json.Marshal(val)
+				if !seen.Has(string(key)) {
+					seen.Add(string(key))
+					merged = append(merged, val)
+				}
+			}
+		}
+		if t, ok := schema["type"]; ok && t != mergedType {
+			return
+		}
+
+		delete(schema, keyword)
+		schema["type"] = mergedType
+		schema["enum"] = merged
+	})
+}
+
+// hasEnum returns true if s has an enum of values that fit typePrefix, the Go
+// type of a property, so that the property gets an enum type of its own.
+func hasEnum(s *metaSchema, typePrefix string) bool {
+	switch typePrefix {
+	case typeString:
+		return len(enumStrings(s)) > 0
+	case typeInt:
+		return len(enumInts(s)) > 0
+	case typeEmptyInterface:
+		return len(enumStrings(s)) > 0 || len(enumInts(s)) > 0
+	default:
+		return false
+	}
+}
+
+// enumExtensions returns the x-enum-varnames and x-enum-descriptions of s, as
+// used by OpenAPI generators, lined up with the enum values returned by
+// enumStrings or enumInts. Missing entries are left empty.
+func enumExtensions(s *metaSchema) (varnames, descriptions []string) {
+	if len(s.XEnumVarnames) == 0 && len(s.XEnumDescriptions) == 0 {
+		return nil, nil
+	}
+	for i, val := range s.Enum {
+		if val == nil {
+			continue
+		}
+		var varname, description string
+		if i < len(s.XEnumVarnames) {
+			varname = s.XEnumVarnames[i]
+		}
+		if i < len(s.XEnumDescriptions) {
+			description = s.XEnumDescriptions[i]
+		}
+		varnames = append(varnames, varname)
+		descriptions = append(descriptions, description)
+	}
+	return varnames, descriptions
+}
+
+// intersectStrings returns the values of a that are also in b, in a's order.
+func intersectStrings(a, b []string) []string {
+	inB := stringset.New(b...)
+	var vals []string
+	for _, val := range a {
+		if inB.Has(val) {
+			vals = append(vals, val)
+		}
+	}
+	return vals
+}
+
+func upperSnake(name string) string {
+	words := strings.Fields(camelCaseToWords(name))
+	return strings.ToUpper(strings.Join(words, "_"))
+}
+
+// enumConstName returns the name of the constant for the index'th enum value
+// val of typeName using the given naming strategy. An integer value can't name
+// a constant on its own, so value naming falls back to type-value for it.
+func enumConstName(typeName, val string, isInt bool, index int, naming string) string {
+	if isInt {
+		valName := strings.Replace(val, "-", "Minus", 1)
+		if naming == enumNamingUpperSnake {
+			return upperSnake(typeName) + "_" + strings.ToUpper(valName)
+		}
+		return typeName + valName
+	}
+
+	valName := generateIdentifier(val, true)
+	if valName == "" {
+		if val == "" {
+			valName = "Empty"
+		} else {
+			valName = fmt.Sprintf("Value%d", index+1)
+		}
+	}
+
+	switch naming {
+	case enumNamingValue:
+		return valName
+	case enumNamingUpperSnake:
+		return upperSnake(typeName) + "_" + upperSnake(valName)
+	default:
+		return typeName + valName
+	}
+}
+
+// prefixConst returns name with --prefix in front of it if --prefix-constants
+// is set and it doesn't already start with it, as the names of prefixed types
+// do with type-value naming.
+func prefixConst(name string) string {
+	if !*prefixConstants || *typeNamesPrefix == "" {
+		return name
+	}
+	prefix := *typeNamesPrefix
+	if *enumNaming == enumNamingUpperSnake {
+		prefix = upperSnake(prefix) + "_"
+	}
+	if strings.HasPrefix(name, prefix) {
+		return name
+	}
+	return prefix + name
+}
+
+// nameEnumConsts assigns a name to each enum constant. Constants share the
+// package namespace with each other and with the types, so collisions are
+// resolved by falling back to the type-value name and then by numbering.
+func nameEnumConsts() {
+	used := stringset.New()
+	for _, gt := range types {
+		used.Add(gt.Name)
+	}
+
+	paths := make([]string, 0, len(types))
+	for path, gt := range types {
+		if len(gt.enumValues) > 0 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return types[paths[i]].Name < types[paths[j]].Name
+	})
+
+	for _, path := range paths {
+		gt := types[path]
+		isInt := gt.TypePrefix == typeInt
+		gt.enumConsts = make([]enumConst, 0, len(gt.enumValues))
+		for i, val := range gt.enumValues {
+			var name, comment string
+			if i < len(gt.enumVarnames) && gt.enumVarnames[i] != "" {
+				name = gt.enumVarnames[i]
+				if !token.IsIdentifier(name) {
+					name = generateIdentifier(name, true)
+				}
+			}
+			if i < len(gt.enumDescriptions) {
+				comment = strings.Join(strings.Fields(gt.enumDescriptions[i]), " ")
+			}
+
+			if name == "" {
+				name = enumConstName(gt.Name, val, isInt, i, *enumNaming)
+				if used.Has(name) && *enumNaming == enumNamingValue {
+					name = enumConstName(gt.Name, val, isInt, i, enumNamingTypeValue)
+				}
+			}
+			name = prefixConst(name)
+
+			sep := ""
+			if *enumNaming == enumNamingUpperSnake {
+				sep = "_"
+			}
+			baseName := name
+			for n := 2; used.Has(name); n++ {
+				name = fmt.Sprintf("%s%s%d", baseName, sep, n)
+			}
+			used.Add(name)
+
+			value := val
+			if !isInt {
+				value = strconv.Quote(val)
+			}
+			gt.enumConsts = append(gt.enumConsts, enumConst{Name: name, Value: value, Comment: comment})
+		}
+
+		if *enumHelpers {
+			name := gt.Name + "Values"
+			for n := 2; used.Has(name); n++ {
+				name = fmt.Sprintf("%sValues%d", gt.Name, n)
+			}
+			used.Add(name)
+			gt.enumValuesVar = name
+		}
+		types[path] = gt
+	}
+}
+
+// iotaStart returns the first value of gt's integer enum and true if its
+// values count up from 0 or 1, so its constants can be declared with iota.
+func (gt goType) iotaStart() (int, bool) {
+	if gt.TypePrefix != typeInt || len(gt.enumConsts) < 2 {
+		return 0, false
+	}
+
+	start, err := strconv.Atoi(gt.enumConsts[0].Value)
+	if err != nil || (start != 0 && start != 1) {
+		return 0, false
+	}
+	for i, c := range gt.enumConsts {
+		if c.Value != strconv.Itoa(start+i) {
+			return 0, false
+		}
+	}
+	return start, true
+}
+
+func (gt goType) printEnumConsts(buf *bytes.Buffer) {
+	if len(gt.enumConsts) == 0 {
+		return
+	}
+
+	start, useIota := gt.iotaStart()
+	buf.WriteString("\nconst (\n")
+	for i, c := range gt.enumConsts {
+		switch {
+		case !useIota:
+			buf.WriteString(fmt.Sprintf("%s %s = %s", c.Name, gt.Name, c.Value))
+		case i > 0:
+			buf.WriteString(c.Name)
+		case start == 0:
+			buf.WriteString(fmt.Sprintf("%s %s = iota", c.Name, gt.Name))
+		default:
+			buf.WriteString(fmt.Sprintf("%s %s = iota + %d", c.Name, gt.Name, start))
+		}
+		if c.Comment != "" {
+			buf.WriteString(" // " + c.Comment)
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(")\n")
+	gt.printEnumHelpers(buf)
+}
+
+// printEnumHelpers writes the slice of the values of gt's enum and its
+// IsValid method with --enum-helpers.
+func (gt goType) printEnumHelpers(buf *bytes.Buffer) {
+	if gt.enumValuesVar == "" {
+		return
+	}
+
+	names := make([]string, len(gt.enumConsts))
+	for i, c := range gt.enumConsts {
+		names[i] = c.Name
+	}
+	fmt.Fprintf(buf, "\n// %s holds the values of %s in the order of the schema.\n", gt.enumValuesVar, gt.Name)
+	fmt.Fprintf(buf, "var %s = []%s{%s}\n", gt.enumValuesVar, gt.Name, strings.Join(names, ", "))
+
+	fmt.Fprintf(buf, "\n// IsValid returns true if e is one of %s.\n", gt.enumValuesVar)
+	fmt.Fprintf(buf, "func (e %s) IsValid() bool {\n", gt.Name)
+	fmt.Fprintf(buf, "for _, v := range %s {\n", gt.enumValuesVar)
+	buf.WriteString("if e == v {\nreturn true\n}\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return false\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,194 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// arrayPrefixRe matches the length of a Go array type, like the "[3]" of
+// "[3]int64".
+var arrayPrefixRe = regexp.MustCompile(`^\[\d+\]`)
+
+// isComparableBasic returns true if values of typeStr can be compared with ==.
+func isComparableBasic(typeStr string) bool {
+	switch typeStr {
+	case typeString, typeBool, typeInt, typeFloat64:
+		return true
+	}
+	return false
+}
+
+// hasEqual returns true if gt gets an Equal method with --equal. Structs get
+// one unless they have a field of the same name, as do the other types that
+// can't be compared with ==, like slices and maps.
+func (gt goType) hasEqual() bool {
+	if gt.Alias {
+		return false
+	}
+	if gt.TypePrefix == typeStruct {
+		for _, sf := range gt.Fields {
+			if sf.Name == "Equal" {
+				return false
+			}
+		}
+		return true
+	}
+	if gt.TypePrefix == typeEmptyInterface || isComparableBasic(gt.TypePrefix) {
+		return false
+	}
+	if baseType, ok := types[gt.TypeRef]; ok && gt.TypePrefix == "" {
+		return baseType.hasEqual() || baseType.TypePrefix == typeStruct
+	}
+	return true
+}
+
+// typeNamed returns the generated type called name.
+func typeNamed(name string) (goType, bool) {
+	for _, gt := range types {
+		if gt.Name == name {
+			return gt, true
+		}
+	}
+	return goType{}, false
+}
+
+// equalWriter writes statements that return false if two values differ.
+type equalWriter struct {
+	buf     *bytes.Buffer
+	imports stringset.StringSet
+}
+
+// primary returns expr parenthesized if it's a dereference, so it can be
+// indexed or have a method called on it.
+func primary(expr string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+// writeCheck writes the statements comparing a and b, values of the Go type
+// typeStr. depth numbers the loop variables of nested slices and maps.
+func (w equalWriter) writeCheck(a, b, typeStr string, depth int) {
+	suffix := ""
+	if depth > 0 {
+		suffix = fmt.Sprint(depth)
+	}
+
+	switch {
+	case strings.HasPrefix(typeStr, "*"):
+		fmt.Fprintf(w.buf, "if (%s == nil) != (%s == nil) {\nreturn false\n}\n", a, b)
+		fmt.Fprintf(w.buf, "if %s != nil {\n", a)
+		w.writeCheck("*"+a, "*"+b, typeStr[1:], depth)
+		w.buf.WriteString("}\n")
+	case typeStr == typeBytes || typeStr == "json.RawMessage":
+		w.imports.Add("bytes")
+		fmt.Fprintf(w.buf, "if !bytes.Equal(%s, %s) {\nreturn false\n}\n", a, b)
+	case strings.HasPrefix(typeStr, "[]"):
+		fmt.Fprintf(w.buf, "if (%s == nil) != (%s == nil) || len(%s) != len(%s) {\nreturn false\n}\n", a, b, a, b)
+		w.writeElemsCheck(a, b, typeStr[2:], suffix, depth)
+	case arrayPrefixRe.MatchString(typeStr):
+		w.writeElemsCheck(a, b, arrayPrefixRe.ReplaceAllString(typeStr, ""), suffix, depth)
+	case strings.HasPrefix(typeStr, "map[") && strings.HasSuffix(typeStr, "]struct{}"):
+		// a set from --unique-sets has the same items if it has each of the
+		// other's
+		fmt.Fprintf(w.buf, "if (%s == nil) != (%s == nil) || len(%s) != len(%s) {\nreturn false\n}\n", a, b, a, b)
+		k := "k" + suffix
+		fmt.Fprintf(w.buf, "for %s := range %s {\n", k, a)
+		fmt.Fprintf(w.buf, "if _, ok := %s[%s]; !ok {\nreturn false\n}\n", primary(b), k)
+		w.buf.WriteString("}\n")
+	case strings.HasPrefix(typeStr, "map[string]"):
+		fmt.Fprintf(w.buf, "if (%s == nil) != (%s == nil) || len(%s) != len(%s) {\nreturn false\n}\n", a, b, a, b)
+		k, v, ov := "k"+suffix, "v"+suffix, "ov"+suffix
+		fmt.Fprintf(w.buf, "for %s, %s := range %s {\n", k, v, a)
+		fmt.Fprintf(w.buf, "%s, ok := %s[%s]\nif !ok {\nreturn false\n}\n", ov, primary(b), k)
+		w.writeCheck(v, ov, strings.TrimPrefix(typeStr, "map[string]"), depth+1)
+		w.buf.WriteString("}\n")
+	case strings.HasPrefix(typeStr, nullSliceTypeName()+"["):
+		fmt.Fprintf(w.buf, "if %s.Set != %s.Set {\nreturn false\n}\n", primary(a), primary(b))
+		elem := strings.TrimSuffix(strings.TrimPrefix(typeStr, nullSliceTypeName()+"["), "]")
+		w.writeCheck(primary(a)+".Value", primary(b)+".Value", "[]"+elem, depth)
+	case isComparableBasic(typeStr):
+		fmt.Fprintf(w.buf, "if %s != %s {\nreturn false\n}\n", a, b)
+	case typeStr == typeTime:
+		fmt.Fprintf(w.buf, "if !%s.Equal(%s) {\nreturn false\n}\n", primary(a), b)
+	default:
+		gt, ok := typeNamed(typeStr)
+		switch {
+		case ok && gt.hasEqual():
+			fmt.Fprintf(w.buf, "if !%s.Equal(%s) {\nreturn false\n}\n", primary(a), b)
+		case ok && gt.Alias:
+			w.writeCheck(a, b, gt.underlyingString(), depth)
+		case ok && isComparableBasic(gt.TypePrefix):
+			fmt.Fprintf(w.buf, "if %s != %s {\nreturn false\n}\n", a, b)
+		default:
+			// interface{} values and types from --format-map
+			w.imports.Add("reflect")
+			fmt.Fprintf(w.buf, "if !reflect.DeepEqual(%s, %s) {\nreturn false\n}\n", a, b)
+		}
+	}
+}
+
+// writeElemsCheck writes a loop comparing the elements of a and b, slices or
+// arrays of the same length with elements of the Go type elem.
+func (w equalWriter) writeElemsCheck(a, b, elem, suffix string, depth int) {
+	i := "i" + suffix
+	fmt.Fprintf(w.buf, "for %s := range %s {\n", i, a)
+	w.writeCheck(primary(a)+"["+i+"]", primary(b)+"["+i+"]", elem, depth+1)
+	w.buf.WriteString("}\n")
+}
+
+// underlyingString returns the Go type that gt is declared as.
+func (gt goType) underlyingString() string {
+	typeStr := gt.TypePrefix
+	if baseType, ok := types[gt.TypeRef]; ok {
+		typeStr += baseType.Name
+	}
+	return typeStr
+}
+
+// printEqual writes an Equal method for gt that compares it field by field,
+// or element by element, calling Equal on nested generated types.
+func (gt goType) printEqual(buf *bytes.Buffer, imports stringset.StringSet) {
+	if !gt.hasEqual() {
+		return
+	}
+
+	var body bytes.Buffer
+	w := equalWriter{buf: &body, imports: imports}
+	if gt.TypePrefix == typeStruct {
+		sort.Stable(gt.Fields)
+		for _, sf := range gt.Fields {
+			typeStr, _ := sf.typeString()
+			if sf.Embedded {
+				w.writeCheck("t."+typeStr, "o."+typeStr, typeStr, 0)
+				continue
+			}
+			w.writeCheck("t."+sf.Name, "o."+sf.Name, typeStr, 0)
+		}
+		if gt.preservesUnknown() {
+			w.writeCheck("t.raw", "o.raw", "map[string]json.RawMessage", 0)
+		}
+	} else {
+		// a type declared as another named type doesn't have its methods, so
+		// it's converted to it
+		typeStr := gt.underlyingString()
+		a, b := "t", "o"
+		if gt.TypePrefix == "" {
+			a, b = typeStr+"(t)", typeStr+"(o)"
+		}
+		w.writeCheck(a, b, typeStr, 0)
+	}
+
+	buf.WriteString("\n")
+	buf.WriteString("// Equal returns true if t and o hold the same values.\n")
+	buf.WriteString(fmt.Sprintf("func (t %s) Equal(o %s) bool {\n", gt.Name, gt.Name))
+	buf.Write(body.Bytes())
+	buf.WriteString("return true\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,34 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// nameError is returned when no Go identifier can be made from the name of a
+// type or field, such as a property named "$$".
+type nameError struct {
+	Path string // JSON pointer of the schema
+	Name string // name the identifier was generated from
+	Kind string // "type" or "field"
+}
+
+func (e *nameError) Error() string {
+	return fmt.Sprintf("%s: can't generate a %s name from %q", e.Path, e.Kind, e.Name)
+}
+
+// refError is returned when the schemas at Paths can't be processed because
+// the $refs they depend on never resolve.
+type refError struct {
+	Paths []string
+	// Circular is set if Paths is a chain of $refs that leads back to its
+	// start, which is repeated at the end.
+	Circular bool
+}
+
+func (e *refError) Error() string {
+	if e.Circular {
+		return "circular $ref: " + strings.Join(e.Paths, " -> ")
+	}
+	return "can't resolve: " + strings.Join(e.Paths, ", ")
+}
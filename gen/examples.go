@@ -0,0 +1,53 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// printExamples returns the file name and body of a file declaring a
+// <Type>Examples function for each of the generated types whose schema has
+// examples, which returns them as values of the type. They're unmarshaled
+// when it's called, so one that doesn't fit its type, as --output-test would
+// report, is returned as an error rather than failing when the package is
+// initialized.
+func printExamples(typesSlice goTypes) (fileName string, body []byte) {
+	var buf bytes.Buffer
+	for _, gt := range typesSlice {
+		if len(gt.examples) == 0 {
+			continue
+		}
+		name := gt.Name + "Examples"
+		if typesByName.has(name) {
+			log.Printf("Ignoring examples of %s: another type is named %s\n", gt.path, name)
+			continue
+		}
+		examplesJSON, err := json.Marshal(gt.examples)
+		if err != nil {
+			log.Printf("Ignoring examples of %s: %s\n", gt.path, err)
+			continue
+		}
+		literal := strconv.Quote(string(examplesJSON))
+		if strconv.CanBackquote(string(examplesJSON)) {
+			literal = "`" + string(examplesJSON) + "`"
+		}
+
+		fmt.Fprintf(&buf, "\n// %s returns the examples of the schema of %s, or an error if one\n", name, gt.Name)
+		buf.WriteString("// doesn't fit the type.\n")
+		fmt.Fprintf(&buf, "func %s() ([]%s, error) {\n", name, gt.Name)
+		fmt.Fprintf(&buf, "var examples []%s\n", gt.Name)
+		fmt.Fprintf(&buf, "if err := json.Unmarshal([]byte(%s), &examples); err != nil {\n", literal)
+		buf.WriteString("return nil, err\n")
+		buf.WriteString("}\n")
+		buf.WriteString("return examples, nil\n")
+		buf.WriteString("}\n")
+	}
+	if buf.Len() == 0 {
+		return "", nil
+	}
+
+	return *rootTypeName + "_examples.go", bytes.TrimPrefix(buf.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,190 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// fileBundler copies the schemas in other files that refs point to into the
+// definitions of the document being generated.
+type fileBundler struct {
+	defs map[string]interface{}
+	// keys holds the definition each copied schema was put in, by file and
+	// pointer
+	keys map[string]string
+	docs map[string]interface{}
+}
+
+// load returns the schema document in file, reading it the first time.
+func (b *fileBundler) load(file string) (interface{}, error) {
+	if doc, ok := b.docs[file]; ok {
+		return doc, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", file, err)
+	}
+	b.docs[file] = doc
+	return doc, nil
+}
+
+// definitionKey returns an unused key in the definitions for the schema at
+// the pointer ptr in file, named after the last token of ptr, or file's
+// name, or both if that's taken.
+func (b *fileBundler) definitionKey(file, ptr string) string {
+	stem := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	key := stem
+	if i := strings.LastIndex(ptr, "/"); i >= 0 && i < len(ptr)-1 {
+		key = ptr[i+1:]
+		if _, taken := b.defs[key]; taken {
+			key = stem + "-" + key
+		}
+	}
+	base := key
+	for n := 2; ; n++ {
+		if _, taken := b.defs[key]; !taken {
+			return key
+		}
+		key = fmt.Sprintf("%s%d", base, n)
+	}
+}
+
+// bundle returns the local ref that replaces ref, found in file, or ref
+// itself if it doesn't point into another file. Refs within a file that's
+// been bundled point into that file too. The schema ref points to is copied
+// into the definitions, if it hasn't been already, with its refs replaced in
+// turn.
+func (b *fileBundler) bundle(ref, file string) (string, error) {
+	refFile, ptr := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		refFile, ptr = ref[:i], ref[i+1:]
+	}
+	// only JSON pointers can be looked up in another file
+	if ptr != "" && !strings.HasPrefix(ptr, "/") {
+		return ref, nil
+	}
+	switch {
+	case refFile == "" && file == *schemaFile:
+		return ref, nil
+	case refFile == "":
+		refFile = file
+	default:
+		// a URL isn't a file
+		if u, err := url.Parse(refFile); err != nil || u.Scheme != "" {
+			return ref, nil
+		}
+		refFile = filepath.Join(filepath.Dir(file), filepath.FromSlash(refFile))
+	}
+
+	id := refFile + "#" + ptr
+	if key, ok := b.keys[id]; ok {
+		return "#/definitions/" + key, nil
+	}
+	doc, err := b.load(refFile)
+	if err != nil {
+		return "", err
+	}
+	target, err := lookupPointer(doc, ptr)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", refFile, err)
+	}
+
+	// the copy's refs are replaced rather than those of the file's schema,
+	// which other refs may point into
+	copied := copyJSON(target)
+	key := b.definitionKey(refFile, ptr)
+	b.keys[id] = key
+	b.defs[key] = copied
+	if err := b.replaceRefs(copied, refFile); err != nil {
+		return "", err
+	}
+	return "#/definitions/" + key, nil
+}
+
+// replaceRefs replaces the refs in node, found in file, by bundling the
+// schemas they point to.
+func (b *fileBundler) replaceRefs(node interface{}, file string) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok {
+			local, err := b.bundle(ref, file)
+			if err != nil {
+				return err
+			}
+			n["$ref"] = local
+		}
+		keys, _ := stringset.FromMapKeys(n)
+		for _, key := range keys.Sorted() {
+			if key != "$ref" {
+				if err := b.replaceRefs(n[key], file); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if err := b.replaceRefs(child, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyJSON returns a deep copy of the decoded JSON value v.
+func copyJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			copied[key] = copyJSON(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, child := range v {
+			copied[i] = copyJSON(child)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// bundleExternalRefs copies the schemas that the refs in doc, read from
+// schemaFile, point to in other files into doc's definitions, and points the
+// refs at the copies, so they're generated like doc's own definitions. The
+// files are read relative to the directory of the file that refers to them,
+// and their refs are followed in turn. A schema that several refs point to is
+// copied once.
+func bundleExternalRefs(doc interface{}) error {
+	root, ok := doc.(map[string]interface{})
+	if *schemaFile == "" || !ok {
+		return nil
+	}
+	defs, ok := root["definitions"].(map[string]interface{})
+	if !ok {
+		defs = make(map[string]interface{})
+	}
+	b := &fileBundler{defs: defs, keys: make(map[string]string), docs: make(map[string]interface{})}
+	if err := b.replaceRefs(doc, *schemaFile); err != nil {
+		return err
+	}
+	if len(defs) > 0 {
+		root["definitions"] = defs
+	}
+	return nil
+}
@@ -0,0 +1,1781 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	goimports "golang.org/x/tools/imports"
+
+	"github.com/gedex/inflector"
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+//go:generate schematyper --root-type=metaSchema --prefix=meta --package=gen metaschema.json
+
+type structField struct {
+	Name         string
+	TypeRef      string
+	TypePrefix   string
+	Nullable     bool
+	PropertyName string
+	Required     bool
+	Embedded     bool
+	PtrForOmit   bool
+	ReadOnly     bool
+	WriteOnly    bool
+	Deprecated   bool
+	Recursive    bool
+	OmitEmpty    omitEmpty
+	Secret       bool
+	NullSlice    bool
+	// Pattern is the pattern of the patternProperties that a map field holds
+	Pattern string
+	// Catchall is set on the map field holding the properties of an object
+	// with pattern fields that match none of the patterns
+	Catchall bool
+	// Variant is set on the fields of a union, each holding the value if it
+	// matches one of the union's schemas
+	Variant bool
+	// TagValue is the value of the union's tag property that selects a
+	// variant field
+	TagValue string
+	// Position is the position, counting from 1, of the item that a field of
+	// a tuple holds, or 0 if the field isn't one
+	Position int
+	// RestItems is set on the field of a tuple holding the items after those
+	// with positions, allowed by additionalItems
+	RestItems bool
+	// FixedLen is the length of an array property generated as a Go array
+	// with --fixed-arrays, or 0 if it's a slice
+	FixedLen int
+	// JSONName is the key of the property in the field's tag when
+	// --tag-case makes it different from PropertyName
+	JSONName string
+	// ProtoField is the number of the field in its protobuf tag with
+	// --proto-tags, or 0 if it doesn't have one
+	ProtoField int
+	// DependsOn is the property whose dependent schema adds the field's
+	// property, which only applies when that property is set
+	DependsOn string
+
+	constraints constraints
+	format      string
+	// defaultValue is the default of the field's property, or nil if it has
+	// none that's set with --defaults
+	defaultValue interface{}
+}
+
+// omitEmpty overrides whether a field's tag gets omitempty.
+type omitEmpty int
+
+const (
+	omitEmptyDefault omitEmpty = iota
+	omitEmptyForce
+	omitEmptyNever
+)
+
+func getOmitEmpty(s *metaSchema) omitEmpty {
+	switch {
+	case s.XGoOmitempty == nil:
+		return omitEmptyDefault
+	case *s.XGoOmitempty:
+		return omitEmptyForce
+	default:
+		return omitEmptyNever
+	}
+}
+
+// omitsEmpty returns true if the field's tag should have omitempty.
+func (sf structField) omitsEmpty() bool {
+	switch sf.OmitEmpty {
+	case omitEmptyForce:
+		return true
+	case omitEmptyNever:
+		return false
+	}
+	// readOnly fields are always returned, so don't omit them
+	return !sf.Required && !sf.ReadOnly
+}
+
+// typeString returns the Go type of the field as printed and whether it was
+// made a pointer because the field may be omitted.
+func (sf structField) typeString() (typeStr string, isPtr bool) {
+	typeStr = sf.TypePrefix
+	if baseType, ok := types[sf.TypeRef]; ok {
+		if baseType.Inline {
+			typeStr += baseType.inlineString()
+		} else {
+			typeStr += baseType.Name
+		}
+	}
+
+	// a union holds its variants through pointers, set for the one it holds
+	if sf.Recursive || sf.Variant {
+		return "*" + typeStr, true
+	}
+
+	// a tuple holds the items that may be missing through pointers, and its
+	// fields are never omitted otherwise
+	if sf.Position > 0 {
+		if !sf.Required && !sf.RestItems && sf.TypePrefix != typeEmptyInterface {
+			return "*" + typeStr, true
+		}
+		return typeStr, false
+	}
+
+	if sf.NullSlice {
+		return nullSliceType(typeStr), false
+	}
+
+	if sf.FixedLen > 0 {
+		typeStr = fmt.Sprintf("[%d]%s", sf.FixedLen, strings.TrimPrefix(typeStr, "[]"))
+	}
+
+	if !sf.Embedded && !sf.Required && !isSQLNullType(sf.TypePrefix) {
+		// a union without a variant is marshaled as null, and omitempty never
+		// omits a struct, so an optional one is a pointer for it to be omitted
+		if baseType, ok := types[sf.TypeRef]; ok && baseType.union != "" && sf.TypePrefix == "" {
+			return "*" + typeStr, true
+		}
+		if (*ptrForOmit && sf.TypePrefix != "[]*" && sf.TypePrefix != typeBool) ||
+			(*ptrForOmit && sf.PtrForOmit && !sf.Nullable) {
+			return "*" + typeStr, true
+		}
+	}
+	return typeStr, false
+}
+
+type structFields []structField
+
+func (s structFields) Len() int {
+	return len(s)
+}
+
+func (s structFields) Less(i, j int) bool {
+	// the fields of a tuple are in the order of its items
+	if s[i].Position > 0 && s[j].Position > 0 {
+		return s[i].Position < s[j].Position
+	}
+	if s[i].Name == s[j].Name {
+		return s[i].PropertyName < s[j].PropertyName
+	}
+	return s[i].Name < s[j].Name
+}
+
+func (s structFields) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+type goType struct {
+	Name       string
+	TypeRef    string
+	TypePrefix string
+	Nullable   bool
+	Fields     structFields
+	Comment    string
+	Alias      bool
+	// Inline is set with --inline-depth on a nested struct that's written as
+	// an anonymous struct in the field of its parent
+	Inline bool
+	// Deprecated is set if the schema of the type is marked deprecated
+	Deprecated bool
+
+	path             string
+	parentPath       string
+	origTypeName     string
+	constraints      constraints
+	enumValues       []string
+	enumVarnames     []string
+	enumDescriptions []string
+	enumConsts       []enumConst
+	enumValuesVar    string
+	wrappedField     *structField
+	examples         []interface{}
+	// union is the keyword, oneOf or anyOf, of the schemas a union type holds
+	// the value as, and unionTag the property that tells them apart if there
+	// is one
+	union    string
+	unionTag string
+	// tuple is set on the struct type of an array with a schema for the item
+	// at each position, which its fields hold
+	tuple bool
+	// set is set on the map type of an array with uniqueItems generated as a
+	// set with --unique-sets
+	set bool
+	// conditional holds the checks of the if/then/else of a struct's schema,
+	// and dependentRequired the properties that require others
+	conditional       *conditional
+	dependentRequired []dependentRequirement
+	// notRequired holds the properties that the not schema of a struct's
+	// schema forbids to be set together
+	notRequired []dependentRequirement
+}
+
+// writeDeprecated writes the Deprecated paragraph of the doc comment of a
+// type or field marked deprecated by its schema, what it is, after the rest
+// of the comment if there is one, so staticcheck and editors flag its uses.
+func writeDeprecated(buf *bytes.Buffer, what string, commented bool) {
+	if commented {
+		buf.WriteString("//\n")
+	}
+	buf.WriteString(fmt.Sprintf("// Deprecated: The schema marks this %s as deprecated.\n", what))
+}
+
+// print writes the declaration of gt to buf, adding the packages used by the
+// printed types to imports.
+func (gt goType) print(buf *bytes.Buffer, imports stringset.StringSet) {
+	if gt.Comment != "" {
+		commentLines := strings.Split(gt.Comment, "\n")
+		for _, line := range commentLines {
+			buf.WriteString(fmt.Sprintf("// %s\n", line))
+		}
+	}
+	if gt.Deprecated {
+		writeDeprecated(buf, "type", gt.Comment != "")
+	}
+	typeStr := gt.TypePrefix
+	baseType, ok := types[gt.TypeRef]
+	if ok {
+		typeStr += baseType.Name
+	}
+	addImports(typeStr, imports)
+	if gt.Alias {
+		buf.WriteString(fmt.Sprintf("type %s = %s\n", gt.Name, typeStr))
+		return
+	}
+	buf.WriteString(fmt.Sprintf("type %s %s", gt.Name, typeStr))
+	if typeStr != typeStruct {
+		buf.WriteString("\n")
+		gt.printEnumConsts(buf)
+		return
+	}
+	buf.WriteString(" {\n")
+	gt.printFields(buf, imports)
+	buf.WriteString("}\n")
+}
+
+// printFields writes the fields of gt, a struct, to buf.
+func (gt goType) printFields(buf *bytes.Buffer, imports stringset.StringSet) {
+	sort.Stable(gt.Fields)
+	for _, sf := range gt.Fields {
+		sfTypeStr, _ := sf.typeString()
+		// the comment of an anonymous struct is written on its field, so its
+		// field is only marked deprecated with it
+		var commented bool
+		if refType, ok := types[sf.TypeRef]; ok && refType.Inline {
+			refType.inlineImports(imports)
+			if refType.Comment != "" {
+				for _, line := range strings.Split(refType.Comment, "\n") {
+					buf.WriteString(fmt.Sprintf("// %s\n", line))
+				}
+				commented = true
+			}
+			if refType.Deprecated {
+				writeDeprecated(buf, "property", commented)
+				sf.Deprecated = false
+			}
+		} else {
+			addImports(sfTypeStr, imports)
+		}
+
+		// an embedded type has no tag so that its fields are promoted
+		if sf.Embedded {
+			buf.WriteString(sfTypeStr + "\n")
+			continue
+		}
+
+		// variant, tuple and pattern fields are marshaled by the type's
+		// MarshalJSON
+		if sf.Variant || sf.Position > 0 {
+			buf.WriteString(fmt.Sprintf("%s %s `json:\"-\"`\n", sf.Name, sfTypeStr))
+			continue
+		}
+		if sf.Pattern != "" || sf.Catchall {
+			if sf.Pattern != "" {
+				buf.WriteString(fmt.Sprintf("// %s holds the properties matching %s.\n", sf.Name, sf.Pattern))
+			} else {
+				buf.WriteString(fmt.Sprintf("// %s holds the properties matching no pattern.\n", sf.Name))
+			}
+			buf.WriteString(fmt.Sprintf("%s %s `json:\"-\"`\n", sf.Name, sfTypeStr))
+			continue
+		}
+
+		if sf.DependsOn != "" {
+			buf.WriteString(fmt.Sprintf("// %s only applies when %s is set.\n", sf.Name, sf.DependsOn))
+			commented = true
+		}
+		if *fieldDocLinks {
+			if refType, ok := types[sf.TypeRef]; ok && refType.TypePrefix == typeStruct && !refType.Inline {
+				buf.WriteString(fmt.Sprintf("// See [%s].\n", refType.Name))
+				commented = true
+			}
+		}
+		if sf.Deprecated {
+			writeDeprecated(buf, "property", commented)
+		}
+
+		var omitOption string
+		if sf.omitsEmpty() {
+			if sf.NullSlice {
+				omitOption = "omitzero"
+			} else {
+				omitOption = "omitempty"
+			}
+		}
+		tag := sf.tag(omitOption)
+		if *validatorTags {
+			if validateTag := sf.validatorTag(); validateTag != "" {
+				tag += ` validate:"` + validateTag + `"`
+			}
+		}
+		if *protoTags && sf.ProtoField > 0 {
+			tag += " " + sf.protoTag()
+		}
+		tagString := "`" + tag + "`"
+
+		buf.WriteString(fmt.Sprintf("%s %s %s\n", sf.Name, sfTypeStr, tagString))
+	}
+	if gt.preservesUnknown() {
+		imports.Add("encoding/json")
+		buf.WriteString("\n// raw holds the properties that aren't fields, to be marshaled again\n")
+		buf.WriteString("raw map[string]json.RawMessage\n")
+	}
+}
+
+type goTypes []goType
+
+func (t goTypes) Len() int {
+	return len(t)
+}
+
+func (t goTypes) Less(i, j int) bool {
+	return t[i].Name < t[j].Name
+}
+
+func (t goTypes) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
+// importPaths maps the package names that can appear in generated types to
+// their import paths. Those of the types of format mappings are added as
+// they're used.
+var importPaths = defaultImportPaths()
+
+// defaultImportPaths returns the import paths of the packages of the built-in
+// types.
+func defaultImportPaths() map[string]string {
+	return map[string]string{
+		"time": "time",
+		"sql":  "database/sql",
+	}
+}
+
+var qualifiedIdentRegexp = regexp.MustCompile(`([\p{L}_][\p{L}\p{N}_]*)\.`)
+
+// addImports adds the packages referenced by the Go type typeStr to imports.
+// Imports are only ever added for types that are actually printed, so a type
+// replaced after processing doesn't leave an unused import behind.
+func addImports(typeStr string, imports stringset.StringSet) {
+	for _, match := range qualifiedIdentRegexp.FindAllStringSubmatch(typeStr, -1) {
+		if path, ok := importPaths[match[1]]; ok {
+			imports.Add(path)
+		}
+	}
+}
+
+const (
+	typeString              = "string"
+	typeInteger             = "integer"
+	typeInt                 = "int64"
+	typeNumber              = "number"
+	typeFloat64             = "float64"
+	typeBoolean             = "boolean"
+	typeBool                = "bool"
+	typeNull                = "null"
+	typeNil                 = "nil"
+	typeObject              = "object"
+	typeArray               = "array"
+	typeEmptyInterface      = "interface{}"
+	typeEmptyInterfaceSlice = "[]interface{}"
+	typeEmptyStructSlice    = "[]struct{}"
+	typeTime                = "time.Time"
+	typeBytes               = "[]byte"
+	typeStruct              = "struct"
+)
+
+var typeStrings = map[string]string{
+	typeString:  typeString,
+	typeInteger: typeInt,
+	typeNumber:  typeFloat64,
+	typeBoolean: typeBool,
+	typeNull:    typeNil,
+	typeObject:  typeObject,
+	typeArray:   typeArray,
+}
+
+// formatTypes maps string formats to the Go types used for them. Formats that
+// aren't in the map keep the type given by the schema.
+var formatTypes = map[string]string{
+	"date-time": typeTime,
+	"byte":      typeBytes,
+}
+
+// decimalFormats are the formats of numbers that are mapped to --decimal-type.
+var decimalFormats = []string{"decimal", "currency"}
+
+// scalarJSONType returns the JSON type represented by the Go type typePrefix
+// if it's a scalar, or "" otherwise.
+func scalarJSONType(typePrefix string) string {
+	switch typePrefix {
+	case typeString:
+		return typeString
+	case typeInt:
+		return typeInteger
+	case typeFloat64:
+		return typeNumber
+	case typeBool:
+		return typeBoolean
+	default:
+		return ""
+	}
+}
+
+// getTypeString returns the Go type of s, a schema of the JSON type jsonType,
+// given by its format if it has one that's mapped to a type, or otherwise by
+// jsonType. A string with contentEncoding base64 is a []byte, which
+// encoding/json encodes and decodes as base64.
+func getTypeString(jsonType string, s *metaSchema) string {
+	format := s.Format
+	if !formatVocabulary {
+		format = ""
+	}
+	if m, ok := formatMapping(format); ok {
+		return m.GoType
+	}
+	if ts, ok := formatTypes[format]; ok {
+		return ts
+	}
+	if jsonType == typeString && strings.EqualFold(s.ContentEncoding, "base64") {
+		return typeBytes
+	}
+
+	if ts, ok := typeStrings[jsonType]; ok {
+		return ts
+	}
+	return typeEmptyInterface
+}
+
+// copied from golint (https://github.com/golang/lint/blob/4946cea8b6efd778dc31dc2dbeb919535e1b7529/lint.go#L701)
+var commonInitialisms = stringset.New(
+	"API",
+	"ASCII",
+	"CPU",
+	"CSS",
+	"DNS",
+	"EOF",
+	"GUID",
+	"HTML",
+	"HTTP",
+	"HTTPS",
+	"ID",
+	"IP",
+	"JSON",
+	"LHS",
+	"QPS",
+	"RAM",
+	"RHS",
+	"RPC",
+	"SLA",
+	"SMTP",
+	"SQL",
+	"SSH",
+	"TCP",
+	"TLS",
+	"TTL",
+	"UDP",
+	"UI",
+	"UID",
+	"UUID",
+	"URI",
+	"URL",
+	"UTF8",
+	"VM",
+	"XML",
+	"XSRF",
+	"XSS",
+)
+
+func dashedToWords(s string) string {
+	return regexp.MustCompile("-|_").ReplaceAllString(s, " ")
+}
+
+func camelCaseToWords(s string) string {
+	return regexp.MustCompile(`([\p{Ll}\p{N}])(\p{Lu})`).ReplaceAllString(s, "$1 $2")
+}
+
+func getExportedIdentifierPart(part string) string {
+	upperedPart := strings.ToUpper(part)
+	if commonInitialisms.Has(upperedPart) {
+		return upperedPart
+	}
+	return strings.Title(strings.ToLower(part))
+}
+
+func generateIdentifier(origName string, exported bool) string {
+	spacedName := camelCaseToWords(dashedToWords(origName))
+	titledName := strings.Title(spacedName)
+	nameParts := strings.Split(titledName, " ")
+	for i, part := range nameParts {
+		nameParts[i] = getExportedIdentifierPart(part)
+	}
+	if !exported {
+		nameParts[0] = strings.ToLower(nameParts[0])
+	}
+	rawName := strings.Join(nameParts, "")
+
+	// make sure we build a valid identifier
+	buf := &bytes.Buffer{}
+	for pos, char := range rawName {
+		if unicode.IsLetter(char) || char == '_' || (unicode.IsDigit(char) && pos > 0) {
+			buf.WriteRune(char)
+		}
+	}
+
+	return buf.String()
+}
+
+func generateTypeName(origName string) string {
+	if *packageName != "main" || *typeNamesPrefix != "" {
+		return *typeNamesPrefix + generateIdentifier(origName, true)
+	}
+
+	return generateIdentifier(origName, true)
+}
+
+func generateFieldName(origName string) string {
+	return generateIdentifier(origName, true)
+}
+
+func getTypeSchema(typeInterface interface{}) *metaSchema {
+	typeSchemaJSON, _ := json.Marshal(typeInterface)
+	var typeSchema metaSchema
+	json.Unmarshal(typeSchemaJSON, &typeSchema)
+	return &typeSchema
+}
+
+func getTypeSchemas(typeInterface interface{}) map[string]*metaSchema {
+	typeSchemasJSON, _ := json.Marshal(typeInterface)
+	var typeSchemas map[string]*metaSchema
+	json.Unmarshal(typeSchemasJSON, &typeSchemas)
+	return typeSchemas
+}
+
+func singularize(plural string) string {
+	singular := inflector.Singularize(plural)
+	if singular == plural {
+		singular += "Item"
+	}
+	return singular
+}
+
+// boolItemsType returns the type of an array with a boolean items schema:
+// true allows any items, while false allows none, so the array is always empty.
+func boolItemsType(items bool) string {
+	if items {
+		return typeEmptyInterfaceSlice
+	}
+	return typeEmptyStructSlice
+}
+
+// mapValueName returns the name for the type of the values of a map named
+// name: the singular of its last word if that's a plural, like user for users,
+// or name and value otherwise, like metadata value or status value, which a
+// singularizer may mangle. siblings holds the identifiers of the properties
+// next to the map; if the name would be one of them, name and value is used
+// instead, since types with the same parent can't be told apart by it.
+func mapValueName(name string, siblings stringset.StringSet) string {
+	words := strings.Fields(camelCaseToWords(dashedToWords(name)))
+	if len(words) == 0 {
+		return "value"
+	}
+
+	last := words[len(words)-1]
+	lower := strings.ToLower(last)
+	isPlural := strings.HasSuffix(last, "s") && !strings.HasSuffix(lower, "us") &&
+		!strings.HasSuffix(lower, "ss") && !strings.HasSuffix(lower, "is")
+	if singular := inflector.Singularize(last); isPlural && singular != last {
+		valueName := strings.Join(append(words[:len(words)-1:len(words)-1], singular), " ")
+		if !siblings.Has(generateIdentifier(valueName, true)) {
+			return valueName
+		}
+	}
+	return strings.Join(words, " ") + " value"
+}
+
+func parseAdditionalProperties(ap interface{}) (hasAddl bool, addlSchema *metaSchema) {
+	switch ap := ap.(type) {
+	case bool:
+		return ap, nil
+	case map[string]interface{}:
+		// an empty schema allows any value, the same as true
+		if isEmptySchema(ap) {
+			return true, nil
+		}
+		return true, getTypeSchema(ap)
+	default:
+		return
+	}
+}
+
+// isEmptySchema returns true if v is the empty schema {}, which allows any
+// value.
+func isEmptySchema(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	return ok && len(m) == 0
+}
+
+// processAdditionalProperties returns the type of the values of a map with the
+// given additionalProperties schema. A $ref is looked up directly, the same as
+// for properties, so it doesn't get deferred separately from its parent.
+func processAdditionalProperties(s *metaSchema, name, desc, path, parentPath string) (typeRef string, err error) {
+	resolveRecursiveRef(s, parentPath)
+	if s.Ref == "" {
+		return processType(s, name, desc, path, parentPath)
+	}
+
+	if ref, ok := resolveRef(s.Ref); ok {
+		return ref, nil
+	}
+	return "", nil
+}
+
+// resolveRef returns the path of the type that ref refers to, following a ref
+// to a schema that is itself just a ref through to the final type, and whether
+// that type has been processed yet.
+func resolveRef(ref string) (string, bool) {
+	if target, ok := transitiveRefs[ref]; ok {
+		ref = target
+	}
+	_, ok := types[ref]
+	return ref, ok
+}
+
+// refCycle returns the chain of refs starting at path if the deferred schemas
+// along it only refer to each other, or nil otherwise.
+func refCycle(path string) []string {
+	seen := make(map[string]bool)
+	var chain []string
+	for {
+		chain = append(chain, path)
+		if seen[path] {
+			return chain
+		}
+		seen[path] = true
+
+		deferred, ok := deferredTypes[path]
+		if !ok || deferred.schema.Ref == "" {
+			return nil
+		}
+		path = deferred.schema.Ref
+	}
+}
+
+type deferredType struct {
+	schema     *metaSchema
+	name       string
+	desc       string
+	parentPath string
+}
+
+// deferType defers processing the schema at path until the type at waitingOn,
+// the path or ref of a schema it needs, has been processed.
+func deferType(path string, deferred deferredType, waitingOn string) {
+	logf("%s: deferred, waiting on %s", path, waitingOn)
+	deferredTypes[path] = deferred
+}
+
+// refOrPath returns the ref of s if it has one, or otherwise its path.
+func refOrPath(s *metaSchema, path string) string {
+	if s.Ref != "" {
+		return s.Ref
+	}
+	return path
+}
+
+type stringSetMap map[string]stringset.StringSet
+
+func (m stringSetMap) addTo(set, val string) {
+	if m[set] == nil {
+		m[set] = stringset.New()
+	}
+	m[set].Add(val)
+}
+
+func (m stringSetMap) removeFrom(set, val string) {
+	if m[set] == nil {
+		return
+	}
+	m[set].Remove(val)
+}
+
+func (m stringSetMap) existsIn(set, val string) bool {
+	if m[set] == nil {
+		return false
+	}
+	return m[set].Has(val)
+}
+
+func (m stringSetMap) delete(set string) {
+	delete(m, set)
+}
+
+func (m stringSetMap) has(set string) bool {
+	_, ok := m[set]
+	return ok
+}
+
+var types = make(map[string]goType)
+var deferredTypes = make(map[string]deferredType)
+var typesByName = make(stringSetMap)
+var transitiveRefs = make(map[string]string)
+var recursiveAnchors = make(map[string]string)
+
+// origTypeNames holds the names that the types are generated from by path, as
+// soon as they're known, so that nested types can be named after them.
+var origTypeNames = make(map[string]string)
+
+// The namings of nested types that --nested-names accepts.
+const (
+	nestedNamesLeaf = "leaf"
+	nestedNamesPath = "path"
+)
+
+// propertySchemas holds the schemas of the properties of the processed types by
+// path, so that a $ref to a scalar property, which doesn't get a type of its
+// own, can use its schema.
+var propertySchemas = make(map[string]*metaSchema)
+
+// scalarProperty returns a copy of the schema of the property at ref if it's
+// that of a scalar.
+func scalarProperty(ref string) (*metaSchema, bool) {
+	ps, ok := propertySchemas[ref]
+	if !ok || ps.Ref != "" || ps.RecursiveRef != "" || ps.DynamicRef != "" {
+		return nil, false
+	}
+
+	var jsonTypes []interface{}
+	switch t := ps.Type.(type) {
+	case string:
+		jsonTypes = []interface{}{t}
+	case []interface{}:
+		jsonTypes = t
+	default:
+		return nil, false
+	}
+	for _, t := range jsonTypes {
+		if t == typeObject || t == typeArray {
+			return nil, false
+		}
+	}
+
+	target := *ps
+	return &target, true
+}
+
+// maxFixedLen is the longest array property generated as a Go array with
+// --fixed-arrays; longer ones are still slices.
+const maxFixedLen = 32
+
+// setFixedLen makes sf, a required array property, a Go array of values if
+// its minItems and maxItems are equal and at most maxFixedLen. The array's
+// type guarantees its length, so they're no longer checked. Optional
+// properties stay slices, since omitempty never leaves out a Go array.
+func (sf *structField) setFixedLen() {
+	c := sf.constraints
+	if !sf.Required || c.MinItems == nil || c.MaxItems == nil || *c.MinItems != *c.MaxItems || *c.MinItems <= 0 || *c.MinItems > maxFixedLen {
+		return
+	}
+	sf.FixedLen = *c.MinItems
+	sf.constraints.MinItems, sf.constraints.MaxItems = nil, nil
+	if sf.TypePrefix == "[]*" {
+		sf.TypePrefix = "[]"
+	}
+}
+
+// isDefinitionPath returns true if path is that of a schema under definitions
+// or $defs.
+func isDefinitionPath(path string) bool {
+	parent := parentSchemaPath(path)
+	return strings.HasSuffix(parent, "/definitions") || strings.HasSuffix(parent, "/$defs") || pointedDefinitions.Has(path)
+}
+
+func parentSchemaPath(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// resolveRecursiveRef turns a $recursiveRef or $dynamicRef in s into a plain
+// $ref to the nearest enclosing schema with a matching $recursiveAnchor or
+// $dynamicAnchor, or to the root if there is none. This doesn't implement the
+// full dynamic scope semantics, but covers self-referencing schemas like the
+// meta-schemas.
+func resolveRecursiveRef(s *metaSchema, path string) {
+	if s.RecursiveRef == "" && s.DynamicRef == "" {
+		return
+	}
+
+	var anchor string
+	if s.DynamicRef != "" {
+		if i := strings.Index(s.DynamicRef, "#"); i >= 0 {
+			anchor = s.DynamicRef[i+1:]
+		}
+	}
+
+	s.Ref = rootPath
+	for p := path; p != ""; p = parentSchemaPath(p) {
+		if name, ok := recursiveAnchors[p]; ok && name == anchor {
+			s.Ref = p
+			break
+		}
+	}
+	s.RecursiveRef, s.DynamicRef = "", ""
+}
+
+func processType(s *metaSchema, pName, pDesc, path, parentPath string) (typeRef string, err error) {
+	if _, ok := encounterOrder[path]; !ok {
+		encounterOrder[path] = len(encounterOrder)
+	}
+	if s.RecursiveAnchor {
+		recursiveAnchors[path] = ""
+	} else if s.DynamicAnchor != "" {
+		recursiveAnchors[path] = s.DynamicAnchor
+	}
+	resolveRecursiveRef(s, parentPath)
+
+	if len(s.Definitions) > 0 || len(s.Defs) > 0 {
+		if err := parseDefs(s, path); err != nil {
+			return "", err
+		}
+	}
+
+	var gt goType
+	if variant, ok := nullableVariant(s); ok {
+		s = variant
+		gt.Nullable = true
+		resolveRecursiveRef(s, parentPath)
+	}
+
+	// a scalar property doesn't have a type of its own, so a ref to one is
+	// generated from the property's schema
+	if s.Ref != "" {
+		if _, ok := resolveRef(s.Ref); !ok {
+			if target, ok := scalarProperty(s.Ref); ok {
+				s = target
+			}
+		}
+	}
+
+	var aliasOf string
+	if s.Ref != "" {
+		ref, ok := resolveRef(s.Ref)
+		if !ok {
+			deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, s.Ref)
+			return "", nil
+		}
+		// with --aliases a definition that's just a ref keeps its name as an
+		// alias of the type it refers to
+		if !*aliases || !isDefinitionPath(path) {
+			logf("%s: refers to %s", path, ref)
+			transitiveRefs[path] = ref
+			return ref, nil
+		}
+		aliasOf = ref
+	}
+
+	gt.path = path
+	gt.parentPath = parentPath
+	gt.constraints = getConstraints(s)
+	gt.examples = s.Examples
+
+	if path == rootPath {
+		gt.origTypeName = *rootTypeName
+		gt.Name = *rootTypeName
+		if *prefixRoot && *typeNamesPrefix != "" {
+			gt.Name = generateTypeName(*rootTypeName)
+		}
+	} else {
+		/*		gt.origTypeName = s.Title
+				if gt.origTypeName == "" {
+					gt.origTypeName = pName
+				}*/
+		gt.origTypeName = pName
+		// with --nested-names=path an inline schema is named after the ones
+		// it's nested in, up to the root or a definition
+		if *nestedNames == nestedNamesPath && !isDefinitionPath(path) && parentPath != rootPath {
+			if parentName, ok := origTypeNames[parentPath]; ok {
+				gt.origTypeName = parentName + " " + pName
+			}
+		}
+
+		if gt.Name = generateTypeName(gt.origTypeName); gt.Name == "" {
+			return "", &nameError{Path: path, Name: gt.origTypeName, Kind: "type"}
+		}
+	}
+
+	typeRef = path
+	origTypeNames[path] = gt.origTypeName
+
+	gt.Comment = s.Description
+	if gt.Comment == "" {
+		gt.Comment = pDesc
+	}
+	gt.Comment = applySidecarComment(gt.Comment, path)
+	gt.Deprecated = s.Deprecated
+
+	required := stringset.New()
+	for _, req := range s.Required {
+		required.Add(string(req))
+	}
+	fieldsRequired := allOfRequired(s)
+
+	defer func() {
+		// avoid 'recursive type' problem, at least for the root type; a
+		// primitive root is only nullable if its schema says so
+		if path == rootPath && gt.TypePrefix == typeStruct {
+			gt.Nullable = true
+		}
+		types[path] = gt
+		typesByName.addTo(gt.Name, path)
+		if typeRef != "" && err == nil {
+			gt.logDecisions()
+		}
+	}()
+
+	if aliasOf != "" {
+		gt.TypeRef = aliasOf
+		gt.Nullable = gt.Nullable || types[aliasOf].Nullable
+		gt.Alias = true
+		return
+	}
+
+	var jsonType string
+	switch schemaType := s.Type.(type) {
+	case []interface{}:
+		// a lone type in an array is the same as the type by itself
+		if len(schemaType) == 1 && schemaType[0] != typeNull {
+			jsonType, _ = schemaType[0].(string)
+		}
+		if len(schemaType) == 2 && (schemaType[0] == typeNull || schemaType[1] == typeNull) {
+			gt.Nullable = true
+
+			jsonType = schemaType[0].(string)
+			if jsonType == typeNull {
+				jsonType = schemaType[1].(string)
+			}
+		}
+	case string:
+		jsonType = schemaType
+	}
+
+	// an enum of strings or integers doesn't need an explicit type
+	if jsonType == "" && len(enumStrings(s)) > 0 {
+		jsonType = typeString
+	} else if jsonType == "" && len(enumInts(s)) > 0 {
+		jsonType = typeInteger
+	}
+
+	enumValues := enumStrings(s)
+	if jsonType == typeInteger {
+		enumValues = enumInts(s)
+	}
+	hasAllOf := len(s.AllOf) > 0
+	if hasAllOf {
+		var scalarType string
+		hasEnum := len(enumValues) > 0
+		for index, allOfSchema := range s.AllOf {
+			childPath := fmt.Sprintf("%s/allOf/%d", path, index)
+			if allOfSchema.Ref == "" {
+				allOfSchema.Required = requireDefined(&allOfSchema, fieldsRequired)
+			}
+			gotType, err := processType(&allOfSchema, fmt.Sprintf("%sEmbedded%d", pName, index), allOfSchema.Description, childPath, path)
+			if err != nil {
+				return "", err
+			}
+			if gotType == "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, childPath)
+				return "", nil
+			}
+			childType := types[gotType]
+			// if any chid is an object, the parent is an object
+			if childType.TypePrefix == "struct" {
+				jsonType = "object"
+			}
+			// if any child is nullable, the parent is nullable
+			if childType.Nullable {
+				gt.Nullable = true
+			}
+			if childJSONType := scalarJSONType(childType.TypePrefix); childJSONType != "" {
+				scalarType = childJSONType
+			}
+			// the parent can only take values allowed by every child's enum
+			if len(childType.enumValues) > 0 {
+				if hasEnum {
+					enumValues = intersectStrings(enumValues, childType.enumValues)
+				} else {
+					enumValues = childType.enumValues
+				}
+				hasEnum = true
+			}
+		}
+
+		// an allOf of scalars, such as a $ref to an enum narrowed by another
+		// enum, is a scalar itself
+		if jsonType == "" && scalarType != "" {
+			jsonType = scalarType
+		}
+	}
+
+	props := getTypeSchemas(s.Properties)
+	props, addedPaths := addBranchProperties(s, props, path)
+	props, addedPaths, dependsOn := addDependentProperties(s, props, addedPaths, path)
+	hasProps := len(props) > 0
+
+	// nor does an object with properties
+	if jsonType == "" && (hasProps || len(s.PatternProperties) > 0) {
+		jsonType = typeObject
+	}
+	// nor does a union, which is a struct holding one of its variants
+	unionKeyword, variants := unionVariants(s, jsonType)
+	if len(variants) > 0 {
+		jsonType = typeObject
+	}
+	hasAddlProps, addlPropsSchema := parseAdditionalProperties(s.AdditionalProperties)
+
+	ts := getTypeString(jsonType, s)
+	switch ts {
+	case typeObject:
+		if len(variants) > 0 {
+			gt.TypePrefix = typeStruct
+			waitingOn, err := gt.addVariantFields(variants, unionKeyword, path)
+			if err != nil {
+				return "", err
+			}
+			if waitingOn != "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, waitingOn)
+				return "", nil
+			}
+		} else if patterns := multiPatterns(s); patterns != nil && !hasAllOf {
+			gt.TypePrefix = typeStruct
+			waitingOn, err := gt.addPatternFields(s, patterns, path)
+			if err != nil {
+				return "", err
+			}
+			if waitingOn != "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, waitingOn)
+				return "", nil
+			}
+		} else if pattern, valueSchema := singlePattern(s); valueSchema != nil && !hasAllOf && !isEmptyMetaSchema(valueSchema) {
+			valueName := mapValueName(gt.origTypeName, stringset.New())
+			valuePath := path + "/patternProperties/" + escapePointerToken(pattern)
+			gotType, err := processAdditionalProperties(valueSchema, valueName, s.Description, valuePath, path)
+			if err != nil {
+				return "", err
+			}
+			if gotType == "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refOrPath(valueSchema, valuePath))
+				return "", nil
+			}
+			gt.TypePrefix = "map[string]"
+			gt.TypeRef = gotType
+		} else if (hasProps || hasAllOf) && !hasAddlProps {
+			gt.TypePrefix = typeStruct
+		} else if !hasProps && !hasAllOf && hasAddlProps && addlPropsSchema != nil {
+			// the map is the parent of its value type, which allocateNames can name
+			// after it
+			valueName := mapValueName(gt.origTypeName, stringset.New())
+			gotType, err := processAdditionalProperties(addlPropsSchema, valueName, s.Description, path+"/additionalProperties", path)
+			if err != nil {
+				return "", err
+			}
+			if gotType == "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refOrPath(addlPropsSchema, path+"/additionalProperties"))
+				return "", nil
+			}
+			gt.TypePrefix = "map[string]"
+			gt.TypeRef = gotType
+		} else {
+			gt.TypePrefix = "map[string]interface{}"
+		}
+	case typeArray:
+		if keyType, ok := setKeyType(s); ok {
+			gt.TypePrefix = "map[" + keyType + "]struct{}"
+			gt.set = true
+			break
+		}
+		switch arrayItemType := s.Items.(type) {
+		case []interface{}:
+			// a single schema is that of every item, unless additionalItems
+			// gives the items after it one of their own
+			hasAddlItems, addlItemsSchema := tupleAdditionalItems(s)
+			if len(arrayItemType) == 1 && addlItemsSchema == nil {
+				singularName := singularize(gt.origTypeName)
+				typeSchema := getTypeSchema(arrayItemType[0])
+				gotType, err := processType(typeSchema, singularName, s.Description, path+"/items/0", path)
+				if err != nil {
+					return "", err
+				}
+				if gotType == "" {
+					deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, path+"/items/0")
+					return "", nil
+				}
+				gt.TypePrefix = "[]"
+				gt.TypeRef = gotType
+			} else if len(arrayItemType) == 0 {
+				gt.TypePrefix = typeEmptyInterfaceSlice
+			} else {
+				var waitingOn string
+				var err error
+				if itemSchema, ok := sameItemSchema(arrayItemType); ok && !hasAddlItems {
+					waitingOn, err = gt.setFixedArray(itemSchema, len(arrayItemType), path)
+				} else {
+					gt.TypePrefix = typeStruct
+					waitingOn, err = gt.addTupleFields(s, arrayItemType, path)
+				}
+				if err != nil {
+					return "", err
+				}
+				if waitingOn != "" {
+					deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, waitingOn)
+					return "", nil
+				}
+			}
+		case bool:
+			gt.TypePrefix = boolItemsType(arrayItemType)
+		case interface{}:
+			if isEmptySchema(arrayItemType) {
+				// an empty schema allows any item, the same as no items
+				gt.TypePrefix = typeEmptyInterfaceSlice
+				break
+			}
+			singularName := singularize(gt.origTypeName)
+			typeSchema := getTypeSchema(arrayItemType)
+			gotType, err := processType(typeSchema, singularName, s.Description, path+"/items", path)
+			if err != nil {
+				return "", err
+			}
+			if gotType == "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, path+"/items")
+				return "", nil
+			}
+			gt.TypePrefix = "[]"
+			gt.TypeRef = gotType
+		default:
+			gt.TypePrefix = typeEmptyInterfaceSlice
+		}
+	default:
+		gt.TypePrefix = ts
+		if ts == typeString || ts == typeInt {
+			gt.enumValues = enumValues
+		}
+		if varnames, descriptions := enumExtensions(s); len(varnames) == len(gt.enumValues) {
+			gt.enumVarnames, gt.enumDescriptions = varnames, descriptions
+		}
+		// with --aliases a definition that's just a primitive is an alias of
+		// it, unless it needs methods for an enum or validation
+		if *aliases && isDefinitionPath(path) && len(gt.enumValues) == 0 && gt.constraints == (constraints{}) {
+			gt.Alias = true
+		}
+	}
+
+	fieldNames := stringset.New()
+	jsonNames := make(map[string]string)
+	propNames, _ := stringset.FromMapKeys(props)
+	propIdents := stringset.New()
+	for _, propName := range propNames.Sorted() {
+		propIdents.Add(generateIdentifier(propName, true))
+	}
+	for _, propName := range propNames.Sorted() {
+		propSchema := props[propName]
+		sf := structField{
+			PropertyName: propName,
+			Required:     fieldsRequired.Has(propName),
+			ReadOnly:     propSchema.ReadOnly,
+			WriteOnly:    propSchema.WriteOnly,
+			Deprecated:   propSchema.Deprecated,
+			OmitEmpty:    getOmitEmpty(propSchema),
+			Secret:       propSchema.WriteOnly || propSchema.XGoSecret,
+			constraints:  getConstraints(propSchema),
+			format:       propSchema.Format,
+			ProtoField:   int(propSchema.XProtoField),
+			defaultValue: propSchema.Default,
+		}
+
+		if !sf.Required {
+			sf.Nullable = true
+		}
+
+		/*
+			var fieldName string
+			if propSchema.Title != "" {
+				fieldName = propSchema.Title
+			} else {
+				fieldName = propName
+			}*/
+		if sf.Name = generateFieldName(propName); sf.Name == "" {
+			return "", &nameError{Path: path + "/properties/" + propName, Name: propName, Kind: "field"}
+		}
+		// different property names can make the same identifier, like
+		// "user_id" and "userId", so number the later ones
+		baseName := sf.Name
+		for n := 2; fieldNames.Has(sf.Name); n++ {
+			sf.Name = fmt.Sprintf("%s%d", baseName, n)
+		}
+		fieldNames.Add(sf.Name)
+
+		// with --tag-case different properties can get the same key, which
+		// encoding/json would then ignore both of
+		if jsonName := tagName(propName); jsonName != propName {
+			sf.JSONName = jsonName
+		}
+		if other, ok := jsonNames[sf.jsonName()]; ok {
+			return "", fmt.Errorf("%s: properties %q and %q both have the JSON key %q", path, other, propName, sf.jsonName())
+		}
+		jsonNames[sf.jsonName()] = propName
+
+		refPath := path + "/properties/" + propName
+		if addedPath, ok := addedPaths[propName]; ok {
+			// the constraints of a property of then or else, or of a
+			// dependent schema, only apply with that schema
+			refPath = addedPath
+			sf.constraints = constraints{}
+			sf.DependsOn = dependsOn[propName]
+		}
+		nullableUnion := false
+		if variant, ok := nullableVariant(propSchema); ok {
+			propSchema, nullableUnion = variant, true
+			if _, ok := addedPaths[propName]; !ok {
+				sf.constraints = getConstraints(propSchema)
+			}
+		}
+		propertySchemas[refPath] = propSchema
+
+		resolveRecursiveRef(propSchema, path)
+		if _, ok := resolveRef(propSchema.Ref); propSchema.Ref != "" && !ok {
+			if target, ok := scalarProperty(propSchema.Ref); ok {
+				propSchema = target
+				sf.constraints = getConstraints(propSchema)
+			}
+		}
+		if propSchema.Ref != "" {
+			if ref, ok := resolveRef(propSchema.Ref); ok {
+				refType := types[ref]
+				sf.TypeRef, sf.Nullable = ref, refType.Nullable || nullableUnion
+				if refType.TypePrefix == typeStruct {
+					sf.PtrForOmit = true
+				}
+				// a struct can only contain itself through a pointer; a basic type,
+				// like a primitive root, doesn't contain anything
+				if (path == ref || strings.HasPrefix(path, ref+"/")) && !isComparableBasic(refType.TypePrefix) {
+					sf.Recursive = true
+				}
+				gt.Fields = append(gt.Fields, sf)
+				continue
+			}
+			deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, propSchema.Ref)
+			return "", nil
+		}
+
+		schemaNullable := false
+		switch propType := propSchema.Type.(type) {
+		case []interface{}:
+			if len(propType) == 1 && propType[0] != typeNull {
+				if jsonType, ok := propType[0].(string); ok {
+					sf.TypePrefix = getTypeString(jsonType, propSchema)
+				}
+			}
+			if len(propType) == 2 && (propType[0] == typeNull || propType[1] == typeNull) {
+				sf.Nullable = true
+
+				jsonType := propType[0]
+				if jsonType == typeNull {
+					jsonType = propType[1]
+				}
+				sf.TypePrefix = getTypeString(jsonType.(string), propSchema)
+				sf.NullSlice = *nullSlices && !sf.Required && sf.TypePrefix == typeArray
+				schemaNullable = true
+			}
+		case string:
+			sf.TypePrefix = getTypeString(propType, propSchema)
+		case nil:
+			sf.TypePrefix = typeEmptyInterface
+		}
+		if nullableUnion {
+			sf.Nullable = true
+			sf.NullSlice = *nullSlices && !sf.Required && sf.TypePrefix == typeArray
+			schemaNullable = true
+		}
+
+		_, variants := unionVariants(propSchema, "")
+		isUnion := variants != nil && (sf.TypePrefix == typeEmptyInterface || sf.TypePrefix == typeObject)
+		if hasEnum(propSchema, sf.TypePrefix) || isUnion {
+			gotType, err := processType(propSchema, sf.Name, propSchema.Description, refPath, path)
+			if err != nil {
+				return "", err
+			}
+			if gotType == "" {
+				deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refPath)
+				return "", nil
+			}
+			sf.TypePrefix = ""
+			sf.TypeRef = gotType
+			gt.Fields = append(gt.Fields, sf)
+			continue
+		}
+
+		props := getTypeSchemas(propSchema.Properties)
+		hasProps := len(props) > 0
+		hasAddlProps, addlPropsSchema := parseAdditionalProperties(propSchema.AdditionalProperties)
+
+		if sf.TypePrefix == typeObject {
+			if (hasProps && !hasAddlProps) || multiPatterns(propSchema) != nil {
+				gotType, err := processType(propSchema, sf.Name, propSchema.Description, refPath, path)
+				if err != nil {
+					return "", err
+				}
+				if gotType == "" {
+					deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refPath)
+					return "", nil
+				}
+				sf.TypePrefix = ""
+				sf.TypeRef = gotType
+				sf.PtrForOmit = true
+			} else if !hasProps && hasAddlProps && addlPropsSchema != nil {
+				valueName := mapValueName(propName, propIdents)
+				gotType, err := processAdditionalProperties(addlPropsSchema, valueName, propSchema.Description, refPath+"/additionalProperties", path)
+				if err != nil {
+					return "", err
+				}
+				if gotType == "" {
+					deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refOrPath(addlPropsSchema, refPath+"/additionalProperties"))
+					return "", nil
+				}
+				sf.TypePrefix = "map[string]"
+				sf.TypeRef = gotType
+			} else if pattern, valueSchema := singlePattern(propSchema); valueSchema != nil && len(propSchema.AllOf) == 0 && !isEmptyMetaSchema(valueSchema) {
+				valueName := mapValueName(propName, propIdents)
+				valuePath := refPath + "/patternProperties/" + escapePointerToken(pattern)
+				gotType, err := processAdditionalProperties(valueSchema, valueName, propSchema.Description, valuePath, path)
+				if err != nil {
+					return "", err
+				}
+				if gotType == "" {
+					deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refOrPath(valueSchema, valuePath))
+					return "", nil
+				}
+				sf.TypePrefix = "map[string]"
+				sf.TypeRef = gotType
+			} else {
+				sf.TypePrefix = "map[string]interface{}"
+			}
+		} else if _, ok := setKeyType(propSchema); ok && sf.TypePrefix == typeArray {
+			// a set is a map type of its own, with methods
+			gotType, err := processType(propSchema, sf.Name, propSchema.Description, refPath, path)
+			if err != nil {
+				return "", err
+			}
+			sf.TypePrefix = ""
+			sf.TypeRef = gotType
+			sf.NullSlice = false
+		} else if sf.TypePrefix == typeArray {
+			_, addlItemsSchema := parseAdditionalProperties(propSchema.AdditionalItems)
+			switch arrayItemType := propSchema.Items.(type) {
+			case []interface{}:
+				if len(arrayItemType) == 1 && addlItemsSchema == nil {
+					singularName := singularize(propName)
+					typeSchema := getTypeSchema(arrayItemType[0])
+					gotType, err := processType(typeSchema, singularName, propSchema.Description, refPath+"/items/0", path)
+					if err != nil {
+						return "", err
+					}
+					if gotType == "" {
+						deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refPath+"/items/0")
+						return "", nil
+					}
+					sf.TypePrefix = "[]*"
+					sf.TypeRef = gotType
+				} else if len(arrayItemType) == 0 {
+					sf.TypePrefix = typeEmptyInterfaceSlice
+				} else {
+					// a tuple is a struct or Go array of its own
+					gotType, err := processType(propSchema, sf.Name, propSchema.Description, refPath, path)
+					if err != nil {
+						return "", err
+					}
+					if gotType == "" {
+						deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refPath)
+						return "", nil
+					}
+					sf.TypePrefix = ""
+					sf.TypeRef = gotType
+					sf.PtrForOmit = types[gotType].TypePrefix == typeStruct
+				}
+			case bool:
+				sf.TypePrefix = boolItemsType(arrayItemType)
+			case interface{}:
+				if isEmptySchema(arrayItemType) {
+					// an empty schema allows any item, the same as no items
+					sf.TypePrefix = typeEmptyInterfaceSlice
+					break
+				}
+				singularName := singularize(propName)
+				typeSchema := getTypeSchema(arrayItemType)
+				gotType, err := processType(typeSchema, singularName, propSchema.Description, refPath+"/items", path)
+				if err != nil {
+					return "", err
+				}
+				if gotType == "" {
+					deferType(path, deferredType{schema: s, name: pName, desc: pDesc, parentPath: parentPath}, refPath+"/items")
+					return "", nil
+				}
+				sf.TypePrefix = "[]*"
+				sf.TypeRef = gotType
+			default:
+				sf.TypePrefix = typeEmptyInterfaceSlice
+			}
+		} /* else {
+			if (!sf.Required || sf.Nullable) && sf.TypePrefix != typeBool && sf.TypePrefix != typeEmptyInterface {
+				sf.TypePrefix = "*" + sf.TypePrefix
+			}
+		}*/
+
+		if *fixedArrays && !sf.NullSlice && isSliceType(sf.TypePrefix) {
+			sf.setFixedLen()
+		}
+		if sqlType, ok := sqlNullTypes[sf.TypePrefix]; ok && schemaNullable && *nullType == nullTypeSQL {
+			sf.TypePrefix = sqlNullTypeName(sqlType)
+		}
+		// a property with a type of its own was warned about with it
+		if sf.TypeRef != refPath {
+			warnIgnoredNot(propSchema, sf.constraints.Not.appliesTo(sf.TypePrefix), refPath)
+		}
+
+		gt.Fields = append(gt.Fields, sf)
+	}
+
+	for index := range s.AllOf {
+		// only structs can embed the types of their allOf schemas
+		if gt.TypePrefix != typeStruct {
+			break
+		}
+
+		sf := structField{
+			Embedded: true,
+		}
+
+		childPath := fmt.Sprintf("%s/allOf/%d", path, index)
+		if _, ok := transitiveRefs[childPath]; ok {
+			childPath = transitiveRefs[childPath]
+		}
+		// encoding/json only promotes the fields of embedded structs; anything
+		// else, like a schema that only adds required properties, would be
+		// marshaled under its type name
+		if types[childPath].TypePrefix != typeStruct {
+			continue
+		}
+		sf.TypeRef = childPath
+
+		gt.Fields = append(gt.Fields, sf)
+	}
+
+	if *defaults && gt.TypePrefix == typeStruct {
+		gt.warnIgnoredDefaults(path)
+	}
+
+	if gt.TypePrefix == typeStruct && s.If != nil {
+		gt.conditional = getConditional(s, gt.Fields, path)
+	}
+	if gt.TypePrefix == typeStruct && len(s.DependentRequired) > 0 {
+		gt.dependentRequired = getDependentRequired(s, gt.Fields, path)
+	}
+	if gt.TypePrefix == typeStruct && s.Not != nil {
+		gt.notRequired = getNotRequired(s, gt.Fields)
+	}
+	warnIgnoredNot(s, gt.constraints.Not.appliesTo(gt.TypePrefix) || len(gt.notRequired) > 0, path)
+
+	if gt.TypePrefix == typeStruct && required.Len() > 0 {
+		if err := checkRequired(gt, s, required); err != nil {
+			return "", err
+		}
+	}
+
+	if *protoTags && gt.TypePrefix == typeStruct {
+		if err := numberProtoFields(gt.Fields, path); err != nil {
+			return "", err
+		}
+	}
+
+	// only inline objects are collapsed, so a definition keeps its shape for
+	// everything that refers to it
+	if *collapseWrappers && path != rootPath && !isDefinitionPath(path) && len(s.AllOf) == 0 && gt.conditional == nil && len(gt.dependentRequired) == 0 && len(gt.notRequired) == 0 {
+		gt.collapseWrapper()
+	}
+
+	return
+}
+
+func processDeferred() error {
+	for len(deferredTypes) > 0 {
+		startDeferredPaths, _ := stringset.FromMapKeys(deferredTypes)
+		for _, path := range startDeferredPaths.Sorted() {
+			deferred := deferredTypes[path]
+			name, err := processType(deferred.schema, deferred.name, deferred.desc, path, deferred.parentPath)
+			if err != nil {
+				return err
+			}
+			if name != "" {
+				delete(deferredTypes, path)
+			}
+		}
+
+		// if the list is the same as before, we're stuck
+		endDeferredPaths, _ := stringset.FromMapKeys(deferredTypes)
+		if endDeferredPaths.Equals(startDeferredPaths) {
+			for _, path := range endDeferredPaths.Sorted() {
+				if chain := refCycle(path); chain != nil {
+					return &refError{Paths: chain, Circular: true}
+				}
+			}
+			return &refError{Paths: endDeferredPaths.Sorted()}
+		}
+	}
+	return nil
+}
+
+// parseDefs processes the schemas in the definitions of s, and in its $defs,
+// as they're called since draft 2019-09.
+func parseDefs(s *metaSchema, path string) error {
+	for _, keyword := range []string{"definitions", "$defs"} {
+		defs := getTypeSchemas(s.Definitions)
+		if keyword == "$defs" {
+			defs = getTypeSchemas(s.Defs)
+		}
+		defNames, _ := stringset.FromMapKeys(defs)
+		for _, defName := range defNames.Sorted() {
+			defSchema := defs[defName]
+			defPath := path + "/" + keyword + "/" + defName
+			name, err := processType(defSchema, defName, defSchema.Description, defPath, path)
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				deferredTypes[defPath] = deferredType{schema: defSchema, name: defName, desc: defSchema.Description, parentPath: path}
+			}
+		}
+	}
+	return nil
+}
+
+// formatSource formats src with gofmt, or with goimports if requested, which
+// also groups the imports and removes any that are unused.
+func formatSource(fileName string, src []byte) ([]byte, error) {
+	if *runGoimports {
+		return goimports.Process(fileName, src, nil)
+	}
+	return format.Source(src)
+}
+
+// renderFile returns the formatted source of a generated file with the given
+// body and imports, and any directives after the package clause.
+func renderFile(fileName string, body []byte, imports stringset.StringSet, directives ...string) ([]byte, error) {
+	var resultSrc bytes.Buffer
+	if *nolint {
+		// golangci-lint applies a directive above the package clause to the whole file
+		resultSrc.WriteString(fmt.Sprintf("//nolint:%s\n", *nolintLinters))
+	}
+	resultSrc.WriteString(fmt.Sprintln("package", *packageName))
+	resultSrc.WriteString(fmt.Sprintf("\n// generated by \"%s\" -- DO NOT EDIT\n", strings.Join(append([]string{"schematyper"}, options.Args...), " ")))
+	resultSrc.WriteString("\n")
+	for _, directive := range directives {
+		resultSrc.WriteString(directive + "\n\n")
+	}
+	if imports.Len() > 0 {
+		resultSrc.WriteString("import (\n")
+		for _, imp := range imports.Sorted() {
+			resultSrc.WriteString(fmt.Sprintf("%q\n", imp))
+		}
+		resultSrc.WriteString(")\n\n")
+	}
+
+	resultSrc.Write(body)
+	resultSrc.WriteString("\n")
+
+	formattedSrc, err := formatSource(fileName, resultSrc.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %s", fileName, err)
+	}
+	return formattedSrc, nil
+}
+
+// printTypesList returns the file name and body of a file declaring a slice
+// with the zero value of each generated type, for registering the types with
+// reflection-based tools. Types with an interface as their underlying type are
+// left out since their zero value is nil.
+func printTypesList(typesSlice goTypes) (fileName string, body []byte) {
+	varName := generateIdentifier("generated types", *packageName != "main")
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("// %s holds the zero value of each generated type.\n", varName))
+	buf.WriteString(fmt.Sprintf("var %s = []interface{}{\n", varName))
+	for _, gt := range typesSlice {
+		switch gt.TypePrefix {
+		case typeStruct:
+			buf.WriteString(fmt.Sprintf("%s{},\n", gt.Name))
+		case typeEmptyInterface:
+		default:
+			buf.WriteString(fmt.Sprintf("*new(%s),\n", gt.Name))
+		}
+	}
+	buf.WriteString("}\n")
+
+	return varName + ".go", buf.Bytes()
+}
+
+// renderTypes returns the formatted source for each generated type, keyed by
+// output file name.
+func renderTypes() (map[string][]byte, error) {
+	nameEnumConsts()
+
+	var validated map[string]bool
+	if *validate {
+		validated = findValidatedTypes()
+	}
+	markInline(validated)
+	var defaulted map[string]bool
+	if *defaults {
+		defaulted = findDefaultedTypes()
+	}
+
+	typesSlice := make(goTypes, 0, len(types))
+	for _, gt := range types {
+		// inline structs are written in the fields of their parents
+		if !gt.Inline {
+			typesSlice = append(typesSlice, gt)
+		}
+	}
+	sort.Stable(typesSlice)
+	orderTypes(typesSlice, *typeOrder)
+
+	files := make(map[string][]byte, len(typesSlice))
+	for _, gt := range typesSlice {
+		var body bytes.Buffer
+		imports := stringset.New()
+
+		gt.print(&body, imports)
+		gt.printConstructor(&body, defaulted)
+		gt.printWrapperJSON(&body, imports)
+		gt.printPatternJSON(&body, imports)
+		gt.printUnionJSON(&body, imports)
+		gt.printTupleJSON(&body, imports)
+		gt.printSetMethods(&body, imports)
+		gt.printPreserveJSON(&body, imports)
+		gt.printAccessors(&body, *accessors)
+		if *redactSecrets {
+			gt.printString(&body, imports)
+		}
+		if *isZero {
+			gt.printIsZero(&body, imports)
+		}
+		if *equalMethods {
+			gt.printEqual(&body, imports)
+		}
+		if *merge {
+			gt.printMerge(&body, imports)
+		}
+		if validated[gt.path] && !gt.Alias {
+			body.WriteString("\n")
+			gt.printValidate(&body, imports, validated)
+		}
+
+		var directives []string
+		if *emitGoGenerate && gt.path == rootPath {
+			// only once, so go generate doesn't run it for every file
+			directives = append(directives, goGenerateDirective())
+		}
+
+		fileName := gt.Name + ".go"
+		src, err := renderFile(fileName, body.Bytes(), imports, directives...)
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = src
+	}
+
+	if *typesList {
+		fileName, body := printTypesList(typesSlice)
+		src, err := renderFile(fileName, body, stringset.New())
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = src
+	}
+
+	if *outputTest {
+		fileName, body := printTests(typesSlice)
+		src, err := renderFile(fileName, body, stringset.New("encoding/json", "testing"))
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = src
+	}
+
+	if *outputExamples {
+		if fileName, body := printExamples(typesSlice); body != nil {
+			src, err := renderFile(fileName, body, stringset.New("encoding/json"))
+			if err != nil {
+				return nil, err
+			}
+			files[fileName] = src
+		}
+	}
+
+	if *validateAll && len(validated) > 0 {
+		fileName, body := printValidationErrors()
+		imports := stringset.New("strings")
+		if *wrapErrors {
+			imports.Add("fmt")
+		}
+		src, err := renderFile(fileName, body, imports)
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = src
+	}
+
+	if hasNullSlices(typesSlice) {
+		fileName, body := printNullSlice()
+		src, err := renderFile(fileName, body, stringset.New("encoding/json"))
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = src
+	}
+
+	for _, sqlType := range usedSQLNullTypes(typesSlice) {
+		fileName, body := printSQLNull(sqlType)
+		src, err := renderFile(fileName, body, stringset.New("database/sql", "encoding/json"))
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = src
+	}
+
+	return files, nil
+}
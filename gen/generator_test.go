@@ -0,0 +1,5291 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// processSchema runs the same processing steps as Parse and Generate on
+// schemaJSON with the current options and returns the resulting types keyed
+// by type name.
+func processSchema(schemaJSON string) map[string]goType {
+	*rootTypeName = "root"
+	if err := run([]byte(schemaJSON), options); err != nil {
+		panic(err)
+	}
+
+	byName := make(map[string]goType, len(types))
+	for _, gt := range types {
+		byName[gt.Name] = gt
+	}
+	return byName
+}
+
+// generateSchema processes schemaJSON and returns the source of each generated
+// file keyed by file name.
+func generateSchema(schemaJSON string) map[string]string {
+	processSchema(schemaJSON)
+
+	rendered, err := renderTypes()
+	if err != nil {
+		panic(err)
+	}
+
+	files := make(map[string]string)
+	for name, src := range rendered {
+		files[name] = string(src)
+	}
+	return files
+}
+
+// runGenerated builds the generated files together with program, which must
+// provide func main, and returns the combined output of running them.
+func runGenerated(files map[string]string, program string) (string, error) {
+	dir, err := ioutil.TempDir("", "schematyper")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	files["go.mod"] = "module generated\n\ngo 1.24\n"
+	files["program.go"] = program
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// testGenerated runs go test on the generated files and returns its combined
+// output.
+func testGenerated(files map[string]string) (string, error) {
+	dir, err := ioutil.TempDir("", "schematyper")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	files["go.mod"] = "module generated\n\ngo 1.24\n"
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	cmd := exec.Command("go", "test", "-v", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func printType(gt goType) string {
+	buf := &bytes.Buffer{}
+	gt.print(buf, stringset.New())
+	return buf.String()
+}
+
+func TestReadOnly(t *testing.T) {
+	Convey("Given a schema with a readOnly property that is not required", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"id": {"type": "string", "readOnly": true},
+				"name": {"type": "string"}
+			}
+		}`
+
+		Convey("When we generate the root type", func() {
+			src := printType(processSchema(schema)["root"])
+
+			Convey("Then the readOnly field should not have omitempty", func() {
+				So(src, ShouldContainSubstring, "`json:\"id\"`")
+			})
+
+			Convey("Then other optional fields should still have omitempty", func() {
+				So(src, ShouldContainSubstring, "`json:\"name,omitempty\"`")
+			})
+		})
+	})
+}
+
+func TestOmitEmptyOverride(t *testing.T) {
+	Convey("Given a schema with x-go-omitempty on some properties", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["id", "name"],
+			"properties": {
+				"id": {"type": "string", "x-go-omitempty": true},
+				"name": {"type": "string"},
+				"note": {"type": "string", "x-go-omitempty": false},
+				"etag": {"type": "string", "readOnly": true, "x-go-omitempty": true}
+			}
+		}`
+
+		Convey("When we generate the root type", func() {
+			src := printType(processSchema(schema)["root"])
+
+			Convey("Then a required field forced on should have omitempty", func() {
+				So(src, ShouldContainSubstring, "`json:\"id,omitempty\"`")
+			})
+
+			Convey("Then an optional field forced off should not have omitempty", func() {
+				So(src, ShouldContainSubstring, "`json:\"note\"`")
+			})
+
+			Convey("Then the extension should override readOnly", func() {
+				So(src, ShouldContainSubstring, "`json:\"etag,omitempty\"`")
+			})
+
+			Convey("Then fields without the extension should be unchanged", func() {
+				So(src, ShouldContainSubstring, "`json:\"name\"`")
+			})
+		})
+	})
+}
+
+func TestPrefixRoot(t *testing.T) {
+	Convey("Given a schema and a type name prefix", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"child": {"type": "object", "properties": {"name": {"type": "string"}}}
+			}
+		}`
+		*typeNamesPrefix = "api"
+		defer func() { *typeNamesPrefix = "" }()
+
+		Convey("When we generate types without --prefix-root", func() {
+			generated := processSchema(schema)
+
+			Convey("Then only the non-root types should have the prefix", func() {
+				So(generated, ShouldContainKey, "root")
+				So(generated, ShouldContainKey, "apiChild")
+			})
+		})
+
+		Convey("When we generate types with --prefix-root", func() {
+			*prefixRoot = true
+			defer func() { *prefixRoot = false }()
+			generated := processSchema(schema)
+
+			Convey("Then the root type should have the prefix too", func() {
+				So(generated, ShouldContainKey, "apiRoot")
+				So(generated, ShouldNotContainKey, "root")
+				So(generated, ShouldContainKey, "apiChild")
+			})
+		})
+	})
+}
+
+func TestEnumBranches(t *testing.T) {
+	Convey("Given anyOf and oneOf schemas made of enums", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"color": {"anyOf": [{"enum": ["red", "green"]}, {"enum": ["green", "blue"]}]},
+				"level": {"oneOf": [{"const": 1, "title": "low"}, {"type": "integer", "const": 2}]},
+				"mixed": {"anyOf": [{"enum": ["a"]}, {"enum": [1]}]},
+				"bounded": {"anyOf": [{"enum": ["a"]}, {"type": "string", "maxLength": 3}]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the enums should be merged into one without duplicates", func() {
+				So(files["root.go"], ShouldContainSubstring, "Color   Color    `json:\"color,omitempty\"`")
+				So(files["Color.go"], ShouldContainSubstring, "type Color string")
+				So(files["Color.go"], ShouldContainSubstring, `ColorRed   Color = "red"`)
+				So(files["Color.go"], ShouldContainSubstring, `ColorGreen Color = "green"`)
+				So(files["Color.go"], ShouldContainSubstring, `ColorBlue  Color = "blue"`)
+				So(strings.Count(files["Color.go"], "ColorGreen"), ShouldEqual, 1)
+			})
+
+			Convey("Then consts should be merged too", func() {
+				So(files["Level.go"], ShouldContainSubstring, "type Level int64")
+				So(files["Level.go"], ShouldContainSubstring, "Level1 Level = iota + 1\n\tLevel2\n")
+			})
+
+			Convey("Then other schemas shouldn't be merged, but be unions, which are pointers if they're optional", func() {
+				So(files["root.go"], ShouldContainSubstring, "Mixed   *Mixed   `json:\"mixed,omitempty\"`")
+				So(files["Mixed.go"], ShouldContainSubstring, "type Mixed struct")
+				So(files["root.go"], ShouldContainSubstring, "Bounded *Bounded `json:\"bounded,omitempty\"`")
+				So(files["Bounded.go"], ShouldContainSubstring, "String   *string          `json:\"-\"`")
+			})
+		})
+	})
+}
+
+func TestEnumHelpers(t *testing.T) {
+	Convey("Given a schema with string and integer enums", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"color": {"type": "string", "enum": ["red", "green", "blue"]},
+				"size": {"type": "integer", "enum": [1, 2, 3]}
+			}
+		}`
+		*enumHelpers = true
+		defer func() { *enumHelpers = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then each enum should have a values slice in schema order", func() {
+				So(files["Color.go"], ShouldContainSubstring, "var ColorValues = []Color{ColorRed, ColorGreen, ColorBlue}")
+				So(files["Size.go"], ShouldContainSubstring, "var SizeValues = []Size{Size1, Size2, Size3}")
+			})
+
+			Convey("Then IsValid should check membership", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(len(ColorValues), ColorValues)
+	fmt.Println(ColorGreen.IsValid(), Color("purple").IsValid())
+	fmt.Println(Size(3).IsValid(), Size(4).IsValid())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "3 [red green blue]\ntrue false\ntrue false\n")
+			})
+		})
+	})
+}
+
+func TestDeclarationSpacing(t *testing.T) {
+	Convey("Given a schema with an enum", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"status": {"type": "string", "description": "The status.", "enum": ["active", "closed"]}
+			}
+		}`
+		*enumHelpers = true
+		defer func() { *enumHelpers = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the constants should follow their type with only the blank line gofmt requires", func() {
+				So(files["Status.go"], ShouldContainSubstring, "// The status.\ntype Status string\n\nconst (\n")
+				So(files["Status.go"], ShouldContainSubstring, ")\n\n// StatusValues holds")
+			})
+
+			Convey("Then there should be no more than one blank line anywhere", func() {
+				for _, src := range files {
+					So(src, ShouldNotContainSubstring, "\n\n\n")
+				}
+			})
+		})
+	})
+}
+
+func TestPrefixConstants(t *testing.T) {
+	Convey("Given a schema with enums and a type name prefix", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"status": {"type": "string", "enum": ["active", "closed"]},
+				"level": {"type": "integer", "enum": [1, 2], "x-enum-varnames": ["Low", "High"]}
+			}
+		}`
+		*typeNamesPrefix = "api"
+		*enumNaming = enumNamingValue
+		defer func() {
+			*typeNamesPrefix = ""
+			*enumNaming = enumNamingTypeValue
+		}()
+
+		Convey("When we generate the types without --prefix-constants", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the constants should only have their own names", func() {
+				So(files["apiStatus.go"], ShouldContainSubstring, `Active apiStatus = "active"`)
+				So(files["apiLevel.go"], ShouldContainSubstring, "Low apiLevel = iota + 1")
+			})
+		})
+
+		Convey("When we generate the types with --prefix-constants", func() {
+			*prefixConstants = true
+			defer func() { *prefixConstants = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the constants should have the prefix", func() {
+				So(files["apiStatus.go"], ShouldContainSubstring, `apiActive apiStatus = "active"`)
+				So(files["apiStatus.go"], ShouldContainSubstring, `apiClosed apiStatus = "closed"`)
+				So(files["apiLevel.go"], ShouldContainSubstring, "apiLow apiLevel = iota + 1\n\tapiHigh\n")
+			})
+
+			Convey("Then the generated code should compile", func() {
+				_, err := runGenerated(files, "package main\n\nfunc main() {}\n")
+				So(err, ShouldBeNil)
+			})
+		})
+
+		Convey("When we generate the types with type-value naming and --prefix-constants", func() {
+			*enumNaming = enumNamingTypeValue
+			*prefixConstants = true
+			defer func() { *prefixConstants = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the prefix of the type shouldn't be repeated", func() {
+				So(files["apiStatus.go"], ShouldContainSubstring, `apiStatusActive apiStatus = "active"`)
+			})
+		})
+	})
+}
+func TestValidate(t *testing.T) {
+	Convey("Given a schema with constraints on nested types", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "minLength": 1},
+				"address": {
+					"type": "object",
+					"properties": {
+						"zip": {"type": "string", "pattern": "^[0-9]{5}$"}
+					}
+				},
+				"lines": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"qty": {"type": "integer", "minimum": 1}
+						}
+					}
+				},
+				"meta": {
+					"type": "object",
+					"properties": {
+						"note": {"type": "string"}
+					}
+				}
+			}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then only types with constraints should have a Validate method", func() {
+				So(files["root.go"], ShouldContainSubstring, "func (t root) Validate() error")
+				So(files["Address.go"], ShouldContainSubstring, "func (t Address) Validate() error")
+				So(files["Line.go"], ShouldContainSubstring, "func (t Line) Validate() error")
+				So(files["Meta.go"], ShouldNotContainSubstring, "Validate")
+			})
+
+			Convey("Then the root Validate should recurse into nested values", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	valid := root{Name: "x", Address: Address{Zip: "12345"}, Lines: []*Line{{Qty: 1}, nil}}
+	fmt.Println(valid.Validate())
+
+	badZip := valid
+	badZip.Address.Zip = "abc"
+	fmt.Println(badZip.Validate())
+
+	badLine := valid
+	badLine.Lines = []*Line{{Qty: 1}, {Qty: -1}}
+	fmt.Println(badLine.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\nzip: must match pattern ^[0-9]{5}$\nqty: must be >= 1\n")
+			})
+		})
+	})
+
+	Convey("Given a schema with constraints on optional properties", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["id"],
+			"properties": {
+				"id": {"type": "integer", "minimum": 1},
+				"n": {"type": "integer", "minimum": 5},
+				"code": {"type": "string", "minLength": 2},
+				"tags": {"type": "array", "minItems": 1, "items": {"type": "string"}},
+				"address": {
+					"type": "object",
+					"required": ["zip"],
+					"properties": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}
+				}
+			}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we validate an empty document", func() {
+			files := generateSchema(schema)
+			program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var empty root
+	if err := json.Unmarshal([]byte("{\"id\": 1}"), &empty); err != nil {
+		panic(err)
+	}
+	fmt.Println(empty.Validate())
+
+	var set root
+	if err := json.Unmarshal([]byte("{\"id\": 1, \"n\": 4}"), &set); err != nil {
+		panic(err)
+	}
+	fmt.Println(set.Validate())
+
+	var emptyTags root
+	if err := json.Unmarshal([]byte("{\"id\": 1, \"tags\": []}"), &emptyTags); err != nil {
+		panic(err)
+	}
+	fmt.Println(emptyTags.Validate())
+
+	fmt.Println(root{}.Validate())
+}
+`
+
+			Convey("Then only the required and present properties should be checked", func() {
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\nn: must be >= 5\ntags: must have at least 1 items\nid: must be >= 1\n")
+			})
+		})
+	})
+}
+
+func TestValidateElements(t *testing.T) {
+	Convey("Given a schema with constraints on primitive array items and map values", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"tags": {"type": "array", "items": {"type": "string", "minLength": 1}},
+				"scores": {"type": "object", "additionalProperties": {"type": "integer", "maximum": 10}}
+			}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the elements should get named types with their own checks", func() {
+				So(files["root.go"], ShouldContainSubstring, "[]*Tag")
+				So(files["root.go"], ShouldContainSubstring, "map[string]Score")
+				So(files["Tag.go"], ShouldContainSubstring, "func (t Tag) Validate() error")
+				So(files["Score.go"], ShouldContainSubstring, "func (t Score) Validate() error")
+			})
+
+			Convey("Then the root Validate should check each element", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	a, b, empty := Tag("a"), Tag("b"), Tag("")
+	valid := root{Tags: []*Tag{&a, &b}, Scores: map[string]Score{"x": 10}}
+	fmt.Println(valid.Validate())
+
+	badTag := valid
+	badTag.Tags = []*Tag{&a, &empty}
+	fmt.Println(badTag.Validate())
+
+	badScore := valid
+	badScore.Scores = map[string]Score{"x": 11}
+	fmt.Println(badScore.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\nlength must be at least 1\nmust be <= 10\n")
+			})
+		})
+	})
+}
+
+func TestValidateAll(t *testing.T) {
+	Convey("Given a schema with constraints on several fields", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["name"],
+			"properties": {
+				"name": {"type": "string", "minLength": 1},
+				"age": {"type": "integer", "minimum": 0},
+				"lines": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"qty": {"type": "integer", "minimum": 1}
+						}
+					}
+				}
+			}
+		}`
+		*validate = true
+		*validateAll = true
+		defer func() {
+			*validate = false
+			*validateAll = false
+		}()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the error type should be declared once", func() {
+				So(files["validationErrors.go"], ShouldContainSubstring, "type validationErrors []error")
+				So(files["root.go"], ShouldNotContainSubstring, "type validationErrors")
+			})
+
+			Convey("Then Validate should report every violation", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	valid := root{Name: "x", Lines: []*Line{{Qty: 1}}}
+	fmt.Println(valid.Validate())
+
+	invalid := root{Age: -1, Lines: []*Line{{Qty: -1}}}
+	err := invalid.Validate()
+	fmt.Println(err)
+	fmt.Println(len(err.(validationErrors)))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\nage: must be >= 0; qty: must be >= 1; name: length must be at least 1\n3\n")
+			})
+		})
+	})
+}
+
+func TestWrapErrors(t *testing.T) {
+	Convey("Given a schema with constraints nested in slices and maps", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"order": {
+					"type": "object",
+					"properties": {
+						"items": {
+							"type": "array",
+							"items": {
+								"type": "object",
+								"properties": {"sku": {"type": "string", "maxLength": 3}}
+							}
+						}
+					}
+				},
+				"labels": {"type": "object", "additionalProperties": {"type": "string", "minLength": 1}}
+			}
+		}`
+		*validate = true
+		*wrapErrors = true
+		defer func() {
+			*validate = false
+			*wrapErrors = false
+		}()
+		program := `package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func main() {
+	r := root{Order: Order{Items: []*Item{{Sku: "a"}, nil, {Sku: "abcd"}}}}
+	err := r.Validate()
+	fmt.Println(err)
+	fmt.Println(errors.Unwrap(errors.Unwrap(err)) != nil)
+
+	r = root{Labels: map[string]Label{"en": ""}}
+	fmt.Println(r.Validate())
+}
+`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then nested errors should have the full path", func() {
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "order.items[2].sku: length must be at most 3\ntrue\nlabels[\"en\"]: length must be at least 1\n")
+			})
+		})
+
+		Convey("When we generate the types with --validate-all", func() {
+			*validateAll = true
+			defer func() { *validateAll = false }()
+			files := generateSchema(schema)
+
+			Convey("Then each violation should have the full path", func() {
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "order.items[2].sku: length must be at most 3\nfalse\nlabels[\"en\"]: length must be at least 1\n")
+			})
+		})
+	})
+}
+
+func TestImports(t *testing.T) {
+	Convey("Given a schema with a date-time property", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"created": {"type": "string", "format": "date-time"}
+			}
+		}`
+
+		Convey("When we generate the root type", func() {
+			files := generateSchema(schema)
+
+			Convey("Then it should import time", func() {
+				So(files["root.go"], ShouldContainSubstring, `"time"`)
+				So(files["root.go"], ShouldContainSubstring, "time.Time")
+			})
+		})
+
+		Convey("When the date-time field's type is replaced after processing", func() {
+			processSchema(schema)
+			root := types["#"]
+			root.Fields[0].TypePrefix = typeString
+			types["#"] = root
+
+			files, err := renderTypes()
+			So(err, ShouldBeNil)
+
+			Convey("Then it should not import time", func() {
+				So(string(files["root.go"]), ShouldNotContainSubstring, `"time"`)
+			})
+		})
+	})
+}
+
+func TestAdditionalPropertiesRef(t *testing.T) {
+	Convey("Given a map type whose additionalProperties refers to a type that isn't processed yet", t, func() {
+		resetState()
+		mapSchema := getTypeSchema(map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"$ref": "#/definitions/widget"},
+		})
+		widgetSchema := getTypeSchema(map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		})
+
+		Convey("When we process the map type first", func() {
+			gotType, err := processType(mapSchema, "widgets", "", "#/definitions/widgets", "#")
+
+			Convey("Then only the map type should be deferred", func() {
+				So(err, ShouldBeNil)
+				So(gotType, ShouldEqual, "")
+				So(deferredTypes, ShouldContainKey, "#/definitions/widgets")
+				So(deferredTypes, ShouldNotContainKey, "#/definitions/widgets/additionalProperties")
+			})
+
+			Convey("When the referenced type is processed", func() {
+				_, err := processType(widgetSchema, "widget", "", "#/definitions/widget", "#")
+				So(err, ShouldBeNil)
+				So(processDeferred(), ShouldBeNil)
+
+				Convey("Then the map values should have the referenced type", func() {
+					So(printType(types["#/definitions/widgets"]), ShouldContainSubstring, "type Widgets map[string]Widget")
+				})
+			})
+		})
+	})
+}
+
+func TestEmptyAdditionalProperties(t *testing.T) {
+	Convey("Given a schema whose additionalProperties are empty schemas", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"bag": {"type": "object", "additionalProperties": {}}
+			},
+			"properties": {
+				"extra": {"type": "object", "additionalProperties": {}},
+				"bag": {"$ref": "#/definitions/bag"}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then they should be maps of any value", func() {
+				So(files["root.go"], ShouldContainSubstring, "Extra map[string]interface{} `json:\"extra,omitempty\"`")
+				So(files["Bag.go"], ShouldContainSubstring, "type Bag map[string]interface{}")
+			})
+
+			Convey("Then no types should be generated for the values", func() {
+				So(files, ShouldHaveLength, 2)
+			})
+		})
+	})
+}
+
+func TestEmptyItems(t *testing.T) {
+	Convey("Given a schema whose items are empty schemas", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"list": {"type": "array", "items": {}}
+			},
+			"properties": {
+				"anything": {"type": "array", "items": {}},
+				"list": {"$ref": "#/definitions/list"}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then they should be slices of any value", func() {
+				So(files["root.go"], ShouldContainSubstring, "Anything []interface{} `json:\"anything,omitempty\"`")
+				So(files["List.go"], ShouldContainSubstring, "type List []interface{}")
+			})
+
+			Convey("Then no types should be generated for the items", func() {
+				So(files, ShouldHaveLength, 2)
+			})
+		})
+	})
+}
+
+func TestGoimports(t *testing.T) {
+	Convey("Given generated source with an unused import", t, func() {
+		src := []byte("package main\n\nimport \"time\"\n\ntype root struct {\nName string `json:\"name\"`\n}\n")
+
+		Convey("When we format it with gofmt", func() {
+			formatted, err := formatSource("root.go", src)
+
+			Convey("Then the import should be kept", func() {
+				So(err, ShouldBeNil)
+				So(string(formatted), ShouldContainSubstring, `"time"`)
+			})
+		})
+
+		Convey("When we format it with goimports", func() {
+			*runGoimports = true
+			defer func() { *runGoimports = false }()
+			formatted, err := formatSource("root.go", src)
+
+			Convey("Then the import should be removed", func() {
+				So(err, ShouldBeNil)
+				So(string(formatted), ShouldNotContainSubstring, `"time"`)
+				So(string(formatted), ShouldContainSubstring, "type root struct")
+			})
+		})
+	})
+}
+
+func TestRecursiveRef(t *testing.T) {
+	Convey("Given a recursive schema using $recursiveRef", t, func() {
+		schema := `{
+			"$recursiveAnchor": true,
+			"type": "object",
+			"properties": {
+				"title": {"type": "string"},
+				"not": {"$recursiveRef": "#"},
+				"allOf": {"type": "array", "items": {"$recursiveRef": "#"}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the references should be pointers to the root type", func() {
+				So(files["root.go"], ShouldContainSubstring, "Not   *root")
+				So(files["root.go"], ShouldContainSubstring, "AllOf []*root")
+			})
+
+			Convey("Then the generated code should compile", func() {
+				_, err := runGenerated(files, "package main\n\nfunc main() {}\n")
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given a recursive schema using $dynamicRef", t, func() {
+		schema := `{
+			"$dynamicAnchor": "meta",
+			"type": "object",
+			"properties": {
+				"title": {"type": "string"},
+				"properties": {
+					"type": "object",
+					"additionalProperties": {"$dynamicRef": "#meta"}
+				}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the map values should be the root type", func() {
+				So(files["root.go"], ShouldContainSubstring, "map[string]root")
+			})
+		})
+	})
+}
+
+func TestEnumNaming(t *testing.T) {
+	Convey("Given a schema with a string enum", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"status": {"type": "string", "enum": ["active", "in-progress", "on_hold"]}
+			}
+		}`
+		defer func() { *enumNaming = enumNamingTypeValue }()
+
+		Convey("When we generate the types with type-value naming", func() {
+			*enumNaming = enumNamingTypeValue
+			files := generateSchema(schema)
+
+			Convey("Then the constants should be named after the type and value", func() {
+				So(files["root.go"], ShouldContainSubstring, "Status Status `json:\"status,omitempty\"`")
+				So(files["Status.go"], ShouldContainSubstring, "type Status string")
+				So(files["Status.go"], ShouldContainSubstring, `StatusActive     Status = "active"`)
+				So(files["Status.go"], ShouldContainSubstring, `StatusInProgress Status = "in-progress"`)
+				So(files["Status.go"], ShouldContainSubstring, `StatusOnHold     Status = "on_hold"`)
+			})
+		})
+
+		Convey("When we generate the types with value naming", func() {
+			*enumNaming = enumNamingValue
+			files := generateSchema(schema)
+
+			Convey("Then the constants should be named after the value", func() {
+				So(files["Status.go"], ShouldContainSubstring, `Active     Status = "active"`)
+				So(files["Status.go"], ShouldContainSubstring, `InProgress Status = "in-progress"`)
+				So(files["Status.go"], ShouldContainSubstring, `OnHold     Status = "on_hold"`)
+			})
+		})
+
+		Convey("When we generate the types with upper-snake naming", func() {
+			*enumNaming = enumNamingUpperSnake
+			files := generateSchema(schema)
+
+			Convey("Then the constants should be upper snake case", func() {
+				So(files["Status.go"], ShouldContainSubstring, `STATUS_ACTIVE      Status = "active"`)
+				So(files["Status.go"], ShouldContainSubstring, `STATUS_IN_PROGRESS Status = "in-progress"`)
+				So(files["Status.go"], ShouldContainSubstring, `STATUS_ON_HOLD     Status = "on_hold"`)
+			})
+		})
+	})
+
+	Convey("Given a schema with two enums sharing a value", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"status": {"type": "string", "enum": ["active", "closed"]},
+				"state": {"type": "string", "enum": ["active", "paused"]}
+			}
+		}`
+
+		Convey("When we generate the types with value naming", func() {
+			*enumNaming = enumNamingValue
+			defer func() { *enumNaming = enumNamingTypeValue }()
+			files := generateSchema(schema)
+
+			Convey("Then the colliding constant should be disambiguated", func() {
+				So(files["State.go"], ShouldContainSubstring, `Active State = "active"`)
+				So(files["Status.go"], ShouldContainSubstring, `StatusActive Status = "active"`)
+			})
+
+			Convey("Then the generated code should compile", func() {
+				_, err := runGenerated(files, "package main\n\nfunc main() {}\n")
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestCommentsFrom(t *testing.T) {
+	Convey("Given a schema with and without descriptions and a comments file", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"user": {"type": "object", "properties": {"name": {"type": "string"}}},
+				"group": {"type": "object", "description": "From the schema.", "properties": {"name": {"type": "string"}}},
+				"team": {"type": "object", "description": "From the schema.", "properties": {"name": {"type": "string"}}}
+			}
+		}`
+		comments := `{
+			"/definitions/user": "From the sidecar.",
+			"#/definitions/group": "Ignored.",
+			"#/definitions/team": {"description": "Overridden.", "override": true}
+		}`
+
+		Convey("When we generate the types", func() {
+			options.Comments = []byte(comments)
+			defer func() { options.Comments = nil }()
+			generated := processSchema(schema)
+
+			Convey("Then types without a description should get the sidecar one", func() {
+				So(generated["User"].Comment, ShouldEqual, "From the sidecar.")
+			})
+
+			Convey("Then schema descriptions should take precedence", func() {
+				So(generated["Group"].Comment, ShouldEqual, "From the schema.")
+			})
+
+			Convey("Then sidecar overrides should replace schema descriptions", func() {
+				So(generated["Team"].Comment, ShouldEqual, "Overridden.")
+			})
+		})
+	})
+}
+
+func TestTypesList(t *testing.T) {
+	Convey("Given a schema with several types", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"address": {"type": "object", "properties": {"zip": {"type": "string"}}},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"status": {"type": "string", "enum": ["on", "off"]}
+			}
+		}`
+
+		Convey("When we generate the types without --types-list", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no types list", func() {
+				So(files, ShouldNotContainKey, "generatedTypes.go")
+			})
+		})
+
+		Convey("When we generate the types with --types-list", func() {
+			*typesList = true
+			defer func() { *typesList = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the list should hold exactly the generated types", func() {
+				program := `package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+func main() {
+	for _, v := range generatedTypes {
+		fmt.Println(reflect.TypeOf(v).Name())
+	}
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "Address\nStatus\nTag\nroot\n")
+			})
+		})
+	})
+}
+
+func TestBooleanItems(t *testing.T) {
+	Convey("Given a schema with boolean items schemas", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"anything": {"type": "array", "items": true},
+				"nothing": {"type": "array", "items": false}
+			},
+			"properties": {
+				"any": {"type": "array", "items": true},
+				"none": {"type": "array", "items": false}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			generated := processSchema(schema)
+
+			Convey("Then items: true should allow anything", func() {
+				So(printType(generated["Anything"]), ShouldContainSubstring, "type Anything []interface{}")
+				So(printType(generated["root"]), ShouldContainSubstring, "Any []interface{}")
+			})
+
+			Convey("Then items: false should be an empty slice type", func() {
+				So(printType(generated["Nothing"]), ShouldContainSubstring, "type Nothing []struct{}")
+				So(printType(generated["root"]), ShouldContainSubstring, "None []struct{}")
+			})
+
+			Convey("Then no types should be generated for the items", func() {
+				So(generated, ShouldHaveLength, 3)
+			})
+		})
+	})
+}
+
+func TestAllOfItems(t *testing.T) {
+	Convey("Given arrays whose items are composed with allOf", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"color": {"type": "string", "enum": ["red", "green", "blue"]},
+				"named": {"type": "object", "properties": {"name": {"type": "string"}}}
+			},
+			"properties": {
+				"warm": {
+					"type": "array",
+					"items": {"allOf": [{"$ref": "#/definitions/color"}, {"enum": ["red", "orange"]}]}
+				},
+				"things": {
+					"type": "array",
+					"items": {"allOf": [{"$ref": "#/definitions/named"}, {"properties": {"size": {"type": "integer"}}}]}
+				}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			generated := processSchema(schema)
+
+			Convey("Then an allOf of enums should be an enum of the common values", func() {
+				So(generated["WarmItem"].TypePrefix, ShouldEqual, typeString)
+				So(generated["WarmItem"].enumValues, ShouldResemble, []string{"red"})
+				So(generated["WarmItem"].Fields, ShouldBeEmpty)
+			})
+
+			Convey("Then an allOf of objects should embed each of them", func() {
+				So(printType(generated["Thing"]), ShouldContainSubstring, "\nNamed\n")
+				So(printType(generated["ThingEmbedded1"]), ShouldContainSubstring, "Size int64")
+			})
+
+			Convey("Then the arrays should use the composed types", func() {
+				src := printType(generated["root"])
+				So(src, ShouldContainSubstring, "Warm []*WarmItem")
+				So(src, ShouldContainSubstring, "Things []*Thing")
+			})
+		})
+	})
+}
+
+func TestEmbeddedAllOf(t *testing.T) {
+	Convey("Given a schema extending a base type with allOf", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"base": {"type": "object", "properties": {"name": {"type": "string"}}},
+				"thing": {
+					"type": "object",
+					"allOf": [
+						{"$ref": "#/definitions/base"},
+						{"properties": {"size": {"type": "integer"}}},
+						{"required": ["name"]}
+					]
+				}
+			},
+			"properties": {"thing": {"$ref": "#/definitions/thing"}},
+			"required": ["thing"]
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then only the struct schemas should be embedded, without tags", func() {
+				So(files["Thing.go"], ShouldContainSubstring, "\tBase\n")
+				So(files["Thing.go"], ShouldContainSubstring, "\tThingEmbedded1\n")
+				So(files["Thing.go"], ShouldNotContainSubstring, "ThingEmbedded2")
+			})
+
+			Convey("Then the embedded fields should be marshaled at the parent level", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	if err := json.Unmarshal([]byte(` + "`" + `{"thing": {"name": "x", "size": 2}}` + "`" + `), &r); err != nil {
+		panic(err)
+	}
+	fmt.Println(r.Thing.Name, r.Thing.Size)
+
+	out, _ := json.Marshal(r)
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "x 2\n{\"thing\":{\"name\":\"x\",\"size\":2}}\n")
+			})
+		})
+	})
+
+	Convey("Given a schema composed with allOf without a type of its own", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"base": {"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"]},
+				"dog": {
+					"allOf": [
+						{"$ref": "#/definitions/base"},
+						{"properties": {"bark": {"type": "string"}}, "required": ["bark"]}
+					]
+				}
+			},
+			"properties": {"dog": {"$ref": "#/definitions/dog"}}
+		}`
+
+		Convey("When we generate the types", func() {
+			generated := processSchema(schema)
+
+			Convey("Then it should be a struct embedding the base type rather than interface{}", func() {
+				So(printType(generated["Dog"]), ShouldStartWith, "type Dog struct {")
+				So(printType(generated["Dog"]), ShouldContainSubstring, "\nBase\n")
+				So(printType(generated["DogEmbedded1"]), ShouldContainSubstring, "Bark string `json:\"bark\"`")
+				So(printType(generated["Base"]), ShouldContainSubstring, "ID int64 `json:\"id\"`")
+			})
+		})
+	})
+}
+
+func TestNullSlices(t *testing.T) {
+	Convey("Given a schema with an optional nullable array", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+				"names": {"type": "array", "items": {"type": "string"}}
+			}
+		}`
+
+		Convey("When we generate the types with --null-slices", func() {
+			*nullSlices = true
+			defer func() { *nullSlices = false }()
+			files := generateSchema(schema)
+
+			Convey("Then only the nullable array should use the wrapper", func() {
+				So(files, ShouldContainKey, "nullSlice.go")
+				So(files["root.go"], ShouldContainSubstring, "nullSlice[*Tag]")
+				So(files["root.go"], ShouldContainSubstring, "`json:\"tags,omitzero\"`")
+				So(files["root.go"], ShouldContainSubstring, "[]*Name")
+			})
+
+			Convey("Then absent, null and empty arrays should round-trip", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	for _, in := range []string{` + "`{}`, `{\"tags\":null}`, `{\"tags\":[]}`, `{\"tags\":[\"a\"]}`" + `} {
+		var r root
+		if err := json.Unmarshal([]byte(in), &r); err != nil {
+			panic(err)
+		}
+		out, _ := json.Marshal(r)
+		fmt.Println(r.Tags.Set, string(out))
+	}
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "false {}\ntrue {\"tags\":null}\ntrue {\"tags\":[]}\ntrue {\"tags\":[\"a\"]}\n")
+			})
+		})
+
+		Convey("When we generate the types without --null-slices", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the nullable array should be a plain slice", func() {
+				So(files, ShouldNotContainKey, "nullSlice.go")
+				So(files["root.go"], ShouldContainSubstring, "[]*Tag")
+			})
+		})
+	})
+}
+
+func TestRefChains(t *testing.T) {
+	Convey("Given a schema with a chain of refs", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"a": {"$ref": "#/definitions/b"},
+				"b": {"$ref": "#/definitions/c"},
+				"c": {"type": "object", "properties": {"name": {"type": "string"}}}
+			},
+			"properties": {
+				"first": {"$ref": "#/definitions/a"},
+				"seconds": {"type": "array", "items": {"$ref": "#/definitions/b"}}
+			},
+			"required": ["first"]
+		}`
+
+		Convey("When we generate the types", func() {
+			generated := processSchema(schema)
+
+			Convey("Then the intermediate refs shouldn't get types", func() {
+				So(generated, ShouldContainKey, "C")
+				So(generated, ShouldNotContainKey, "A")
+				So(generated, ShouldNotContainKey, "B")
+			})
+
+			Convey("Then the fields should use the final type", func() {
+				src := printType(generated["root"])
+				So(src, ShouldContainSubstring, "First C ")
+				So(src, ShouldContainSubstring, "Seconds []*C ")
+			})
+		})
+	})
+
+	Convey("Given refs that only refer to each other", t, func() {
+		resetState()
+		deferredTypes["#/definitions/x"] = deferredType{schema: &metaSchema{Ref: "#/definitions/y"}}
+		deferredTypes["#/definitions/y"] = deferredType{schema: &metaSchema{Ref: "#/definitions/x"}}
+		deferredTypes["#"] = deferredType{schema: &metaSchema{}}
+
+		Convey("Then the cycle should be found", func() {
+			So(refCycle("#/definitions/x"), ShouldResemble, []string{"#/definitions/x", "#/definitions/y", "#/definitions/x"})
+			So(refCycle("#"), ShouldBeNil)
+		})
+	})
+}
+
+func TestIsZero(t *testing.T) {
+	Convey("Given a schema with fields of each kind", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"color": {"type": "string", "enum": ["red", "blue"]},
+				"stamp": {"type": "string", "format": "date-time"}
+			},
+			"properties": {
+				"name": {"type": "string"},
+				"count": {"type": "integer"},
+				"ok": {"type": "boolean"},
+				"created": {"type": "string", "format": "date-time"},
+				"updated": {"$ref": "#/definitions/stamp"},
+				"color": {"$ref": "#/definitions/color"},
+				"child": {"type": "object", "properties": {"note": {"type": "string"}}},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"extra": {"type": "object", "additionalProperties": {"type": "string"}}
+			},
+			"required": ["child"]
+		}`
+
+		Convey("When we generate the types with --iszero", func() {
+			*isZero = true
+			defer func() { *isZero = false }()
+			files := generateSchema(schema)
+
+			Convey("Then each struct should get an IsZero method", func() {
+				So(files["root.go"], ShouldContainSubstring, "func (t root) IsZero() bool")
+				So(files["Child.go"], ShouldContainSubstring, "func (t Child) IsZero() bool")
+				So(files["Color.go"], ShouldNotContainSubstring, "IsZero")
+			})
+
+			Convey("Then only the zero value should report IsZero", func() {
+				program := `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	fmt.Println(root{}.IsZero())
+	fmt.Println(root{Child: Child{Note: "x"}}.IsZero())
+	fmt.Println(root{Color: ColorRed}.IsZero())
+	fmt.Println(root{Created: time.Now()}.IsZero())
+	fmt.Println(root{Tags: []*Tag{}}.IsZero())
+	zone := time.FixedZone("CET", 3600)
+	fmt.Println(root{Created: time.Time{}.In(zone), Updated: Stamp(time.Time{}.In(zone))}.IsZero())
+	fmt.Println(root{Updated: Stamp(time.Now())}.IsZero())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "true\nfalse\nfalse\nfalse\nfalse\ntrue\nfalse\n")
+			})
+		})
+	})
+}
+
+func TestFieldNameCollisions(t *testing.T) {
+	Convey("Given properties whose names make the same identifier", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"user_id": {"type": "string", "title": "User ID"},
+				"userId": {"type": "integer", "title": "User ID"},
+				"user-id": {"type": "boolean"}
+			}
+		}`
+
+		Convey("When we generate the root type", func() {
+			generated := processSchema(schema)
+			src := printType(generated["root"])
+
+			Convey("Then each field should get its own name, numbered in property order", func() {
+				So(src, ShouldContainSubstring, "UserID bool `json:\"user-id,omitempty\"`")
+				So(src, ShouldContainSubstring, "UserID2 int64 `json:\"userId,omitempty\"`")
+				So(src, ShouldContainSubstring, "UserID3 string `json:\"user_id,omitempty\"`")
+			})
+		})
+	})
+}
+
+func TestAliases(t *testing.T) {
+	Convey("Given definitions that are just a ref or a primitive", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"other": {"type": "object", "properties": {"name": {"type": "string"}}},
+				"alias": {"$ref": "#/definitions/other"},
+				"label": {"type": "string"},
+				"code": {"type": "string", "minLength": 2},
+				"status": {"enum": ["on", "off"]}
+			},
+			"properties": {
+				"thing": {"$ref": "#/definitions/alias"},
+				"label": {"$ref": "#/definitions/label"},
+				"code": {"$ref": "#/definitions/code"}
+			},
+			"required": ["thing", "label", "code"]
+		}`
+
+		Convey("When we generate the types with --aliases", func() {
+			*aliases = true
+			defer func() { *aliases = false }()
+			files := generateSchema(schema)
+
+			Convey("Then they should be aliases", func() {
+				So(files["Alias.go"], ShouldContainSubstring, "type Alias = Other\n")
+				So(files["Label.go"], ShouldContainSubstring, "type Label = string\n")
+			})
+
+			Convey("Then definitions that need methods should still be named types", func() {
+				So(files["Code.go"], ShouldContainSubstring, "type Code string\n")
+				So(files["Status.go"], ShouldContainSubstring, "type Status string\n")
+			})
+
+			Convey("Then fields should refer to the aliases", func() {
+				So(files["root.go"], ShouldContainSubstring, "Thing Alias")
+				So(files["root.go"], ShouldContainSubstring, "Label Label")
+			})
+
+			Convey("Then the aliases should be interchangeable with their types", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	var other Other = Other{Name: "x"}
+	r := root{Thing: other, Label: "y"}
+	var s string = r.Label
+	fmt.Println(r.Thing.Name, s)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "x y\n")
+			})
+		})
+
+		Convey("When we generate the types without --aliases", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the ref should resolve to its type and the primitive should be a named type", func() {
+				So(files, ShouldNotContainKey, "Alias.go")
+				So(files["root.go"], ShouldContainSubstring, "Thing Other")
+				So(files["Label.go"], ShouldContainSubstring, "type Label string\n")
+			})
+		})
+	})
+}
+
+func TestIotaEnums(t *testing.T) {
+	Convey("Given integer enums with and without gaps", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"fromZero": {"enum": [0, 1, 2]},
+				"fromOne": {"enum": [1, 2, 3]},
+				"gapped": {"enum": [0, 2, 3]},
+				"fromTwo": {"enum": [2, 3, 4]},
+				"unordered": {"enum": [1, 0, 2]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then contiguous enums from 0 or 1 should use iota", func() {
+				So(files["FromZero.go"], ShouldContainSubstring, "const (\n\tFromZero0 FromZero = iota\n\tFromZero1\n\tFromZero2\n)\n")
+				So(files["FromOne.go"], ShouldContainSubstring, "const (\n\tFromOne1 FromOne = iota + 1\n\tFromOne2\n\tFromOne3\n)\n")
+			})
+
+			Convey("Then other enums should use explicit values", func() {
+				So(files["Gapped.go"], ShouldContainSubstring, "Gapped2 Gapped = 2\n")
+				So(files["FromTwo.go"], ShouldContainSubstring, "FromTwo2 FromTwo = 2\n")
+				So(files["Unordered.go"], ShouldContainSubstring, "Unordered0 Unordered = 0\n")
+			})
+
+			Convey("Then the constants should have the enum's values", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(FromZero0, FromZero2, FromOne1, FromOne3, Gapped2)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "0 2 1 3 2\n")
+			})
+		})
+	})
+}
+
+func TestIntegerEnums(t *testing.T) {
+	Convey("Given integer enums", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"priority": {
+					"type": "integer",
+					"enum": [1, 2, 3],
+					"x-enum-varnames": ["PriorityLow", "PriorityMedium", "PriorityHigh"],
+					"x-enum-descriptions": ["Can wait", "Soon", "Right\nnow"]
+				},
+				"level": {"enum": [-1, 0, 1]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then they should get integer types", func() {
+				So(files["Priority.go"], ShouldContainSubstring, "type Priority int64")
+				So(files["Level.go"], ShouldContainSubstring, "type Level int64")
+				So(files["root.go"], ShouldContainSubstring, "Priority Priority")
+			})
+
+			Convey("Then the constants should be named and documented from the parallel arrays", func() {
+				So(files["Priority.go"], ShouldContainSubstring, "PriorityLow    Priority = iota + 1 // Can wait\n")
+				So(files["Priority.go"], ShouldContainSubstring, "PriorityMedium                     // Soon\n")
+				So(files["Priority.go"], ShouldContainSubstring, "PriorityHigh                       // Right now\n")
+			})
+
+			Convey("Then constants without varnames should be named from their values", func() {
+				So(files["Level.go"], ShouldContainSubstring, "LevelMinus1 Level = -1\n")
+				So(files["Level.go"], ShouldContainSubstring, "Level0      Level = 0\n")
+				So(files["Level.go"], ShouldContainSubstring, "Level1      Level = 1\n")
+			})
+		})
+	})
+}
+
+func TestCollapseWrappers(t *testing.T) {
+	Convey("Given a schema with single-property wrapper objects", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"box": {"type": "object", "properties": {"value": {"type": "string"}}}
+			},
+			"properties": {
+				"count": {"type": "object", "properties": {"value": {"type": "integer"}}, "required": ["value"]},
+				"box": {"$ref": "#/definitions/box"},
+				"pair": {"type": "object", "properties": {"a": {"type": "string"}, "b": {"type": "string"}}}
+			},
+			"required": ["count", "box", "pair"]
+		}`
+
+		Convey("When we generate the types with --collapse-wrappers", func() {
+			*collapseWrappers = true
+			defer func() { *collapseWrappers = false }()
+			files := generateSchema(schema)
+
+			Convey("Then an inline wrapper should be collapsed to its value's type", func() {
+				So(files["Count.go"], ShouldContainSubstring, "// Count is the value of the \"value\" property of a JSON object.\ntype Count int64\n")
+			})
+
+			Convey("Then definitions and objects with more properties should be kept", func() {
+				So(files["Box.go"], ShouldContainSubstring, "type Box struct {")
+				So(files["Pair.go"], ShouldContainSubstring, "type Pair struct {")
+			})
+
+			Convey("Then the collapsed type should still be an object in JSON", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	if err := json.Unmarshal([]byte(` + "`" + `{"count": {"value": 3}}` + "`" + `), &r); err != nil {
+		panic(err)
+	}
+	fmt.Println(int64(r.Count))
+
+	out, _ := json.Marshal(root{Count: 4})
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "3\n{\"box\":{},\"count\":{\"value\":4},\"pair\":{}}\n")
+			})
+		})
+
+		Convey("When we generate the types without --collapse-wrappers", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the wrapper should be a struct", func() {
+				So(files["Count.go"], ShouldContainSubstring, "type Count struct {")
+			})
+		})
+	})
+	Convey("Given unions of one variant besides null", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"value": {"oneOf": [{"type": "string"}, {"type": "null"}]},
+				"label": {"oneOf": [{"type": "string"}]}
+			}
+		}`
+
+		Convey("When we generate the types with --collapse-wrappers", func() {
+			*collapseWrappers = true
+			defer func() { *collapseWrappers = false }()
+			files := generateSchema(schema)
+
+			Convey("Then they shouldn't be collapsed, so their JSON methods aren't declared twice", func() {
+				So(files["Label.go"], ShouldContainSubstring, "type Label struct {")
+				_, err := runGenerated(files, "package main\n\nfunc main() {}\n")
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestErrors(t *testing.T) {
+	generateErr := func(schemaJSON string) error {
+		*rootTypeName = "root"
+		if err := run([]byte(schemaJSON), options); err != nil {
+			return err
+		}
+		_, err := renderTypes()
+		return err
+	}
+
+	Convey("Given a property that doesn't make a Go identifier", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"child": {"type": "object", "properties": {"$$": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			err := generateErr(schema)
+
+			Convey("Then it should return an error naming the property", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `#/properties/child/properties/$$: can't generate a field name from "$$"`)
+				nameErr, ok := err.(*nameError)
+				So(ok, ShouldBeTrue)
+				So(nameErr.Kind, ShouldEqual, "field")
+			})
+		})
+	})
+
+	Convey("Given a definition whose name doesn't make a Go identifier", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {"%%": {"type": "object", "properties": {"a": {"type": "string"}}}}
+		}`
+
+		Convey("When we generate the types", func() {
+			err := generateErr(schema)
+
+			Convey("Then it should return an error naming the definition", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `#/definitions/%%: can't generate a type name from "%%"`)
+			})
+		})
+	})
+
+	Convey("Given refs that only refer to each other", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"x": {"$ref": "#/definitions/y"},
+				"y": {"$ref": "#/definitions/x"}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			err := generateErr(schema)
+
+			Convey("Then it should return an error with the cycle", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "circular $ref: #/definitions/x -> #/definitions/y -> #/definitions/x")
+			})
+		})
+	})
+}
+
+func TestAccessors(t *testing.T) {
+	Convey("Given a schema with optional fields generated as pointers", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"count": {"type": "integer"},
+				"id": {"type": "string"}
+			},
+			"required": ["id"]
+		}`
+		*ptrForOmit = true
+		defer func() { *ptrForOmit = false }()
+
+		Convey("When we generate the types with commaok accessors", func() {
+			*accessors = accessorsCommaOK
+			defer func() { *accessors = accessorsNone }()
+			files := generateSchema(schema)
+
+			Convey("Then only pointer fields should get accessors", func() {
+				So(files["root.go"], ShouldContainSubstring, "func (t root) GetName() (string, bool)")
+				So(files["root.go"], ShouldContainSubstring, "func (t root) GetCount() (int64, bool)")
+				So(files["root.go"], ShouldNotContainSubstring, "GetID")
+			})
+
+			Convey("Then they should report whether the field is set", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	var unset root
+	fmt.Println(unset.GetName())
+
+	name := "x"
+	set := root{Name: &name}
+	fmt.Println(set.GetName())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, " false\nx true\n")
+			})
+		})
+
+		Convey("When we generate the types without accessors", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no accessors", func() {
+				So(files["root.go"], ShouldNotContainSubstring, "GetName")
+			})
+		})
+	})
+}
+
+func TestRedactSecrets(t *testing.T) {
+	Convey("Given a schema with writeOnly and x-go-secret properties", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"user": {"type": "string"},
+				"password": {"type": "string", "writeOnly": true},
+				"token": {"type": "string", "x-go-secret": true},
+				"profile": {"type": "object", "properties": {"nick": {"type": "string"}}}
+			},
+			"required": ["user", "password", "token", "profile"]
+		}`
+
+		Convey("When we generate the types with --redact-secrets", func() {
+			*redactSecrets = true
+			defer func() { *redactSecrets = false }()
+			files := generateSchema(schema)
+
+			Convey("Then only types with secret fields should get a String method", func() {
+				So(files["root.go"], ShouldContainSubstring, "func (t root) String() string")
+				So(files["Profile.go"], ShouldNotContainSubstring, "String()")
+			})
+
+			Convey("Then formatting a value should not print the secrets", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	r := root{User: "bob", Password: "hunter2", Token: "s3cret", Profile: Profile{Nick: "b"}}
+	fmt.Println(fmt.Sprintf("%v", r))
+	fmt.Println(fmt.Sprintf("%v", &r))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldNotContainSubstring, "hunter2")
+				So(out, ShouldNotContainSubstring, "s3cret")
+				So(out, ShouldEqual, "{Password:[REDACTED] Profile:{b} Token:[REDACTED] User:bob}\n{Password:[REDACTED] Profile:{b} Token:[REDACTED] User:bob}\n")
+			})
+		})
+
+		Convey("When we generate the types without --redact-secrets", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no String method", func() {
+				So(files["root.go"], ShouldNotContainSubstring, "String()")
+			})
+		})
+	})
+}
+
+func TestNetworkFormats(t *testing.T) {
+	Convey("Given a schema with network formats", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"v4": {"type": "string", "format": "ipv4"},
+				"v6": {"type": "string", "format": "ipv6"},
+				"host": {"type": "string", "format": "hostname"}
+			}
+		}`
+
+		Convey("When we generate the types with --validate", func() {
+			*validate = true
+			defer func() { *validate = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the formats should be strings checked by Validate", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	valid := root{V4: "192.168.0.1", V6: "2001:db8::1", Host: "api.example.com"}
+	fmt.Println(valid.Validate())
+
+	badV4 := valid
+	badV4.V4 = "300.1.1.1"
+	fmt.Println(badV4.Validate())
+
+	badV6 := valid
+	badV6.V6 = "192.168.0.1"
+	fmt.Println(badV6.Validate())
+
+	badHost := valid
+	badHost.Host = "-bad-.example.com"
+	fmt.Println(badHost.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\nv4: must be an IPv4 address\nv6: must be an IPv6 address\nhost: must be a valid host name\n")
+			})
+		})
+
+		Convey("When we map the IP formats to another type", func() {
+			options.FormatMappers = map[string]FormatMapping{"ipv4": NewFormatMapping("net/netip.Addr")}
+			defer func() { options.FormatMappers = nil }()
+			files := generateSchema(schema)
+
+			Convey("Then the field should use that type and import its package", func() {
+				So(files["root.go"], ShouldContainSubstring, "V4   netip.Addr")
+				So(files["root.go"], ShouldContainSubstring, `"net/netip"`)
+			})
+		})
+	})
+}
+
+func TestDecimalType(t *testing.T) {
+	Convey("Given a schema with decimal and currency formats", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"rate": {"type": "number", "format": "decimal"},
+				"price": {"type": "number", "format": "currency"},
+				"ratio": {"type": "number"}
+			}
+		}`
+
+		Convey("When we generate the root type with a decimal type", func() {
+			options.DecimalType = "github.com/shopspring/decimal.Decimal"
+			defer func() { options.DecimalType = "" }()
+			files := generateSchema(schema)
+
+			Convey("Then the decimal fields should use that type and import its package", func() {
+				So(files["root.go"], ShouldContainSubstring, "Rate  decimal.Decimal")
+				So(files["root.go"], ShouldContainSubstring, "Price decimal.Decimal")
+				So(files["root.go"], ShouldContainSubstring, `"github.com/shopspring/decimal"`)
+			})
+
+			Convey("Then other numbers should still be float64", func() {
+				So(files["root.go"], ShouldContainSubstring, "Ratio float64")
+			})
+		})
+
+		Convey("When we generate the root type without a decimal type", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the decimal fields should be float64", func() {
+				So(files["root.go"], ShouldContainSubstring, "Rate  float64")
+				So(files["root.go"], ShouldNotContainSubstring, "decimal")
+			})
+		})
+	})
+}
+
+func TestPropertiesName(t *testing.T) {
+	Convey("Given a schema with a definition and a property named properties", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"properties": {"type": "object", "properties": {"name": {"type": "string"}}}
+			},
+			"properties": {
+				"properties": {"$ref": "#/definitions/properties"}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			generated := processSchema(schema)
+
+			Convey("Then a Properties type should be generated", func() {
+				So(printType(generated["Properties"]), ShouldContainSubstring, "type Properties struct")
+				So(printType(generated["root"]), ShouldContainSubstring, "Properties Properties")
+			})
+		})
+	})
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	Convey("Given a schema with enums, constraints and colliding names", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"color": {"type": "string", "enum": ["red", "green", "blue"]},
+				"shade": {"type": "string", "enum": ["red", "dark", "light"]},
+				"item": {"type": "object", "properties": {"name": {"type": "string", "pattern": "^[a-z]+$"}}}
+			},
+			"properties": {
+				"stock": {"type": "object", "properties": {"count": {"type": "integer", "minimum": 0}}},
+				"items": {"type": "array", "items": {"$ref": "#/definitions/item"}},
+				"color": {"$ref": "#/definitions/color"},
+				"shade": {"$ref": "#/definitions/shade"},
+				"size": {"type": "string", "minLength": 1, "maxLength": 10}
+			}
+		}`
+		*validate = true
+		*enumNaming = enumNamingValue
+		defer func() {
+			*validate = false
+			*enumNaming = enumNamingTypeValue
+		}()
+
+		Convey("When we generate the types several times", func() {
+			first := generateSchema(schema)
+			var runs []map[string]string
+			for i := 0; i < 5; i++ {
+				runs = append(runs, generateSchema(schema))
+			}
+
+			Convey("Then the output should be identical every time", func() {
+				for _, run := range runs {
+					So(run, ShouldResemble, first)
+				}
+			})
+		})
+	})
+}
+
+func TestRootPointer(t *testing.T) {
+	Convey("Given an OpenAPI-style document with several schemas", t, func() {
+		doc := `{
+			"openapi": "3.0.0",
+			"components": {
+				"schemas": {
+					"User": {
+						"type": "object",
+						"properties": {
+							"name": {"type": "string"},
+							"address": {"$ref": "#/components/schemas/Address"}
+						}
+					},
+					"Address": {
+						"type": "object",
+						"properties": {"street": {"type": "string"}}
+					},
+					"Other": {
+						"type": "object",
+						"properties": {"unused": {"type": "string"}}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate from a pointer to one of them", func() {
+			files, err := Generate([]byte(doc), Options{RootTypeName: "user", RootPointer: "#/components/schemas/User"})
+
+			Convey("Then only it and the types it refers to should be generated", func() {
+				So(err, ShouldBeNil)
+				names := stringset.New()
+				for _, gt := range types {
+					names.Add(gt.Name)
+				}
+				So(names.Sorted(), ShouldResemble, []string{"Address", "user"})
+				So(files, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When the pointer doesn't exist", func() {
+			_, err := Generate([]byte(doc), Options{RootPointer: "/components/schemas/Missing"})
+
+			Convey("Then we should get an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestNolint(t *testing.T) {
+	Convey("Given a simple schema", t, func() {
+		schema := `{"type": "object", "properties": {"name": {"type": "string"}}}`
+
+		Convey("When we generate it without --nolint", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no directive", func() {
+				So(files["root.go"], ShouldNotContainSubstring, "//nolint")
+			})
+		})
+
+		Convey("When we generate it with --nolint", func() {
+			*nolint = true
+			defer func() { *nolint = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the directive should come before the package clause", func() {
+				So(files["root.go"], ShouldStartWith, "//nolint:all\npackage main\n")
+			})
+		})
+
+		Convey("When we generate it with --nolint for specific linters", func() {
+			*nolint = true
+			*nolintLinters = "lll,revive"
+			defer func() {
+				*nolint = false
+				*nolintLinters = "all"
+			}()
+			files := generateSchema(schema)
+
+			Convey("Then the directive should name them", func() {
+				So(files["root.go"], ShouldStartWith, "//nolint:lll,revive\npackage main\n")
+			})
+		})
+	})
+}
+
+func TestSchemaVersion(t *testing.T) {
+	Convey("Given a schema with a boolean exclusiveMinimum", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"qty": {"type": "integer", "minimum": 1, "exclusiveMinimum": true}
+			}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When it's interpreted as draft-04", func() {
+			*schemaVersion = draft04
+			defer func() { *schemaVersion = "" }()
+			files := generateSchema(schema)
+
+			Convey("Then the minimum should be exclusive", func() {
+				So(files["root.go"], ShouldContainSubstring, "if float64(t.Qty) <= 1 {")
+			})
+		})
+
+		Convey("When it's interpreted as draft-06", func() {
+			*schemaVersion = draft06
+			defer func() { *schemaVersion = "" }()
+			files := generateSchema(schema)
+
+			Convey("Then the boolean should be ignored", func() {
+				So(files["root.go"], ShouldContainSubstring, "if float64(t.Qty) < 1 {")
+			})
+		})
+	})
+
+	Convey("Given a schema with a numeric exclusiveMinimum", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"price": {"type": "number", "exclusiveMinimum": 0}
+			}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When it's interpreted as draft-06", func() {
+			*schemaVersion = draft06
+			defer func() { *schemaVersion = "" }()
+			files := generateSchema(schema)
+
+			Convey("Then it should be an exclusive bound", func() {
+				So(files["root.go"], ShouldContainSubstring, "if t.Price <= 0 {")
+			})
+		})
+
+		Convey("When it's interpreted as draft-04", func() {
+			*schemaVersion = draft04
+			defer func() { *schemaVersion = "" }()
+			files := generateSchema(schema)
+
+			Convey("Then it should be ignored", func() {
+				So(files["root.go"], ShouldNotContainSubstring, "Validate")
+			})
+		})
+	})
+
+	Convey("Given $schema URIs", t, func() {
+		Convey("Then the version should be detected from them", func() {
+			So(detectSchemaVersion("http://json-schema.org/draft-04/schema#"), ShouldEqual, draft04)
+			So(detectSchemaVersion("http://json-schema.org/draft-06/schema#"), ShouldEqual, draft06)
+			So(detectSchemaVersion("https://json-schema.org/draft/2019-09/schema"), ShouldEqual, draft2019)
+			So(detectSchemaVersion("https://json-schema.org/draft/2020-12/schema"), ShouldEqual, draft2020)
+			So(detectSchemaVersion(""), ShouldEqual, draft07)
+		})
+	})
+}
+
+func TestVocabulary(t *testing.T) {
+	schema := func(vocabulary string) string {
+		return `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			` + vocabulary + `
+			"type": "object",
+			"properties": {
+				"since": {"type": "string", "format": "date-time"}
+			}
+		}`
+	}
+
+	Convey("Given a schema whose $vocabulary leaves out the format vocabulary", t, func() {
+		files := generateSchema(schema(`"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"https://json-schema.org/draft/2020-12/vocab/validation": true
+		},`))
+
+		Convey("Then formats shouldn't change types", func() {
+			So(files["root.go"], ShouldContainSubstring, "Since string `json:\"since,omitempty\"`")
+		})
+	})
+
+	Convey("Given a schema whose $vocabulary doesn't require the format vocabulary", t, func() {
+		files := generateSchema(schema(`"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"https://json-schema.org/draft/2020-12/vocab/format-annotation": false
+		},`))
+
+		Convey("Then formats shouldn't change types", func() {
+			So(files["root.go"], ShouldContainSubstring, "Since string `json:\"since,omitempty\"`")
+		})
+	})
+
+	Convey("Given a schema whose $vocabulary requires the format vocabulary", t, func() {
+		files := generateSchema(schema(`"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"https://json-schema.org/draft/2020-12/vocab/format-annotation": true
+		},`))
+
+		Convey("Then formats should change types", func() {
+			So(files["root.go"], ShouldContainSubstring, "Since time.Time `json:\"since,omitempty\"`")
+		})
+	})
+
+	Convey("Given a schema without $vocabulary", t, func() {
+		files := generateSchema(schema(""))
+
+		Convey("Then formats should change types", func() {
+			So(files["root.go"], ShouldContainSubstring, "Since time.Time `json:\"since,omitempty\"`")
+		})
+	})
+}
+
+func TestBooleanRequired(t *testing.T) {
+	Convey("Given a draft-03 schema with required flags on its properties", t, func() {
+		schema := `{
+			"$schema": "http://json-schema.org/draft-03/schema#",
+			"type": "object",
+			"required": ["id"],
+			"properties": {
+				"id": {"type": "integer"},
+				"name": {"type": "string", "required": true},
+				"note": {"type": "string", "required": false},
+				"address": {
+					"type": "object",
+					"required": true,
+					"properties": {"city": {"type": "string", "required": true}}
+				}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the flagged properties should be required", func() {
+				So(files["root.go"], ShouldContainSubstring, "Name    string  `json:\"name\"`")
+				So(files["root.go"], ShouldContainSubstring, "ID      int64   `json:\"id\"`")
+				So(files["root.go"], ShouldContainSubstring, "Address Address `json:\"address\"`")
+				So(files["Address.go"], ShouldContainSubstring, "City string `json:\"city\"`")
+			})
+
+			Convey("Then the other properties should be optional", func() {
+				So(files["root.go"], ShouldContainSubstring, "Note    string  `json:\"note,omitempty\"`")
+			})
+		})
+	})
+}
+
+func TestOutputTest(t *testing.T) {
+	Convey("Given a schema with examples", t, func() {
+		schema := `{
+			"type": "object",
+			"examples": [{"name": "x", "tags": ["a"], "when": "2020-01-02T03:04:05Z"}],
+			"properties": {
+				"name": {"type": "string"},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"when": {"type": "string", "format": "date-time"},
+				"status": {"type": "string", "enum": ["on", "off"]},
+				"extra": {"type": "object", "additionalProperties": {"type": "integer"}}
+			}
+		}`
+
+		Convey("When we generate it without --output-test", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no test file", func() {
+				So(files, ShouldNotContainKey, "root_schematype_test.go")
+			})
+		})
+
+		Convey("When we generate it with --output-test", func() {
+			*outputTest = true
+			defer func() { *outputTest = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the test file should cover the zero values and examples", func() {
+				So(files, ShouldContainKey, "root_schematype_test.go")
+				testFile := files["root_schematype_test.go"]
+				So(testFile, ShouldStartWith, "package main\n")
+				So(testFile, ShouldContainSubstring, "func TestZeroValuesMarshal(t *testing.T)")
+				So(testFile, ShouldContainSubstring, "func TestExamplesRoundTrip(t *testing.T)")
+			})
+
+			Convey("Then the generated tests should pass", func() {
+				out, err := testGenerated(files)
+				So(err, ShouldBeNil)
+				So(out, ShouldContainSubstring, "--- PASS: TestZeroValuesMarshal")
+				So(out, ShouldContainSubstring, "--- PASS: TestExamplesRoundTrip")
+			})
+		})
+
+		Convey("When an example doesn't match the type", func() {
+			*outputTest = true
+			defer func() { *outputTest = false }()
+			files := generateSchema(`{"type": "object", "examples": [{"name": 1}], "properties": {"name": {"type": "string"}}}`)
+
+			Convey("Then the generated test should fail", func() {
+				out, err := testGenerated(files)
+				So(err, ShouldNotBeNil)
+				So(out, ShouldContainSubstring, "--- FAIL: TestExamplesRoundTrip")
+			})
+		})
+	})
+}
+
+func TestScalarPropertyRefs(t *testing.T) {
+	Convey("Given a schema referring to scalar properties of another type", t, func() {
+		schema := `{
+			"type": "object",
+			"definitions": {
+				"user": {
+					"type": "object",
+					"properties": {
+						"email": {"type": "string", "format": "email"},
+						"age": {"type": "integer", "minimum": 0},
+						"created": {"type": "string", "format": "date-time"}
+					}
+				},
+				"email": {"$ref": "#/definitions/user/properties/email"}
+			},
+			"properties": {
+				"contact": {"$ref": "#/definitions/user/properties/email"},
+				"age": {"$ref": "#/definitions/user/properties/age"},
+				"since": {"$ref": "#/definitions/user/properties/created"},
+				"primary": {"$ref": "#/definitions/email"}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			*validate = true
+			defer func() { *validate = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the properties should have the referenced properties' types", func() {
+				root := printType(types["#"])
+				So(root, ShouldContainSubstring, "Contact string `json:\"contact,omitempty\"`")
+				So(root, ShouldContainSubstring, "Age int64 `json:\"age,omitempty\"`")
+				So(root, ShouldContainSubstring, "Since time.Time `json:\"since,omitempty\"`")
+				So(root, ShouldContainSubstring, "Primary Email `json:\"primary,omitempty\"`")
+			})
+
+			Convey("Then the referenced properties' constraints should be checked", func() {
+				So(files["root.go"], ShouldContainSubstring, `errors.New("age: must be >= 0")`)
+			})
+
+			Convey("Then a definition that refers to one should get its type", func() {
+				So(files["Email.go"], ShouldContainSubstring, "type Email string")
+			})
+		})
+	})
+}
+
+func TestVerbose(t *testing.T) {
+	Convey("Given a schema with a ref to a definition that's processed later", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"owner": {"$ref": "#/definitions/user"},
+				"data": {}
+			},
+			"definitions": {
+				"user": {"$ref": "#/definitions/person"},
+				"person": {"type": "object", "properties": {"name": {"type": "string"}}}
+			}
+		}`
+
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		log.SetFlags(0)
+		defer func() {
+			log.SetOutput(os.Stderr)
+			log.SetFlags(log.LstdFlags)
+		}()
+
+		Convey("When we generate it without -v", func() {
+			quiet := generateSchema(schema)
+
+			Convey("Then nothing should be logged", func() {
+				So(logs.String(), ShouldEqual, "")
+			})
+
+			Convey("When we generate it with -v", func() {
+				*verbose = true
+				defer func() { *verbose = false }()
+				files := generateSchema(schema)
+
+				Convey("Then the output should be the same", func() {
+					So(files, ShouldResemble, quiet)
+				})
+
+				Convey("Then the types, fields, refs and deferrals should be logged", func() {
+					out := logs.String()
+					So(out, ShouldContainSubstring, "#: type root struct\n")
+					So(out, ShouldContainSubstring, "#/properties/data: field Data interface{}\n")
+					So(out, ShouldContainSubstring, "#/properties/owner: field Owner Person\n")
+					So(out, ShouldContainSubstring, "#/definitions/user: refers to #/definitions/person\n")
+					So(out, ShouldContainSubstring, "#/definitions/person: type Person struct\n")
+				})
+			})
+		})
+
+		Convey("When a ref can't be resolved yet with -v", func() {
+			*verbose = true
+			defer func() { *verbose = false }()
+			resetState()
+			s := getTypeSchema(map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"owner": map[string]interface{}{"$ref": "#/definitions/later"}},
+			})
+			_, err := processType(s, "thing", "", "#/definitions/thing", "#")
+
+			Convey("Then the deferral should be logged with the ref", func() {
+				So(err, ShouldBeNil)
+				So(logs.String(), ShouldContainSubstring, "#/definitions/thing: deferred, waiting on #/definitions/later\n")
+			})
+		})
+	})
+}
+
+func TestMultiplePatternProperties(t *testing.T) {
+	Convey("Given an object with two patternProperties of different value types", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"metrics": {
+					"type": "object",
+					"patternProperties": {
+						"^s_": {"type": "string"},
+						"^n_": {"type": "number"}
+					}
+				},
+				"strict": {
+					"type": "object",
+					"additionalProperties": false,
+					"patternProperties": {
+						"^a": {"type": "integer"},
+						"^b": {"type": "boolean"}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the object should have a map for each pattern and a catch-all", func() {
+				metrics := printType(types["#/properties/metrics"])
+				So(metrics, ShouldContainSubstring, "SProperties map[string]SProperty `json:\"-\"`")
+				So(metrics, ShouldContainSubstring, "NProperties map[string]NProperty `json:\"-\"`")
+				So(metrics, ShouldContainSubstring, "AdditionalProperties map[string]interface{} `json:\"-\"`")
+			})
+
+			Convey("Then there should be no catch-all if additionalProperties is false", func() {
+				So(printType(types["#/properties/strict"]), ShouldNotContainSubstring, "AdditionalProperties")
+			})
+
+			Convey("Then properties should be routed by pattern", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var m Metrics
+	err := json.Unmarshal([]byte(` + "`" + `{"s_name": "x", "n_count": 2.5, "other": true}` + "`" + `), &m)
+	fmt.Println(err, m.SProperties, m.NProperties, m.AdditionalProperties)
+
+	out, _ := json.Marshal(m)
+	fmt.Println(string(out))
+
+	err = json.Unmarshal([]byte(` + "`" + `{"n_count": "x"}` + "`" + `), &m)
+	fmt.Println(err != nil)
+
+	var s Strict
+	err = json.Unmarshal([]byte(` + "`" + `{"a1": 1, "b1": true, "c1": null}` + "`" + `), &s)
+	fmt.Println(err, s.AProperties, s.BProperties)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `<nil> map[s_name:x] map[n_count:2.5] map[other:true]
+{"n_count":2.5,"other":true,"s_name":"x"}
+true
+<nil> map[a1:1] map[b1:true]
+`)
+			})
+		})
+	})
+}
+
+func TestSinglePatternProperties(t *testing.T) {
+	Convey("Given objects with a single patternProperties pattern", t, func() {
+		*validate = true
+		defer func() { *validate = false }()
+
+		schema := `{
+			"type": "object",
+			"properties": {
+				"labels": {"type": "object", "patternProperties": {"^[a-z]+$": {"type": "string"}}},
+				"extra": {"type": "object", "patternProperties": {"^x-": {}}},
+				"open": {"type": "object", "patternProperties": {"^a": {"type": "string"}}, "additionalProperties": true}
+			},
+			"definitions": {
+				"headers": {"type": "object", "patternProperties": {"^[A-Z]": {"type": "string", "maxLength": 3}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then they should be maps of the pattern's schema", func() {
+				root := printType(types["#"])
+				So(root, ShouldContainSubstring, "Labels map[string]Label `json:\"labels,omitempty\"`")
+				So(root, ShouldContainSubstring, "Extra map[string]interface{} `json:\"extra,omitempty\"`")
+				So(printType(types["#/definitions/headers"]), ShouldStartWith, "type Headers map[string]Header")
+			})
+
+			Convey("Then one that allows other properties should stay a map of any value", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "Open map[string]interface{} `json:\"open,omitempty\"`")
+			})
+
+			Convey("Then Validate should check the keys against the pattern", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(root{Labels: map[string]Label{"env": "prod"}}.Validate())
+	fmt.Println(root{Labels: map[string]Label{"Env": "prod"}}.Validate())
+	fmt.Println(Headers{"Accept": "abc"}.Validate())
+	fmt.Println(Headers{"accept": "abc"}.Validate())
+	fmt.Println(Headers{"Accept": "abcd"}.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `<nil>
+labels: keys must match pattern ^[a-z]+$
+<nil>
+keys must match pattern ^[A-Z]
+length must be at most 3
+`)
+			})
+		})
+	})
+}
+
+func TestSortedMaps(t *testing.T) {
+	Convey("Given a schema with open objects", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+				"metrics": {
+					"type": "object",
+					"patternProperties": {"^s_": {"type": "string"}, "^n_": {"type": "number"}}
+				}
+			},
+			"definitions": {
+				"settings": {"type": "object", "additionalProperties": {"type": "integer"}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then marshaling should give the same key-sorted JSON each time", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	r := root{
+		Labels: map[string]Label{"zeta": "z", "alpha": "a", "mid": "m", "beta": "b"},
+		Metrics: Metrics{
+			SProperties:          map[string]SProperty{"s_z": "z", "s_a": "a"},
+			NProperties:          map[string]NProperty{"n_b": 2, "n_a": 1},
+			AdditionalProperties: map[string]interface{}{"y": true, "b": false},
+		},
+	}
+	s := Settings{"c": 3, "a": 1, "b": 2}
+
+	first, _ := json.Marshal(r)
+	same := true
+	for i := 0; i < 20; i++ {
+		again, _ := json.Marshal(r)
+		same = same && string(again) == string(first)
+	}
+	fmt.Println(string(first), same)
+
+	out, _ := json.Marshal(s)
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `{"labels":{"alpha":"a","beta":"b","mid":"m","zeta":"z"},"metrics":{"b":false,"n_a":1,"n_b":2,"s_a":"a","s_z":"z","y":true}} true
+{"a":1,"b":2,"c":3}
+`)
+			})
+		})
+	})
+}
+
+func TestFieldDocLinks(t *testing.T) {
+	Convey("Given a schema with struct, slice of struct and scalar fields", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"address": {"type": "object", "properties": {"street": {"type": "string"}}},
+				"lines": {"type": "array", "items": {"type": "object", "properties": {"qty": {"type": "integer"}}}},
+				"name": {"type": "string"}
+			}
+		}`
+
+		Convey("When we generate it without --field-doc-links", func() {
+			processSchema(schema)
+
+			Convey("Then there should be no doc links", func() {
+				So(printType(types["#"]), ShouldNotContainSubstring, "// See")
+			})
+		})
+
+		Convey("When we generate it with --field-doc-links", func() {
+			*fieldDocLinks = true
+			defer func() { *fieldDocLinks = false }()
+			processSchema(schema)
+			root := printType(types["#"])
+
+			Convey("Then struct-typed fields should link to their type", func() {
+				So(root, ShouldContainSubstring, "// See [Address].\nAddress Address")
+				So(root, ShouldContainSubstring, "// See [Line].\nLines []*Line")
+			})
+
+			Convey("Then scalar fields should not", func() {
+				So(root, ShouldNotContainSubstring, "// See [Name]")
+				So(strings.Count(root, "// See"), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestSplitReadWrite(t *testing.T) {
+	Convey("Given a schema with required readOnly properties", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["id", "name", "owner"],
+			"properties": {
+				"id": {"type": "string", "readOnly": true},
+				"name": {"type": "string", "minLength": 1},
+				"created": {"type": "string", "format": "date-time", "readOnly": true},
+				"owner": {"$ref": "#/definitions/user"},
+				"tags": {"type": "array", "items": {"type": "string"}}
+			},
+			"definitions": {
+				"user": {
+					"type": "object",
+					"required": ["userId"],
+					"properties": {
+						"userId": {"type": "integer", "readOnly": true},
+						"email": {"type": "string"}
+					}
+				},
+				"note": {"type": "object", "properties": {"text": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate it without --split-rw", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no request types", func() {
+				So(files, ShouldNotContainKey, "rootRequest.go")
+			})
+		})
+
+		Convey("When we generate it with --split-rw", func() {
+			*splitRW = true
+			*validate = true
+			defer func() {
+				*splitRW = false
+				*validate = false
+			}()
+			files := generateSchema(schema)
+
+			Convey("Then the request type should neither contain nor require the readOnly fields", func() {
+				request := printType(types["#@Request"])
+				So(request, ShouldContainSubstring, "type rootRequest struct")
+				So(request, ShouldNotContainSubstring, "ID ")
+				So(request, ShouldNotContainSubstring, "Created ")
+				So(request, ShouldContainSubstring, "Name string `json:\"name\"`")
+				So(request, ShouldContainSubstring, "Tags []*Tag `json:\"tags,omitempty\"`")
+			})
+
+			Convey("Then it should refer to the request types of nested structs", func() {
+				So(printType(types["#@Request"]), ShouldContainSubstring, "Owner UserRequest `json:\"owner\"`")
+				So(printType(types["#/definitions/user@Request"]), ShouldNotContainSubstring, "UserID")
+			})
+
+			Convey("Then the full types should be unchanged", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "ID string `json:\"id\"`")
+				So(printType(types["#"]), ShouldContainSubstring, "Owner User `json:\"owner\"`")
+			})
+
+			Convey("Then structs without readOnly properties shouldn't get request types", func() {
+				So(files, ShouldNotContainKey, "NoteRequest.go")
+			})
+
+			Convey("Then the request types should be checked too", func() {
+				So(files["rootRequest.go"], ShouldContainSubstring, "func (t rootRequest) Validate() error")
+			})
+		})
+	})
+}
+
+func TestSplitReadWriteResponse(t *testing.T) {
+	Convey("Given a schema with a required writeOnly property", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["id", "username", "password"],
+			"properties": {
+				"id": {"type": "string", "readOnly": true},
+				"username": {"type": "string"},
+				"password": {"type": "string", "writeOnly": true},
+				"profile": {"$ref": "#/definitions/profile"}
+			},
+			"definitions": {
+				"profile": {
+					"type": "object",
+					"required": ["pin"],
+					"properties": {
+						"pin": {"type": "string", "writeOnly": true},
+						"bio": {"type": "string"}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate it with --split-rw", func() {
+			*splitRW = true
+			defer func() { *splitRW = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the response type should neither contain nor require the writeOnly field", func() {
+				response := printType(types["#@Response"])
+				So(response, ShouldContainSubstring, "type rootResponse struct")
+				So(response, ShouldNotContainSubstring, "Password")
+				So(response, ShouldContainSubstring, "ID string `json:\"id\"`")
+				So(response, ShouldContainSubstring, "Profile ProfileResponse `json:\"profile,omitempty\"`")
+				So(printType(types["#/definitions/profile@Response"]), ShouldNotContainSubstring, "Pin")
+			})
+
+			Convey("Then the request type should still contain and require it", func() {
+				request := printType(types["#@Request"])
+				So(request, ShouldContainSubstring, "Password string `json:\"password\"`")
+				So(request, ShouldNotContainSubstring, "ID ")
+			})
+
+			Convey("Then the variants shouldn't get variants of their own", func() {
+				So(files, ShouldNotContainKey, "rootRequestResponse.go")
+				So(files, ShouldNotContainKey, "rootResponseRequest.go")
+			})
+		})
+	})
+}
+
+func TestPreserveUnknown(t *testing.T) {
+	Convey("Given a schema for an object with a nested object", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"count": {"type": "integer"},
+				"address": {"type": "object", "properties": {"street": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate it without --preserve-unknown", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be no raw field", func() {
+				So(files["root.go"], ShouldNotContainSubstring, "raw")
+			})
+		})
+
+		Convey("When we generate it with --preserve-unknown", func() {
+			*preserveUnknown = true
+			defer func() { *preserveUnknown = false }()
+			files := generateSchema(schema)
+
+			Convey("Then the structs should have a hidden raw field", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "raw map[string]json.RawMessage\n")
+			})
+
+			Convey("Then unknown properties should survive a decode and encode", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	in := ` + "`" + `{"name": "a", "count": 1, "extra": {"x": [1, 2]}, "address": {"street": "s", "zip": "123"}}` + "`" + `
+	if err := json.Unmarshal([]byte(in), &r); err != nil {
+		fmt.Println(err)
+		return
+	}
+	r.Name = "b"
+	r.Count = 0
+	out, err := json.Marshal(r)
+	fmt.Println(string(out), err)
+
+	out, err = json.Marshal(root{Name: "c"})
+	fmt.Println(string(out), err)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `{"address":{"street":"s","zip":"123"},"extra":{"x":[1,2]},"name":"b"} <nil>
+{"address":{},"name":"c"} <nil>
+`)
+			})
+		})
+
+		Convey("When a struct is embedded by another with --preserve-unknown", func() {
+			*preserveUnknown = true
+			defer func() { *preserveUnknown = false }()
+			processSchema(`{
+				"type": "object",
+				"allOf": [{"$ref": "#/definitions/base"}],
+				"properties": {"name": {"type": "string"}},
+				"definitions": {"base": {"type": "object", "properties": {"id": {"type": "string"}}}}
+			}`)
+
+			Convey("Then neither should keep unknown properties", func() {
+				So(types["#"].preservesUnknown(), ShouldBeFalse)
+				So(types["#/definitions/base"].preservesUnknown(), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestPhantomRequired(t *testing.T) {
+	Convey("Given a schema requiring a name that isn't a property", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["name", "nmae", "id", "x_extra"],
+			"allOf": [{"$ref": "#/definitions/base"}],
+			"properties": {"name": {"type": "string"}},
+			"patternProperties": {"^x_": {"type": "string"}},
+			"definitions": {"base": {"type": "object", "properties": {"id": {"type": "string"}}}}
+		}`
+
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		defer log.SetOutput(os.Stderr)
+
+		Convey("When we generate the types", func() {
+			processSchema(schema)
+
+			Convey("Then there should be a warning about only that name", func() {
+				So(logs.String(), ShouldContainSubstring, `Warning: #: required "nmae" not in properties`)
+				So(strings.Count(logs.String(), "Warning"), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When we generate the types with --strict", func() {
+			*strict = true
+			defer func() { *strict = false }()
+			resetState()
+			s := getTypeSchema(map[string]interface{}{
+				"type":       "object",
+				"required":   []interface{}{"nmae"},
+				"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+			})
+			_, err := processType(s, "root", "", "#", "")
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `#: required "nmae" not in properties`)
+			})
+		})
+	})
+}
+
+func TestTypeOrder(t *testing.T) {
+	Convey("Given a schema whose types refer to each other", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"b": {"$ref": "#/definitions/zebra"},
+				"a": {"type": "object", "properties": {"x": {"type": "string"}}}
+			},
+			"definitions": {
+				"zebra": {"type": "object", "properties": {"stripe": {"$ref": "#/definitions/stripe"}}},
+				"stripe": {"type": "object", "properties": {"width": {"type": "integer"}}}
+			}
+		}`
+		*typesList = true
+		defer func() { *typesList = false }()
+
+		order := func(files map[string]string) []string {
+			var names []string
+			for _, line := range strings.Split(files["generatedTypes.go"], "\n") {
+				line = strings.TrimSpace(line)
+				if strings.HasSuffix(line, "{},") {
+					names = append(names, strings.TrimSuffix(line, "{},"))
+				}
+			}
+			return names
+		}
+
+		Convey("When we generate it in the default order", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the types should be alphabetical", func() {
+				So(order(files), ShouldResemble, []string{"A", "Stripe", "Zebra", "root"})
+			})
+		})
+
+		Convey("When we generate it in dependency order", func() {
+			*typeOrder = typeOrderDependency
+			defer func() { *typeOrder = typeOrderAlpha }()
+			files := generateSchema(schema)
+
+			Convey("Then referenced types should come before the types using them", func() {
+				So(order(files), ShouldResemble, []string{"A", "Stripe", "Zebra", "root"})
+			})
+		})
+
+		Convey("When we generate it in definition order", func() {
+			*typeOrder = typeOrderDefinition
+			defer func() { *typeOrder = typeOrderAlpha }()
+			files := generateSchema(schema)
+
+			Convey("Then the root type should come first, then the others in the order they were reached", func() {
+				So(order(files), ShouldResemble, []string{"root", "Stripe", "Zebra", "A"})
+			})
+		})
+	})
+}
+
+func TestFormatMappers(t *testing.T) {
+	Convey("Given a schema with a custom format", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"fg": {"type": "string", "format": "color"},
+				"created": {"type": "string", "format": "date-time"},
+				"name": {"type": "string"}
+			}
+		}`
+
+		Convey("When it's mapped to a type in options", func() {
+			options = Options{FormatMappers: map[string]FormatMapping{
+				"color":     {GoType: "mypkg.Color", Import: "example.com/lib/v2/mypkg"},
+				"date-time": {GoType: "string"},
+			}}
+			defer func() { options = defaultOptions() }()
+			files := generateSchema(schema)
+
+			Convey("Then the field should use the type and import its package", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "Fg mypkg.Color `json:\"fg,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, `"example.com/lib/v2/mypkg"`)
+			})
+
+			Convey("Then it should take precedence over the built-in formats", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "Created string `json:\"created,omitempty\"`")
+				So(files["root.go"], ShouldNotContainSubstring, `"time"`)
+			})
+		})
+
+		Convey("When it's generated again with a mapping of the package without an import path", func() {
+			first := Options{RootTypeName: "root", FormatMappers: map[string]FormatMapping{
+				"color": {GoType: "mypkg.Color", Import: "example.com/lib/v2/mypkg"},
+			}}
+			_, err := Generate([]byte(schema), first)
+			So(err, ShouldBeNil)
+			second := Options{RootTypeName: "root", FormatMappers: map[string]FormatMapping{
+				"color": {GoType: "mypkg.Color"},
+			}}
+			files, err := Generate([]byte(schema), second)
+			So(err, ShouldBeNil)
+
+			Convey("Then it shouldn't import the package of the earlier mapping", func() {
+				So(string(files["root.go"]), ShouldContainSubstring, "mypkg.Color")
+				So(string(files["root.go"]), ShouldNotContainSubstring, `"example.com/lib/v2/mypkg"`)
+			})
+
+			Convey("Then the options' mappings should be left as they were", func() {
+				So(first.FormatMappers, ShouldHaveLength, 1)
+				So(second.FormatMappers, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
+
+func TestPrimitiveRoot(t *testing.T) {
+	Convey("Given a schema whose root is a string", t, func() {
+		schema := `{
+			"type": "string",
+			"definitions": {
+				"holder": {"type": "object", "properties": {"value": {"$ref": "#"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then the root should be a plain string type", func() {
+				So(printType(types["root"]), ShouldEqual, "type root string\n")
+				So(types["root"].Nullable, ShouldBeFalse)
+			})
+
+			Convey("Then a ref to the root shouldn't be nullable", func() {
+				So(printType(types["Holder"]), ShouldContainSubstring, "Value root `json:\"value,omitempty\"`")
+				So(types["Holder"].Fields[0].Nullable, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a schema whose root is a number", t, func() {
+		schema := `{"type": "number", "description": "A ratio."}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the root should be a plain float64 type", func() {
+				So(files, ShouldHaveLength, 1)
+				So(files["root.go"], ShouldContainSubstring, "// A ratio.\ntype root float64\n")
+				So(types["#"].Nullable, ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given a schema whose root is a nullable string", t, func() {
+		types := processSchema(`{"type": ["string", "null"]}`)
+
+		Convey("Then the root should be nullable", func() {
+			So(printType(types["root"]), ShouldEqual, "type root string\n")
+			So(types["root"].Nullable, ShouldBeTrue)
+		})
+	})
+}
+
+func TestProtoTags(t *testing.T) {
+	Convey("Given a schema with x-proto-field on some properties", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "x-proto-field": 3},
+				"age": {"type": "integer"},
+				"ratio": {"type": "number", "x-proto-field": 1},
+				"scores": {"type": "array", "items": {"type": "integer"}},
+				"labels": {"type": "object", "additionalProperties": {"type": "string"}}
+			}
+		}`
+
+		Convey("When we generate it with --proto-tags", func() {
+			*protoTags = true
+			defer func() { *protoTags = false }()
+			types := processSchema(schema)
+			root := printType(types["root"])
+
+			Convey("Then a field with x-proto-field should have that number", func() {
+				So(root, ShouldContainSubstring, "Name string `json:\"name,omitempty\" protobuf:\"bytes,3,opt,name=name\"`")
+				So(root, ShouldContainSubstring, "Ratio float64 `json:\"ratio,omitempty\" protobuf:\"fixed64,1,opt,name=ratio\"`")
+			})
+
+			Convey("Then the other fields should be numbered in order with the numbers left over", func() {
+				So(root, ShouldContainSubstring, "Age int64 `json:\"age,omitempty\" protobuf:\"varint,2,opt,name=age\"`")
+				So(root, ShouldContainSubstring, `protobuf:"bytes,4,rep,name=labels" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`)
+				So(root, ShouldContainSubstring, `protobuf:"varint,5,rep,packed,name=scores"`)
+			})
+		})
+
+		Convey("When we generate it without --proto-tags", func() {
+			types := processSchema(schema)
+
+			Convey("Then there shouldn't be protobuf tags", func() {
+				So(printType(types["root"]), ShouldNotContainSubstring, "protobuf")
+			})
+		})
+	})
+
+	Convey("Given a schema with two properties with the same x-proto-field", t, func() {
+		var s metaSchema
+		So(json.Unmarshal([]byte(`{"type": "object", "properties": {"a": {"type": "string", "x-proto-field": 1}, "b": {"type": "string", "x-proto-field": 1}}}`), &s), ShouldBeNil)
+
+		Convey("When we process it with --proto-tags", func() {
+			resetState()
+			*protoTags = true
+			defer func() { *protoTags = false }()
+			_, err := processType(&s, "root", "", "#", "")
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "both have x-proto-field 1")
+			})
+		})
+	})
+}
+
+func TestInlineDepth(t *testing.T) {
+	Convey("Given a schema with objects nested three levels deep", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"a": {
+					"type": "object",
+					"properties": {
+						"b": {
+							"type": "object",
+							"properties": {
+								"c": {"type": "object", "properties": {"x": {"type": "string"}}}
+							}
+						}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate it with --inline-depth=2", func() {
+			*inlineDepth = 2
+			defer func() { *inlineDepth = 0 }()
+			files := generateSchema(schema)
+
+			Convey("Then the depth 1 and 2 objects should be anonymous structs", func() {
+				So(files, ShouldNotContainKey, "A.go")
+				So(files, ShouldNotContainKey, "B.go")
+				So(files["root.go"], ShouldContainSubstring, "A struct {")
+				So(files["root.go"], ShouldContainSubstring, "B struct {")
+			})
+
+			Convey("Then the depth 3 object should be named", func() {
+				So(files, ShouldContainKey, "C.go")
+				So(files["root.go"], ShouldContainSubstring, "C C `json:\"c,omitempty\"`")
+			})
+		})
+
+		Convey("When we generate it without --inline-depth", func() {
+			files := generateSchema(schema)
+
+			Convey("Then every object should be named", func() {
+				So(files, ShouldContainKey, "A.go")
+				So(files, ShouldContainKey, "B.go")
+				So(files, ShouldContainKey, "C.go")
+			})
+		})
+	})
+}
+
+func TestBaseURIs(t *testing.T) {
+	Convey("Given a bundled schema with a nested $id", t, func() {
+		schema := `{
+			"$id": "https://example.com/schemas/order.json",
+			"type": "object",
+			"properties": {
+				"shipTo": {"$ref": "urn:example:address"},
+				"billTo": {"$ref": "customer.json#/definitions/address"},
+				"total": {"$ref": "#/definitions/money"}
+			},
+			"definitions": {
+				"money": {"type": "number"},
+				"address": {
+					"$id": "urn:example:address",
+					"type": "object",
+					"properties": {
+						"street": {"type": "string"},
+						"country": {"$ref": "#/definitions/country"}
+					},
+					"definitions": {
+						"country": {"type": "object", "properties": {"code": {"type": "string"}}}
+					}
+				},
+				"customer": {
+					"$id": "customer.json",
+					"definitions": {
+						"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then a relative ref should resolve against the nearest enclosing $id", func() {
+				So(printType(types["Address"]), ShouldContainSubstring, "Country Country `json:\"country,omitempty\"`")
+				So(printType(types["Country"]), ShouldContainSubstring, "Code string")
+			})
+
+			Convey("Then refs to an $id should resolve to the schema with it", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "ShipTo Address `json:\"shipTo,omitempty\"`")
+				So(types["Address"].path, ShouldEqual, "#/definitions/address")
+			})
+
+			Convey("Then refs relative to the root's $id should resolve too", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Total Money `json:\"total,omitempty\"`")
+				So(printType(types["root"]), ShouldContainSubstring, "BillTo CustomerAddress `json:\"billTo,omitempty\"`")
+				So(types["CustomerAddress"].path, ShouldEqual, "#/definitions/customer/definitions/address")
+			})
+		})
+	})
+
+	Convey("Given a draft-04 schema with nested relative ids", t, func() {
+		schema := `{
+			"$schema": "http://json-schema.org/draft-04/schema#",
+			"id": "http://example.com/root.json",
+			"type": "object",
+			"properties": {
+				"line": {"$ref": "http://example.com/orders/line.json"}
+			},
+			"definitions": {
+				"orders": {
+					"id": "orders/",
+					"definitions": {
+						"line": {
+							"id": "line.json",
+							"type": "object",
+							"properties": {
+								"sku": {"$ref": "sku.json"},
+								"qty": {"$ref": "#/definitions/qty"}
+							},
+							"definitions": {
+								"qty": {"type": "integer"}
+							}
+						},
+						"sku": {"id": "sku.json", "type": "object", "properties": {"code": {"type": "string"}}}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then each id should resolve against the one around it", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Line Line `json:\"line,omitempty\"`")
+				So(types["Line"].path, ShouldEqual, "#/definitions/orders/definitions/line")
+				So(printType(types["Line"]), ShouldContainSubstring, "Sku Sku `json:\"sku,omitempty\"`")
+				So(types["Sku"].path, ShouldEqual, "#/definitions/orders/definitions/sku")
+			})
+
+			Convey("Then a fragment ref should point into the schema with the nearest id", func() {
+				So(printType(types["Line"]), ShouldContainSubstring, "Qty Qty `json:\"qty,omitempty\"`")
+				So(types["Qty"].path, ShouldEqual, "#/definitions/orders/definitions/line/definitions/qty")
+			})
+		})
+	})
+
+	Convey("Given a schema with a property named id", t, func() {
+		var doc interface{}
+		So(json.Unmarshal([]byte(`{"properties": {"id": {"type": "string"}, "user": {"$ref": "#/definitions/user"}}, "definitions": {"user": {"type": "object"}}}`), &doc), ShouldBeNil)
+
+		Convey("When we resolve its refs", func() {
+			resolveBaseURIs(doc)
+
+			Convey("Then the property shouldn't be taken for an $id", func() {
+				props := doc.(map[string]interface{})["properties"].(map[string]interface{})
+				So(props["user"].(map[string]interface{})["$ref"], ShouldEqual, "#/definitions/user")
+			})
+		})
+	})
+}
+
+func TestParse(t *testing.T) {
+	Convey("Given a sample schema", t, func() {
+		schema := `{
+			"type": "object",
+			"description": "An order.",
+			"required": ["id"],
+			"properties": {
+				"id": {"type": "integer", "readOnly": true},
+				"customer": {"$ref": "#/definitions/customer"},
+				"lines": {"type": "array", "items": {"$ref": "#/definitions/line"}},
+				"status": {"type": "string", "enum": ["open", "closed"]}
+			},
+			"definitions": {
+				"customer": {"type": "object", "properties": {"email": {"type": "string"}}},
+				"line": {"type": "object", "properties": {"sku": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we parse it with the root type named Order", func() {
+			parsed, err := Parse([]byte(schema), Options{RootTypeName: "Order"})
+			So(err, ShouldBeNil)
+
+			Convey("Then it should return each type in order", func() {
+				var names []string
+				for _, gt := range parsed {
+					names = append(names, gt.Name)
+				}
+				So(names, ShouldResemble, []string{"Customer", "Line", "Order", "Status"})
+			})
+
+			Convey("Then the types should have the shapes they're generated with", func() {
+				root := parsed[2]
+				So(root.Type, ShouldEqual, "struct")
+				So(root.Comment, ShouldEqual, "An order.")
+				So(root.Fields, ShouldResemble, []StructField{
+					{Name: "Customer", PropertyName: "customer", JSONName: "customer", Type: "Customer"},
+					{Name: "ID", PropertyName: "id", JSONName: "id", Type: "int64", Required: true, ReadOnly: true},
+					{Name: "Lines", PropertyName: "lines", JSONName: "lines", Type: "[]*Line", Nullable: true},
+					{Name: "Status", PropertyName: "status", JSONName: "status", Type: "Status", Nullable: true},
+				})
+				So(parsed[3].Type, ShouldEqual, "string")
+				So(parsed[3].EnumValues, ShouldResemble, []string{"open", "closed"})
+			})
+		})
+
+		Convey("When we parse it again after parsing another schema with other options", func() {
+			_, err := Parse([]byte(`{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": "string"}}}}`),
+				Options{RootTypeName: "Tagged", Prefix: "X", PrefixRoot: true})
+			So(err, ShouldBeNil)
+			parsed, err := Parse([]byte(schema), Options{RootTypeName: "Order"})
+			So(err, ShouldBeNil)
+
+			Convey("Then it should return only its own types, without the other options", func() {
+				var names []string
+				for _, gt := range parsed {
+					names = append(names, gt.Name)
+				}
+				So(names, ShouldResemble, []string{"Customer", "Line", "Order", "Status"})
+			})
+		})
+	})
+}
+
+func TestValidatorTags(t *testing.T) {
+	Convey("Given a schema with constraints", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"email": {"type": "string", "format": "email"},
+				"name": {"type": "string", "minLength": 1, "maxLength": 50},
+				"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+				"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 5},
+				"color": {"type": "string", "enum": ["red", "green"]},
+				"active": {"type": "boolean"},
+				"note": {"type": "string"}
+			},
+			"required": ["email", "age", "active"]
+		}`
+
+		Convey("When we generate it with --validator-tags", func() {
+			*validatorTags = true
+			defer func() { *validatorTags = false }()
+			types := processSchema(schema)
+			root := printType(types["root"])
+
+			Convey("Then a required email field should be required and an email", func() {
+				So(root, ShouldContainSubstring, "Email string `json:\"email\" validate:\"required,email\"`")
+			})
+
+			Convey("Then optional fields should skip their rules when empty", func() {
+				So(root, ShouldContainSubstring, "Name string `json:\"name,omitempty\" validate:\"omitempty,min=1,max=50\"`")
+				So(root, ShouldContainSubstring, "Tags []*Tag `json:\"tags,omitempty\" validate:\"omitempty,max=5\"`")
+				So(root, ShouldContainSubstring, "Color Color `json:\"color,omitempty\" validate:\"omitempty,oneof=red green\"`")
+			})
+
+			Convey("Then required numbers and booleans shouldn't be required to be non-zero", func() {
+				So(root, ShouldContainSubstring, "Age int64 `json:\"age\" validate:\"gte=0,lt=150\"`")
+				So(root, ShouldContainSubstring, "Active bool `json:\"active\"`")
+			})
+
+			Convey("Then fields without constraints shouldn't get a tag", func() {
+				So(root, ShouldContainSubstring, "Note string `json:\"note,omitempty\"`")
+			})
+		})
+
+		Convey("When we generate it without --validator-tags", func() {
+			types := processSchema(schema)
+
+			Convey("Then there shouldn't be validate tags", func() {
+				So(printType(types["root"]), ShouldNotContainSubstring, "validate:")
+			})
+		})
+	})
+}
+
+func TestInfer(t *testing.T) {
+	Convey("Given two sample objects, one per line", t, func() {
+		samples := `{"id": 1, "name": "a", "nickname": "x", "score": 1, "tags": ["t"]}
+{"id": 2, "name": "b", "nickname": null, "score": 2.5, "extra": true}
+`
+
+		Convey("When we infer a schema from them", func() {
+			s, err := inferSchema([]byte(samples))
+			So(err, ShouldBeNil)
+			schemaJSON, err := json.Marshal(s)
+			So(err, ShouldBeNil)
+			types := processSchema(string(schemaJSON))
+			root := printType(types["root"])
+
+			Convey("Then fields in every sample should be required", func() {
+				So(root, ShouldContainSubstring, "ID int64 `json:\"id\"`")
+				So(root, ShouldContainSubstring, "Name string `json:\"name\"`")
+			})
+
+			Convey("Then null and missing fields should be optional", func() {
+				So(s.Properties["nickname"].Type, ShouldResemble, []interface{}{"string", "null"})
+				So(root, ShouldContainSubstring, "Nickname string `json:\"nickname,omitempty\"`")
+				So(root, ShouldContainSubstring, "Extra bool `json:\"extra,omitempty\"`")
+				So(root, ShouldContainSubstring, "Tags []*Tag `json:\"tags,omitempty\"`")
+			})
+
+			Convey("Then integers and numbers together should be numbers", func() {
+				So(root, ShouldContainSubstring, "Score float64 `json:\"score\"`")
+			})
+		})
+	})
+
+	Convey("Given an array of samples with conflicting types and repeated strings", t, func() {
+		samples := `[
+			{"value": 1, "status": "on"},
+			{"value": "one", "status": "off"},
+			{"value": true, "status": "on"},
+			{"value": null, "status": "off"}
+		]`
+
+		Convey("When we infer a schema from them", func() {
+			s, err := inferSchema([]byte(samples))
+			So(err, ShouldBeNil)
+
+			Convey("Then the conflicting types should have no type", func() {
+				So(s.Properties["value"].Type, ShouldBeNil)
+			})
+
+			Convey("Then the repeated strings should be an enum", func() {
+				So(s.Properties["status"].Enum, ShouldResemble, []interface{}{"off", "on"})
+			})
+		})
+	})
+}
+
+func TestSingleTypeArrays(t *testing.T) {
+	Convey("Given a schema with types given as arrays of one type", t, func() {
+		schema := `{
+			"type": ["object"],
+			"properties": {
+				"count": {"type": ["integer"]},
+				"names": {"type": ["array"], "items": {"type": ["string"]}}
+			},
+			"definitions": {
+				"id": {"type": ["integer"]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then they should be the same as the types by themselves", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "type root struct {")
+				So(printType(types["root"]), ShouldContainSubstring, "Count int64 `json:\"count,omitempty\"`")
+				So(printType(types["root"]), ShouldContainSubstring, "Names []*Name `json:\"names,omitempty\"`")
+				So(printType(types["Name"]), ShouldContainSubstring, "type Name string")
+				So(printType(types["ID"]), ShouldContainSubstring, "type ID int64")
+			})
+		})
+	})
+}
+
+func TestEqual(t *testing.T) {
+	Convey("Given a schema with nested objects, arrays and maps", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"address": {"$ref": "#/definitions/address"},
+				"history": {"type": "array", "items": {"$ref": "#/definitions/address"}},
+				"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+				"extra": {}
+			},
+			"definitions": {
+				"address": {"type": "object", "properties": {"street": {"type": "string"}, "zip": {"type": "integer"}}},
+				"route": {"type": "array", "items": {"$ref": "#/definitions/address"}}
+			}
+		}`
+		*equalMethods = true
+		defer func() { *equalMethods = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then structs and slice types should have an Equal method", func() {
+				So(files["root.go"], ShouldContainSubstring, "func (t root) Equal(o root) bool")
+				So(files["Address.go"], ShouldContainSubstring, "func (t Address) Equal(o Address) bool")
+				So(files["Route.go"], ShouldContainSubstring, "func (t Route) Equal(o Route) bool")
+				So(files["Label.go"], ShouldNotContainSubstring, "Equal")
+			})
+
+			Convey("Then Equal should compare nested values", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	newRoot := func() root {
+		tag := Label("b")
+		return root{
+			Name:    "x",
+			Address: Address{Street: "Main", Zip: 1},
+			History: []*Address{{Street: "Old"}, nil},
+			Labels:  map[string]Label{"a": tag},
+			Extra:   []interface{}{1.0, "two"},
+		}
+	}
+	a, b := newRoot(), newRoot()
+	fmt.Println(a.Equal(b))
+
+	b.Address.Zip = 2
+	fmt.Println(a.Equal(b))
+
+	b = newRoot()
+	b.History[0].Street = "New"
+	fmt.Println(a.Equal(b))
+
+	b = newRoot()
+	b.History[1] = &Address{}
+	fmt.Println(a.Equal(b))
+
+	b = newRoot()
+	b.Labels["a"] = "c"
+	fmt.Println(a.Equal(b))
+
+	fmt.Println(Route{{Zip: 1}}.Equal(Route{{Zip: 1}}), Route{{Zip: 1}}.Equal(Route{}))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "true\nfalse\nfalse\nfalse\nfalse\ntrue false\n")
+			})
+		})
+	})
+}
+
+func TestNullTypeSQL(t *testing.T) {
+	Convey("Given a schema with nullable and non-nullable scalars", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"count": {"type": ["integer", "null"]},
+				"name": {"type": ["null", "string"]},
+				"seen": {"type": ["string", "null"], "format": "date-time"},
+				"total": {"type": "integer"}
+			}
+		}`
+		*nullType = nullTypeSQL
+		*ptrForOmit = true
+		defer func() {
+			*nullType = nullTypeNone
+			*ptrForOmit = false
+		}()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the nullable scalars should have wrappers of the database/sql types", func() {
+				So(files["root.go"], ShouldContainSubstring, "Count nullInt64  `json:\"count,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, "Name  nullString `json:\"name,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, "Seen  nullTime   `json:\"seen,omitempty\"`")
+				So(files["root.go"], ShouldNotContainSubstring, "\"time\"")
+				So(files["nullInt64.go"], ShouldContainSubstring, "type nullInt64 struct {\n\tsql.NullInt64\n}")
+				So(files, ShouldNotContainKey, "nullFloat64.go")
+			})
+
+			Convey("Then the other scalars shouldn't change", func() {
+				So(files["root.go"], ShouldContainSubstring, "Total *int64     `json:\"total,omitempty\"`")
+			})
+
+			Convey("Then the types should round-trip JSON and work with database/sql", func() {
+				program := `package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	if err := json.Unmarshal([]byte(` + "`" + `{"count": 3, "name": null, "seen": "2024-01-02T03:04:05Z"}` + "`" + `), &r); err != nil {
+		panic(err)
+	}
+	fmt.Println(r.Count.Int64, r.Count.Valid, r.Name.Valid, r.Seen.Time.Year())
+
+	out, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+
+	var scanned root
+	if err := scanned.Count.Scan(int64(7)); err != nil {
+		panic(err)
+	}
+	var valuer driver.Valuer = scanned.Count
+	value, _ := valuer.Value()
+	fmt.Println(value, scanned.Count.NullInt64 == sql.NullInt64{Int64: 7, Valid: true})
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "3 true false 2024\n"+
+					`{"count":3,"name":null,"seen":"2024-01-02T03:04:05Z"}`+"\n"+
+					"7 true\n")
+			})
+		})
+	})
+}
+
+func TestAllOfRequired(t *testing.T) {
+	Convey("Given allOf schemas that require each other's properties", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["age"],
+			"properties": {"id": {"type": "integer"}},
+			"allOf": [
+				{
+					"type": "object",
+					"properties": {"name": {"type": "string"}, "age": {"type": "integer"}, "nick": {"type": "string"}},
+					"allOf": [{"type": "object", "properties": {"email": {"type": "string"}}}]
+				},
+				{"required": ["name", "id", "email"]},
+				{"$ref": "#/definitions/base"}
+			],
+			"definitions": {
+				"base": {"type": "object", "properties": {"created": {"type": "string"}}, "required": []}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then a property required by another allOf schema should be required", func() {
+				So(files["RootEmbedded0.go"], ShouldContainSubstring, "Name string `json:\"name\"`")
+				So(files["RootEmbedded0Embedded0.go"], ShouldContainSubstring, "Email string `json:\"email\"`")
+			})
+
+			Convey("Then a property required by the parent should be required", func() {
+				So(files["RootEmbedded0.go"], ShouldContainSubstring, "Age  int64  `json:\"age\"`")
+			})
+
+			Convey("Then a property of the parent required by an allOf schema should be required", func() {
+				So(files["root.go"], ShouldContainSubstring, "ID int64 `json:\"id\"`")
+			})
+
+			Convey("Then other properties should stay optional", func() {
+				So(files["RootEmbedded0.go"], ShouldContainSubstring, "Nick string `json:\"nick,omitempty\"`")
+				So(files["Base.go"], ShouldContainSubstring, "Created string `json:\"created,omitempty\"`")
+			})
+		})
+	})
+}
+
+func TestMapValueNames(t *testing.T) {
+	Convey("Given maps of structured objects", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"users": {
+					"type": "object",
+					"additionalProperties": {
+						"type": "object",
+						"properties": {"name": {"type": "string"}, "address": {"$ref": "#/definitions/address"}}
+					}
+				},
+				"user": {"type": "object", "properties": {"id": {"type": "integer"}}},
+				"metadata": {"type": "object", "additionalProperties": {"type": "object", "properties": {"v": {"type": "string"}}}},
+				"status": {"type": "object", "additionalProperties": {"type": "object", "properties": {"v": {"type": "string"}}}},
+				"byID": {"type": "object", "additionalProperties": {"type": "object", "properties": {"v": {"type": "string"}}}},
+				"prices": {"type": "object", "additionalProperties": {"type": "object", "properties": {"amount": {"type": "number"}}}}
+			},
+			"definitions": {
+				"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then plural names should be singularized", func() {
+				So(files["root.go"], ShouldContainSubstring, "Prices   map[string]Price ")
+			})
+
+			Convey("Then other names should get Value appended", func() {
+				So(files["root.go"], ShouldContainSubstring, "Metadata map[string]MetadataValue ")
+				So(files["root.go"], ShouldContainSubstring, "Status   map[string]StatusValue ")
+				So(files["root.go"], ShouldContainSubstring, "ByID     map[string]ByIDValue ")
+			})
+
+			Convey("Then a name taken by a sibling property shouldn't collide", func() {
+				So(files["root.go"], ShouldContainSubstring, "User     User ")
+				So(files["root.go"], ShouldContainSubstring, "Users    map[string]UsersValue ")
+				So(files["UsersValue.go"], ShouldContainSubstring, "type UsersValue struct")
+			})
+
+			Convey("Then refs in the values should be resolved", func() {
+				So(files["UsersValue.go"], ShouldContainSubstring, "Address Address")
+			})
+		})
+	})
+
+	Convey("Given map names", t, func() {
+		Convey("Then the value names should be derived from them", func() {
+			So(mapValueName("line_items", stringset.New()), ShouldEqual, "line item")
+			So(mapValueName("address", stringset.New()), ShouldEqual, "address value")
+			So(mapValueName("analysis", stringset.New()), ShouldEqual, "analysis value")
+			So(mapValueName("users", stringset.New("User")), ShouldEqual, "users value")
+		})
+	})
+}
+
+func TestMerge(t *testing.T) {
+	Convey("Given a schema with nested and optional fields", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"age": {"type": "integer"},
+				"active": {"type": "boolean"},
+				"nickname": {"type": ["string", "null"]},
+				"address": {"$ref": "#/definitions/address"},
+				"billing": {"$ref": "#/definitions/address"},
+				"tags": {"type": "array", "items": {"type": "string"}}
+			},
+			"definitions": {
+				"address": {"type": "object", "properties": {"street": {"type": "string"}, "city": {"type": "string"}}}
+			}
+		}`
+		*merge = true
+		*ptrForOmit = true
+		defer func() {
+			*merge = false
+			*ptrForOmit = false
+		}()
+		program := `package main
+
+import "fmt"
+
+func main() {
+	name, nickname, street, city := "Alice", "Al", "Main", "Springfield"
+	age := int64(30)
+	base := root{
+		Name:     &name,
+		Age:      &age,
+		Nickname: &nickname,
+		Address:  &Address{Street: &street},
+		Tags:     []*Tag{},
+	}
+	partial := root{
+		Address: &Address{City: &city},
+		Billing: &Address{City: &city},
+	}
+	base.Merge(partial)
+	fmt.Println(*base.Name, *base.Age, *base.Nickname, *base.Address.Street, *base.Address.City, *base.Billing.City, base.Tags != nil)
+
+	fmt.Println(base.Billing == partial.Billing)
+}
+`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then structs should have a Merge method", func() {
+				So(files["root.go"], ShouldContainSubstring, "func (t *root) Merge(o root) {")
+				So(files["Address.go"], ShouldContainSubstring, "func (t *Address) Merge(o Address) {")
+				So(files["root.go"], ShouldContainSubstring, "if o.Name != nil {\n\t\tt.Name = o.Name\n\t}")
+				So(files["root.go"], ShouldContainSubstring, "t.Address.Merge(*o.Address)")
+			})
+
+			Convey("Then merging a partial value should only update the fields it sets", func() {
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "Alice 30 Al Main Springfield Springfield true\nfalse\n")
+			})
+		})
+	})
+
+	Convey("Given a schema with value fields", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"count": {"type": "integer"},
+				"created": {"type": "string", "format": "date-time"},
+				"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+			}
+		}`
+		*merge = true
+		defer func() { *merge = false }()
+		program := `package main
+
+import "fmt"
+
+func main() {
+	base := root{Name: "a", Count: 2, Address: Address{City: "x"}}
+	base.Merge(root{Count: 3})
+	fmt.Println(base.Name, base.Count, base.Address.City)
+}
+`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then zero values shouldn't overwrite set ones", func() {
+				So(files["root.go"], ShouldContainSubstring, "if o.Count != 0 {")
+				So(files["root.go"], ShouldContainSubstring, "if !o.Created.IsZero() {")
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "a 3 x\n")
+			})
+		})
+
+		Convey("When we generate the types with --merge-values=always", func() {
+			*mergeValues = mergeValuesAlways
+			defer func() { *mergeValues = mergeValuesNonZero }()
+			files := generateSchema(schema)
+
+			Convey("Then value fields should always be copied", func() {
+				So(files["root.go"], ShouldContainSubstring, "t.Count = o.Count\n")
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, " 3 \n")
+			})
+		})
+	})
+
+	Convey("Given a schema with a oneOf union", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"pet": {"oneOf": [{"$ref": "#/definitions/cat"}, {"$ref": "#/definitions/dog"}]}
+			},
+			"required": ["pet"],
+			"definitions": {
+				"cat": {"type": "object", "properties": {"kind": {"const": "cat"}, "lives": {"type": "integer"}}, "required": ["kind"]},
+				"dog": {"type": "object", "properties": {"kind": {"const": "dog"}, "good": {"type": "boolean"}}, "required": ["kind"]}
+			}
+		}`
+		*merge = true
+		defer func() { *merge = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then merging another variant should replace the one held", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	json.Unmarshal([]byte(` + "`" + `{"pet": {"kind": "cat", "lives": 9}}` + "`" + `), &r)
+	var o root
+	json.Unmarshal([]byte(` + "`" + `{"pet": {"kind": "dog", "good": true}}` + "`" + `), &o)
+	r.Merge(o)
+	out, _ := json.Marshal(r)
+	fmt.Println(r.Pet.Cat == nil, string(out))
+
+	r.Merge(root{Pet: Pet{Dog: &Dog{Kind: "dog"}}})
+	fmt.Println(r.Pet.Dog.Good)
+	r.Merge(root{})
+	fmt.Println(r.Pet.Dog != nil)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "true {\"pet\":{\"good\":true,\"kind\":\"dog\"}}\ntrue\ntrue\n")
+			})
+		})
+	})
+}
+
+func TestTagCase(t *testing.T) {
+	Convey("Given property names in different cases", t, func() {
+		Convey("Then lowerCamel should convert them to lowerCamelCase", func() {
+			So(lowerCamel("user_name"), ShouldEqual, "userName")
+			So(lowerCamel("UserName"), ShouldEqual, "userName")
+			So(lowerCamel("user-name"), ShouldEqual, "userName")
+			So(lowerCamel("userName"), ShouldEqual, "userName")
+		})
+
+		Convey("Then lowerCamel should treat acronyms as words", func() {
+			So(lowerCamel("URL"), ShouldEqual, "url")
+			So(lowerCamel("userID"), ShouldEqual, "userId")
+			So(lowerCamel("TLSVersion"), ShouldEqual, "tlsVersion")
+			So(lowerCamel("api_URL"), ShouldEqual, "apiUrl")
+		})
+	})
+
+	Convey("Given a schema with snake_case properties", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"user_name": {"type": "string"},
+				"URL": {"type": "string"},
+				"age": {"type": "integer"}
+			}
+		}`
+
+		Convey("When we generate it with --tag-case=camel", func() {
+			*tagCase = tagCaseCamel
+			defer func() { *tagCase = tagCaseSchema }()
+			types := processSchema(schema)
+			out := printType(types["root"])
+
+			Convey("Then the JSON tags should have lowerCamelCase keys, without other tags", func() {
+				So(out, ShouldContainSubstring, "UserName string `json:\"userName,omitempty\"`")
+				So(out, ShouldContainSubstring, "URL string `json:\"url,omitempty\"`")
+			})
+
+			Convey("Then names that are already lowerCamelCase should be left alone", func() {
+				So(out, ShouldContainSubstring, "Age int64 `json:\"age,omitempty\"`")
+			})
+		})
+
+		Convey("When we generate it with the default tag case", func() {
+			types := processSchema(schema)
+
+			Convey("Then the tags should have the schema's names", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "UserName string `json:\"user_name,omitempty\"`")
+			})
+		})
+	})
+
+	Convey("Given properties that are the same in lowerCamelCase", t, func() {
+		var s metaSchema
+		So(json.Unmarshal([]byte(`{"type": "object", "properties": {"user_id": {"type": "string"}, "userId": {"type": "string"}}}`), &s), ShouldBeNil)
+
+		Convey("When we process it with --tag-case=camel", func() {
+			resetState()
+			*tagCase = tagCaseCamel
+			defer func() { *tagCase = tagCaseSchema }()
+			_, err := processType(&s, "root", "", "#", "")
+
+			Convey("Then it should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, `the JSON key "userId"`)
+			})
+		})
+	})
+}
+
+func TestFixedArrays(t *testing.T) {
+	Convey("Given a schema with arrays of fixed and unbounded length", t, func() {
+		schema := `{
+			"type": "object",
+			"required": ["rgb", "tags", "huge", "points"],
+			"properties": {
+				"rgb": {"type": "array", "items": {"type": "integer"}, "minItems": 3, "maxItems": 3},
+				"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+				"huge": {"type": "array", "items": {"type": "number"}, "minItems": 1000, "maxItems": 1000},
+				"points": {
+					"type": "array",
+					"items": {"type": "object", "properties": {"x": {"type": "integer"}}},
+					"minItems": 2,
+					"maxItems": 2
+				},
+				"pair": {"type": "array", "items": {"type": "string"}, "minItems": 2, "maxItems": 2}
+			}
+		}`
+
+		Convey("When we generate it without --fixed-arrays", func() {
+			types := processSchema(schema)
+
+			Convey("Then the fixed length array should be a slice", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Rgb []*RgbItem `json:\"rgb\"`")
+			})
+		})
+
+		Convey("When we generate it with --fixed-arrays", func() {
+			*fixedArrays = true
+			defer func() { *fixedArrays = false }()
+			types := processSchema(schema)
+			out := printType(types["root"])
+
+			Convey("Then the required fixed length arrays should be Go arrays of values", func() {
+				So(out, ShouldContainSubstring, "Rgb [3]RgbItem `json:\"rgb\"`")
+				So(out, ShouldContainSubstring, "Points [2]Point ")
+			})
+
+			Convey("Then the other arrays should still be slices", func() {
+				So(out, ShouldContainSubstring, "Tags []*Tag ")
+				So(out, ShouldContainSubstring, "Huge []*HugeItem ")
+				So(out, ShouldContainSubstring, "Pair []*PairItem ")
+			})
+
+			Convey("Then an absent optional array should be left out when marshaled", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	if err := json.Unmarshal([]byte(` + "`" + `{"rgb": [1, 2, 3], "tags": ["a"], "huge": [], "points": [{"x": 1}, {"x": 2}]}` + "`" + `), &r); err != nil {
+		panic(err)
+	}
+	out, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+}
+`
+				files, err := renderTypes()
+				So(err, ShouldBeNil)
+				generated := make(map[string]string)
+				for name, src := range files {
+					generated[name] = string(src)
+				}
+				out, err := runGenerated(generated, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `{"huge":[],"points":[{"x":1},{"x":2}],"rgb":[1,2,3],"tags":["a"]}`+"\n")
+			})
+		})
+	})
+}
+
+func TestNestedNames(t *testing.T) {
+	Convey("Given a schema with three levels of inline objects", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"a": {
+					"type": "object",
+					"properties": {
+						"b": {
+							"type": "object",
+							"properties": {
+								"c": {"type": "object", "properties": {"x": {"type": "string"}}},
+								"tags": {"type": "array", "items": {"type": "object", "properties": {"y": {"type": "string"}}}}
+							}
+						}
+					}
+				}
+			},
+			"definitions": {
+				"user": {
+					"type": "object",
+					"properties": {
+						"address": {"type": "object", "properties": {"street": {"type": "string"}}}
+					}
+				}
+			}
+		}`
+
+		Convey("When we generate it with the default naming", func() {
+			types := processSchema(schema)
+
+			Convey("Then the types should be named after their properties", func() {
+				So(types, ShouldContainKey, "A")
+				So(types, ShouldContainKey, "B")
+				So(types, ShouldContainKey, "C")
+				So(types, ShouldContainKey, "Tag")
+			})
+		})
+
+		Convey("When we generate it with --nested-names=path", func() {
+			*nestedNames = nestedNamesPath
+			defer func() { *nestedNames = nestedNamesLeaf }()
+			types := processSchema(schema)
+
+			Convey("Then the types should be named after the path to them", func() {
+				So(types, ShouldContainKey, "A")
+				So(types, ShouldContainKey, "AB")
+				So(types, ShouldContainKey, "ABC")
+				So(types, ShouldContainKey, "ABTag")
+				So(types["ABC"].Fields[0].Name, ShouldEqual, "X")
+			})
+
+			Convey("Then the names should start again at definitions", func() {
+				So(types, ShouldContainKey, "User")
+				So(types, ShouldContainKey, "UserAddress")
+			})
+		})
+	})
+}
+
+func TestNameAllocation(t *testing.T) {
+	Convey("Given a schema whose definitions and properties want the same names", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"user": {"type": "object", "properties": {"id": {"type": "integer"}}},
+				"owner": {"$ref": "#/definitions/user"},
+				"account": {
+					"type": "object",
+					"properties": {
+						"user": {"type": "object", "properties": {"login": {"type": "string"}}}
+					}
+				}
+			},
+			"definitions": {
+				"user": {"type": "object", "properties": {"name": {"type": "string"}}},
+				"User": {"type": "object", "properties": {"email": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then the definitions should be named first, in order of their paths", func() {
+				So(types["User"].path, ShouldEqual, "#/definitions/User")
+				So(types["RootUser"].path, ShouldEqual, "#/definitions/user")
+				So(printType(types["root"]), ShouldContainSubstring, "Owner RootUser `json:\"owner,omitempty\"`")
+			})
+
+			Convey("Then the inline types should be named after their parents, or numbered", func() {
+				So(types["AccountUser"].path, ShouldEqual, "#/properties/account/properties/user")
+				So(types["RootUser2"].path, ShouldEqual, "#/properties/user")
+			})
+
+			Convey("Then the names should be the same every time", func() {
+				for i := 0; i < 10; i++ {
+					again := processSchema(schema)
+					for name, gt := range types {
+						So(again[name].path, ShouldEqual, gt.path)
+					}
+				}
+			})
+		})
+	})
+}
+
+func TestEmitGoGenerate(t *testing.T) {
+	Convey("Given a schema generated with --emit-go-generate", t, func() {
+		*emitGoGenerate = true
+		options.Args = []string{"--package", "p", "--prefix", "A$B", "my schema.json"}
+		defer func() {
+			*emitGoGenerate = false
+			options.Args = nil
+		}()
+
+		files := generateSchema(`{
+			"type": "object",
+			"properties": {"item": {"type": "object", "properties": {"id": {"type": "integer"}}}}
+		}`)
+
+		Convey("Then the root type's file should have the directive after the package clause", func() {
+			src := files["root.go"]
+			So(src, ShouldContainSubstring, "\n//go:generate schematyper --package p --prefix A$DOLLARB \"my schema.json\"\n")
+			So(strings.Index(src, "package "), ShouldBeLessThan, strings.Index(src, "//go:generate"))
+
+			f, err := parser.ParseFile(token.NewFileSet(), "root.go", src, parser.ParseComments)
+			So(err, ShouldBeNil)
+			var directives int
+			for _, group := range f.Comments {
+				for _, c := range group.List {
+					if strings.HasPrefix(c.Text, "//go:generate ") {
+						directives++
+					}
+				}
+			}
+			So(directives, ShouldEqual, 1)
+		})
+
+		Convey("Then the other files shouldn't have it", func() {
+			So(files, ShouldContainKey, "Item.go")
+			So(files["Item.go"], ShouldNotContainSubstring, "//go:generate")
+		})
+	})
+}
+
+func TestOneOfUnions(t *testing.T) {
+	Convey("Given a schema with oneOf unions", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"pet": {"$ref": "#/definitions/pet"},
+				"id": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+				"shape": {
+					"oneOf": [
+						{"title": "circle", "type": "object", "properties": {"radius": {"type": "number"}}, "required": ["radius"]},
+						{"title": "square", "type": "object", "properties": {"side": {"type": "number"}}, "required": ["side"]},
+						{"type": "null"}
+					]
+				}
+			},
+			"required": ["pet", "id"],
+			"definitions": {
+				"pet": {"oneOf": [{"$ref": "#/definitions/cat"}, {"$ref": "#/definitions/dog"}]},
+				"cat": {"type": "object", "properties": {"kind": {"const": "cat"}, "lives": {"type": "integer"}}, "required": ["kind"]},
+				"dog": {"type": "object", "properties": {"kind": {"type": "string", "enum": ["dog"]}, "good": {"type": "boolean"}}, "required": ["kind"]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then each union should be a struct with a pointer field for each variant", func() {
+				So(files["Pet.go"], ShouldContainSubstring, "Cat *Cat `json:\"-\"`")
+				So(files["Pet.go"], ShouldContainSubstring, "Dog *Dog `json:\"-\"`")
+				So(files["ID.go"], ShouldContainSubstring, "Integer *int64  `json:\"-\"`")
+				So(files["ID.go"], ShouldContainSubstring, "String  *string `json:\"-\"`")
+				So(files["Shape.go"], ShouldContainSubstring, "Circle *ShapeCircle `json:\"-\"`")
+				So(files["Shape.go"], ShouldContainSubstring, "Square *ShapeSquare `json:\"-\"`")
+			})
+
+			Convey("Then an optional union should be a pointer, so it's omitted when it's absent", func() {
+				So(files["root.go"], ShouldContainSubstring, "Shape *Shape `json:\"shape,omitempty\"`")
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	json.Unmarshal([]byte(` + "`" + `{"pet": {"kind": "cat"}, "id": 1}` + "`" + `), &r)
+	out, _ := json.Marshal(r)
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `{"id":1,"pet":{"kind":"cat"}}`+"\n")
+			})
+
+			Convey("Then a union whose variants have a const property should switch on it", func() {
+				So(files["Pet.go"], ShouldContainSubstring, "Value *string `json:\"kind\"`")
+			})
+
+			Convey("Then the values should be unmarshaled into the variant they match", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	for _, in := range []string{
+		` + "`" + `{"pet": {"kind": "dog", "good": true}, "id": 7, "shape": {"side": 2}}` + "`" + `,
+		` + "`" + `{"pet": {"kind": "cat", "lives": 9}, "id": "x", "shape": null}` + "`" + `,
+		` + "`" + `{"pet": {"kind": "cow"}}` + "`" + `,
+		` + "`" + `{"id": true}` + "`" + `,
+	} {
+		var r root
+		err := json.Unmarshal([]byte(in), &r)
+		fmt.Println(err, r.Pet.Cat != nil, r.Pet.Dog != nil, r.ID.Integer != nil, r.ID.String != nil, r.Shape != nil && r.Shape.Square != nil)
+		if err == nil {
+			out, _ := json.Marshal(r.Pet)
+			fmt.Println(string(out))
+		}
+	}
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil> false true true false true\n"+
+					"{\"good\":true,\"kind\":\"dog\"}\n"+
+					"<nil> true false false true false\n"+
+					"{\"kind\":\"cat\",\"lives\":9}\n"+
+					"unknown kind \"cow\" false false false false false\n"+
+					"value matches none of the oneOf schemas false false false false false\n")
+			})
+		})
+	})
+}
+
+func TestNullableUnions(t *testing.T) {
+	Convey("Given unions of a schema and null", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"tls-model": {"anyOf": [{"$ref": "#/definitions/TLSModel"}, {"type": "null"}]},
+				"owner": {"oneOf": [{"type": "null"}, {"$ref": "#/definitions/person"}]},
+				"nickname": {"anyOf": [{"type": "string", "maxLength": 8}, {"type": "null"}], "description": "A short name."}
+			},
+			"definitions": {
+				"TLSModel": {"type": "string", "enum": ["local-exec", "initial-exec"]},
+				"person": {"type": "object", "properties": {"name": {"type": "string"}}},
+				"maybePerson": {"anyOf": [{"$ref": "#/definitions/person"}, {"type": "null"}]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+			files := generateSchema(schema)
+
+			Convey("Then they should be the other schema's type, nullable, without a wrapper", func() {
+				fileNames, _ := stringset.FromMapKeys(files)
+				So(fileNames.Sorted(), ShouldResemble, []string{"Person.go", "Tlsmodel.go", "root.go"})
+				So(files["root.go"], ShouldContainSubstring, "TLSModel Tlsmodel `json:\"tls-model,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, "Owner    Person   `json:\"owner,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, "Nickname string   `json:\"nickname,omitempty\"`")
+				for _, sf := range types["root"].Fields {
+					So(sf.Nullable, ShouldBeTrue)
+				}
+			})
+
+			Convey("Then a definition of one should refer to the other schema's type", func() {
+				So(types, ShouldNotContainKey, "#/definitions/maybePerson")
+			})
+		})
+	})
+}
+
+func TestAnyOfUnions(t *testing.T) {
+	Convey("Given a schema with an anyOf union", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"amount": {"anyOf": [{"type": "integer"}, {"type": "number"}, {"$ref": "#/definitions/money"}]}
+			},
+			"definitions": {
+				"money": {"type": "object", "properties": {"value": {"type": "number"}, "currency": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then it should be a struct with a pointer field for each variant", func() {
+				So(files["Amount.go"], ShouldContainSubstring, "Integer *int64   `json:\"-\"`")
+				So(files["Amount.go"], ShouldContainSubstring, "Money   *Money   `json:\"-\"`")
+				So(files["Amount.go"], ShouldContainSubstring, "Number  *float64 `json:\"-\"`")
+			})
+
+			Convey("Then every variant the value matches should be set", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	for _, in := range []string{
+		` + "`" + `{"amount": 5}` + "`" + `,
+		` + "`" + `{"amount": 5.5}` + "`" + `,
+		` + "`" + `{"amount": {"value": 5, "currency": "EUR"}}` + "`" + `,
+		` + "`" + `{"amount": "5"}` + "`" + `,
+	} {
+		var r root
+		err := json.Unmarshal([]byte(in), &r)
+		fmt.Println(err, r.Amount.Integer != nil, r.Amount.Number != nil, r.Amount.Money != nil)
+		if err == nil {
+			out, _ := json.Marshal(r)
+			fmt.Println(string(out))
+		}
+	}
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil> true true false\n"+
+					"{\"amount\":5}\n"+
+					"<nil> false true false\n"+
+					"{\"amount\":5.5}\n"+
+					"<nil> false false true\n"+
+					"{\"amount\":{\"currency\":\"EUR\",\"value\":5}}\n"+
+					"value matches none of the anyOf schemas false false false\n")
+			})
+		})
+	})
+}
+
+func TestExternalRefs(t *testing.T) {
+	Convey("Given a schema with refs to schemas in other files", t, func() {
+		dir, err := ioutil.TempDir("", "schematyper")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(os.Mkdir(filepath.Join(dir, "shared"), 0755), ShouldBeNil)
+		files := map[string]string{
+			"common.json": `{
+				"definitions": {
+					"address": {
+						"type": "object",
+						"properties": {
+							"street": {"type": "string"},
+							"country": {"$ref": "#/definitions/country"}
+						}
+					},
+					"country": {"type": "string", "enum": ["US", "CA"]}
+				}
+			}`,
+			"shared/company.json": `{
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"country": {"$ref": "../common.json#/definitions/country"}
+				}
+			}`,
+		}
+		for name, src := range files {
+			So(ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(src), 0644), ShouldBeNil)
+		}
+		*schemaFile = filepath.Join(dir, "root.json")
+		defer func() { *schemaFile = "" }()
+
+		schema := `{
+			"type": "object",
+			"properties": {
+				"home": {"$ref": "common.json#/definitions/address"},
+				"work": {"$ref": "common.json#/definitions/address"},
+				"employer": {"$ref": "shared/company.json"},
+				"tags": {"$ref": "#/definitions/tags"}
+			},
+			"definitions": {
+				"tags": {"type": "array", "items": {"type": "string"}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then the schemas should be generated like definitions", func() {
+				So(types["Address"].path, ShouldEqual, "#/definitions/address")
+				So(types["Company"].path, ShouldEqual, "#/definitions/company")
+				So(types["Tags"].path, ShouldEqual, "#/definitions/tags")
+				So(printType(types["Address"]), ShouldContainSubstring, "Country Country `json:\"country,omitempty\"`")
+			})
+
+			Convey("Then a schema referred to from several places should have one type", func() {
+				So(types["Country"].path, ShouldEqual, "#/definitions/country")
+				So(types, ShouldNotContainKey, "Country2")
+				So(printType(types["Company"]), ShouldContainSubstring, "Country Country `json:\"country,omitempty\"`")
+				root := printType(types["root"])
+				So(root, ShouldContainSubstring, "Home Address `json:\"home,omitempty\"`")
+				So(root, ShouldContainSubstring, "Work Address `json:\"work,omitempty\"`")
+			})
+		})
+
+		Convey("When a ref points to a file that doesn't exist", func() {
+			var s metaSchema
+			_, err := decodeSchema([]byte(`{"$ref": "missing.json#/definitions/x"}`), &s)
+
+			Convey("Then decoding should fail", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "missing.json")
+			})
+		})
+	})
+}
+
+func TestDraft07Keywords(t *testing.T) {
+	Convey("Given a schema with draft-07 keywords", t, func() {
+		schema := `{
+			"$schema": "http://json-schema.org/draft-07/schema#",
+			"$comment": "Shipping details.",
+			"type": "object",
+			"properties": {
+				"country": {"type": "string"},
+				"postalCode": {"type": "string", "writeOnly": true},
+				"label": {"type": "string", "contentEncoding": "base64", "contentMediaType": "image/png", "readOnly": true}
+			},
+			"if": {"properties": {"country": {"const": "US"}}},
+			"then": {"properties": {"postalCode": {"pattern": "^[0-9]{5}$"}}},
+			"else": {"properties": {"postalCode": {"pattern": "^[A-Z0-9 ]+$"}}}
+		}`
+
+		Convey("When we decode it", func() {
+			var s metaSchema
+			_, err := decodeSchema([]byte(schema), &s)
+			So(err, ShouldBeNil)
+
+			Convey("Then the keywords should be kept", func() {
+				So(s.Comment, ShouldEqual, "Shipping details.")
+				So(s.If.Properties["country"].Const, ShouldEqual, "US")
+				So(s.Then.Properties["postalCode"].Pattern, ShouldEqual, "^[0-9]{5}$")
+				So(s.Else.Properties["postalCode"].Pattern, ShouldEqual, "^[A-Z0-9 ]+$")
+				So(s.Properties["label"].ContentEncoding, ShouldEqual, "base64")
+				So(s.Properties["label"].ContentMediaType, ShouldEqual, "image/png")
+				So(s.Properties["label"].ReadOnly, ShouldBeTrue)
+				So(s.Properties["postalCode"].WriteOnly, ShouldBeTrue)
+			})
+		})
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then the properties should be generated as before", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "PostalCode string `json:\"postalCode,omitempty\"`")
+			})
+
+			Convey("Then the base64 property should be a []byte", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Label []byte `json:\"label")
+			})
+		})
+	})
+}
+
+func TestDefsAndAnchors(t *testing.T) {
+	Convey("Given a draft 2019-09 schema with $defs and $anchors", t, func() {
+		schema := `{
+			"$schema": "https://json-schema.org/draft/2019-09/schema",
+			"type": "object",
+			"properties": {
+				"customer": {"$ref": "#/$defs/customer"},
+				"shipTo": {"$ref": "#address"},
+				"status": {"$ref": "#/$defs/status"}
+			},
+			"$defs": {
+				"customer": {
+					"type": "object",
+					"properties": {
+						"billTo": {"$ref": "#address"},
+						"tier": {"$ref": "#/$defs/customer/$defs/tier"}
+					},
+					"$defs": {
+						"tier": {"type": "string", "enum": ["gold", "silver"]}
+					}
+				},
+				"address": {
+					"$anchor": "address",
+					"type": "object",
+					"properties": {"street": {"type": "string"}}
+				},
+				"status": {"type": "string", "enum": ["open", "closed"]}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then the $defs should be generated like definitions", func() {
+				So(types["Customer"].path, ShouldEqual, "#/$defs/customer")
+				So(types["Status"].path, ShouldEqual, "#/$defs/status")
+				So(types["Tier"].path, ShouldEqual, "#/$defs/customer/$defs/tier")
+				So(printType(types["Customer"]), ShouldContainSubstring, "Tier Tier `json:\"tier,omitempty\"`")
+			})
+
+			Convey("Then refs to an $anchor should resolve to the schema with it", func() {
+				So(types["Address"].path, ShouldEqual, "#/$defs/address")
+				So(printType(types["root"]), ShouldContainSubstring, "ShipTo Address `json:\"shipTo,omitempty\"`")
+				So(printType(types["Customer"]), ShouldContainSubstring, "BillTo Address `json:\"billTo,omitempty\"`")
+			})
+		})
+	})
+
+	Convey("Given a draft-07 schema with an $id that's only a fragment", t, func() {
+		schema := `{
+			"$schema": "http://json-schema.org/draft-07/schema#",
+			"type": "object",
+			"properties": {"item": {"$ref": "#item"}},
+			"definitions": {
+				"item": {"$id": "#item", "type": "object", "properties": {"sku": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then refs to the fragment should resolve to the schema with it", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Item Item `json:\"item,omitempty\"`")
+				So(types["Item"].path, ShouldEqual, "#/definitions/item")
+			})
+		})
+	})
+}
+
+func TestPrefixItems(t *testing.T) {
+	Convey("Given a 2020-12 schema with prefixItems", t, func() {
+		schema := `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"tags": {"type": "array", "prefixItems": [{"type": "string"}]},
+				"point": {"type": "array", "prefixItems": [{"type": "number"}, {"type": "number"}], "items": false}
+			}
+		}`
+
+		Convey("When we decode it", func() {
+			var s metaSchema
+			_, err := decodeSchema([]byte(schema), &s)
+			So(err, ShouldBeNil)
+
+			Convey("Then prefixItems should be read as items, and items as additionalItems", func() {
+				So(s.Properties["point"].Items, ShouldHaveLength, 2)
+				So(s.Properties["point"].AdditionalItems, ShouldEqual, false)
+			})
+		})
+
+		Convey("When we generate the types", func() {
+			types := processSchema(schema)
+
+			Convey("Then the items should be typed as they are with items", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Tags []*Tag `json:\"tags,omitempty\"`")
+				So(printType(types["Tag"]), ShouldStartWith, "type Tag string")
+			})
+		})
+	})
+}
+
+func TestTupleItems(t *testing.T) {
+	Convey("Given a schema with tuple items", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"point": {"type": "array", "items": [{"type": "number"}, {"type": "number"}], "additionalItems": false},
+				"entry": {
+					"type": "array",
+					"minItems": 2,
+					"items": [
+						{"type": "string", "title": "key"},
+						{"$ref": "#/definitions/value"},
+						{"type": "string"}
+					]
+				}
+			},
+			"definitions": {
+				"value": {"type": "object", "properties": {"n": {"type": "integer"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then items of the same schema that allow no more items should make a Go array", func() {
+				So(printType(types["#/properties/point"]), ShouldStartWith, "type Point [2]float64\n")
+			})
+
+			Convey("Then other items should make a struct with a field for each, in order, and one for any items after them", func() {
+				So(printType(types["#/properties/entry"]), ShouldEqual, "type Entry struct {\n"+
+					"Key string `json:\"-\"`\n"+
+					"Value Value `json:\"-\"`\n"+
+					"Item3 *string `json:\"-\"`\n"+
+					"AdditionalItems []interface{} `json:\"-\"`\n"+
+					"}\n")
+			})
+
+			Convey("Then the struct should be marshaled as an array", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	err := json.Unmarshal([]byte(` + "`" + `{"point": [1, 2.5], "entry": ["a", {"n": 1}]}` + "`" + `), &r)
+	fmt.Println(err, r.Point, r.Entry.Key, r.Entry.Value.N, r.Entry.Item3 == nil)
+	out, _ := json.Marshal(r.Entry)
+	fmt.Println(string(out))
+
+	err = json.Unmarshal([]byte(` + "`" + `["b", {"n": 2}, "c"]` + "`" + `), &r.Entry)
+	fmt.Println(err, *r.Entry.Item3)
+	out, _ = json.Marshal(r.Entry)
+	fmt.Println(string(out))
+
+	err = json.Unmarshal([]byte(` + "`" + `["b"]` + "`" + `), &r.Entry)
+	fmt.Println(err)
+
+	err = json.Unmarshal([]byte(` + "`" + `["d", {"n": 3}, "e", 4, "f"]` + "`" + `), &r.Entry)
+	fmt.Println(err, r.Entry.AdditionalItems)
+	out, _ = json.Marshal(r.Entry)
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `<nil> [1 2.5] a 1 true
+["a",{"n":1}]
+<nil> c
+["b",{"n":2},"c"]
+array has fewer than 2 items
+<nil> [4 f]
+["d",{"n":3},"e",4,"f"]
+`)
+			})
+		})
+	})
+}
+
+func TestAdditionalItems(t *testing.T) {
+	Convey("Given tuples with additionalItems", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"command": {
+					"type": "array",
+					"minItems": 1,
+					"items": [{"type": "string", "title": "name"}],
+					"additionalItems": {"type": "string"}
+				},
+				"row": {
+					"type": "array",
+					"minItems": 2,
+					"items": [{"type": "integer"}, {"type": "string"}],
+					"additionalItems": {"$ref": "#/definitions/cell"}
+				},
+				"pair": {
+					"type": "array",
+					"items": [{"type": "integer"}, {"type": "integer"}],
+					"additionalItems": false
+				}
+			},
+			"definitions": {
+				"cell": {"type": "object", "properties": {"v": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the items after the positions should go in a slice after their fields", func() {
+				So(printType(types["#/properties/command"]), ShouldEqual, "type Command struct {\n"+
+					"Name string `json:\"-\"`\n"+
+					"AdditionalItems []string `json:\"-\"`\n"+
+					"}\n")
+				So(printType(types["#/properties/row"]), ShouldContainSubstring, "AdditionalItems []Cell `json:\"-\"`")
+			})
+
+			Convey("Then a tuple that allows no more items shouldn't have the slice", func() {
+				So(printType(types["#/properties/pair"]), ShouldStartWith, "type Pair [2]int64\n")
+			})
+
+			Convey("Then the additional items should be marshaled after the others", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	err := json.Unmarshal([]byte(` + "`" + `{"command": ["ls", "-l", "/tmp"], "row": [1, "a", {"v": "x"}, {"v": "y"}]}` + "`" + `), &r)
+	fmt.Println(err, r.Command.Name, r.Command.AdditionalItems, r.Row.AdditionalItems)
+	out, _ := json.Marshal(r)
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, `<nil> ls [-l /tmp] [{x} {y}]
+{"command":["ls","-l","/tmp"],"pair":[0,0],"row":[1,"a",{"v":"x"},{"v":"y"}]}
+`)
+			})
+		})
+	})
+}
+
+func TestUniqueSets(t *testing.T) {
+	Convey("Given arrays with uniqueItems", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"tags": {"type": "array", "uniqueItems": true, "items": {"type": "string"}},
+				"ids": {"type": "array", "uniqueItems": true, "items": {"type": "integer"}},
+				"flags": {"type": "array", "uniqueItems": true, "items": {"type": "boolean"}},
+				"codes": {"type": "array", "uniqueItems": true, "items": {"type": "string", "enum": ["a", "b"]}},
+				"names": {"type": "array", "items": {"type": "string"}}
+			},
+			"required": ["tags"]
+		}`
+
+		Convey("When we generate them without --unique-sets", func() {
+			types := processSchema(schema)
+
+			Convey("Then they should be slices", func() {
+				So(printType(types["root"]), ShouldContainSubstring, "Tags []*Tag `json:\"tags\"`")
+			})
+		})
+
+		Convey("When we generate them with --unique-sets", func() {
+			*uniqueSets = true
+			defer func() { *uniqueSets = false }()
+			files := generateSchema(schema)
+
+			Convey("Then arrays of unique strings and numbers should be sets", func() {
+				So(printType(types["#/properties/tags"]), ShouldEqual, "type Tags map[string]struct{}\n")
+				So(printType(types["#/properties/ids"]), ShouldEqual, "type Ids map[int64]struct{}\n")
+				So(printType(types["#"]), ShouldContainSubstring, "Tags Tags `json:\"tags\"`")
+			})
+
+			Convey("Then other arrays should still be slices", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "Flags []*Flag `json:\"flags,omitempty\"`")
+				So(printType(types["#"]), ShouldContainSubstring, "Codes []*Code `json:\"codes,omitempty\"`")
+				So(printType(types["#"]), ShouldContainSubstring, "Names []*Name `json:\"names,omitempty\"`")
+			})
+
+			Convey("Then the sets should be marshaled as sorted arrays", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	err := json.Unmarshal([]byte(` + "`" + `{"tags": ["b", "a"], "ids": [3, 1, 2]}` + "`" + `), &r)
+	fmt.Println(err, r.Tags.Has("a"), r.Tags.Has("c"), len(r.Ids))
+	r.Tags.Add("c")
+	var s root
+	s.Ids.Add(5)
+	out, _ := json.Marshal(r)
+	fmt.Println(string(out))
+	out, _ = json.Marshal(s)
+	fmt.Println(string(out))
+	fmt.Println(json.Unmarshal([]byte(` + "`" + `{"tags": ["a", "a"]}` + "`" + `), &r))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil> true false 3\n"+
+					`{"ids":[1,2,3],"tags":["a","b","c"]}`+"\n"+
+					`{"ids":[5],"tags":null}`+"\n"+
+					"duplicate item a\n")
+			})
+		})
+
+		Convey("When we generate the types with --unique-sets and --equal", func() {
+			*uniqueSets = true
+			*equalMethods = true
+			defer func() {
+				*uniqueSets = false
+				*equalMethods = false
+			}()
+			files := generateSchema(schema)
+
+			Convey("Then the sets should be compared by looking up each item, without reflect", func() {
+				So(files["Ids.go"], ShouldContainSubstring, "if _, ok := o[k]; !ok {")
+				So(files["Tags.go"], ShouldNotContainSubstring, "reflect")
+				program := `package main
+
+import "fmt"
+
+func main() {
+	a := Tags{"x": {}, "y": {}}
+	fmt.Println(a.Equal(Tags{"y": {}, "x": {}}), a.Equal(Tags{"x": {}, "z": {}}), a.Equal(Tags{"x": {}}), Ids(nil).Equal(Ids{}))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "true false false false\n")
+			})
+		})
+	})
+}
+
+func TestConditionals(t *testing.T) {
+	Convey("Given a schema with if/then/else", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"country": {"type": "string"},
+				"postal_code": {"type": "string"}
+			},
+			"if": {"properties": {"country": {"const": "US"}}, "required": ["country"]},
+			"then": {
+				"properties": {"state": {"type": "string", "maxLength": 2}, "postal_code": {"pattern": "^[0-9]{5}$"}},
+				"required": ["state"]
+			},
+			"else": {
+				"properties": {"province": {"type": "string"}},
+				"required": ["province"]
+			}
+		}`
+
+		Convey("When we generate the types without --validate", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the struct should have the properties of both branches", func() {
+				So(printType(types["#"]), ShouldEqual, "type root struct {\n"+
+					"Country string `json:\"country,omitempty\"`\n"+
+					"PostalCode string `json:\"postal_code,omitempty\"`\n"+
+					"Province string `json:\"province,omitempty\"`\n"+
+					"State string `json:\"state,omitempty\"`\n"+
+					"}\n")
+				So(files["root.go"], ShouldNotContainSubstring, "Validate")
+			})
+		})
+
+		Convey("When we generate the types with --validate", func() {
+			*validate = true
+			defer func() { *validate = false }()
+			files := generateSchema(schema)
+
+			Convey("Then Validate should check the branch that applies", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(root{Country: "US", State: "NY", PostalCode: "10001"}.Validate())
+	fmt.Println(root{Country: "US", PostalCode: "10001"}.Validate())
+	fmt.Println(root{Country: "US", State: "New York", PostalCode: "10001"}.Validate())
+	fmt.Println(root{Country: "US", State: "NY", PostalCode: "K1A"}.Validate())
+	fmt.Println(root{Country: "CA", Province: "ON", PostalCode: "K1A"}.Validate())
+	fmt.Println(root{Country: "CA"}.Validate())
+	fmt.Println(root{}.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\n"+
+					"state: required when country is \"US\"\n"+
+					"state: length must be at most 2\n"+
+					"postal_code: must match pattern ^[0-9]{5}$\n"+
+					"<nil>\n"+
+					"province: required unless country is \"US\"\n"+
+					"province: required unless country is \"US\"\n")
+			})
+		})
+	})
+
+	Convey("Given an if schema that only gives a property a value", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {"kind": {"type": "string"}, "size": {"type": "integer"}},
+			"if": {"properties": {"kind": {"const": "box"}}},
+			"then": {"required": ["size"]}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then it should match when the property is absent too", func() {
+				So(files["root.go"], ShouldContainSubstring, `if t.Kind == "" || t.Kind == "box" {`)
+			})
+		})
+	})
+
+	Convey("Given an if schema that can't be checked", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {"name": {"type": "string"}},
+			"if": {"properties": {"name": {"minLength": 3}}},
+			"then": {"properties": {"nickname": {"type": "string"}}, "required": ["nickname"]}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the properties should still be merged, without a check", func() {
+				So(printType(types["#"]), ShouldContainSubstring, "Nickname string `json:\"nickname,omitempty\"`")
+				So(files["root.go"], ShouldNotContainSubstring, "Validate")
+			})
+		})
+	})
+}
+
+func TestDependentRequired(t *testing.T) {
+	Convey("Given schemas with properties that require others", t, func() {
+		schemas := map[string]string{
+			"draft-04 dependencies": `{
+				"$schema": "http://json-schema.org/draft-04/schema#",
+				"type": "object",
+				"properties": {
+					"credit_card": {"type": "string"},
+					"billing_address": {"type": "string"},
+					"cvv": {"type": "integer"}
+				},
+				"dependencies": {"credit_card": ["billing_address", "cvv"]}
+			}`,
+			"2019-09 dependentRequired": `{
+				"$schema": "https://json-schema.org/draft/2019-09/schema",
+				"type": "object",
+				"properties": {
+					"credit_card": {"type": "string"},
+					"billing_address": {"type": "string"},
+					"cvv": {"type": "integer"}
+				},
+				"dependentRequired": {"credit_card": ["billing_address", "cvv"]}
+			}`,
+		}
+		*validate = true
+		defer func() { *validate = false }()
+
+		for name, schema := range schemas {
+			Convey("When we generate the types from "+name, func() {
+				files := generateSchema(schema)
+
+				Convey("Then Validate should check the required properties of those that are set", func() {
+					program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(root{}.Validate())
+	fmt.Println(root{CreditCard: "4111", BillingAddress: "1 Main St", Cvv: 123}.Validate())
+	fmt.Println(root{CreditCard: "4111", Cvv: 123}.Validate())
+	fmt.Println(root{CreditCard: "4111", BillingAddress: "1 Main St"}.Validate())
+	fmt.Println(root{BillingAddress: "1 Main St"}.Validate())
+}
+`
+					out, err := runGenerated(files, program)
+					So(err, ShouldBeNil)
+					So(out, ShouldEqual, "<nil>\n<nil>\n"+
+						"billing_address: required when credit_card is set\n"+
+						"cvv: required when credit_card is set\n"+
+						"<nil>\n")
+				})
+			})
+		}
+	})
+}
+
+func TestDependentSchemas(t *testing.T) {
+	Convey("Given schemas with properties that only apply when another is set", t, func() {
+		schemas := map[string]string{
+			"draft-04 dependencies": `{
+				"$schema": "http://json-schema.org/draft-04/schema#",
+				"type": "object",
+				"properties": {"credit_card": {"type": "string"}, "name": {"type": "string"}},
+				"dependencies": {
+					"credit_card": {
+						"properties": {"billing_address": {"type": "string"}, "name": {"minLength": 1}},
+						"required": ["billing_address"]
+					}
+				}
+			}`,
+			"2019-09 dependentSchemas": `{
+				"$schema": "https://json-schema.org/draft/2019-09/schema",
+				"type": "object",
+				"properties": {"credit_card": {"type": "string"}, "name": {"type": "string"}},
+				"dependentSchemas": {
+					"credit_card": {
+						"properties": {"billing_address": {"type": "string"}, "name": {"minLength": 1}},
+						"required": ["billing_address"]
+					}
+				}
+			}`,
+		}
+
+		for name, schema := range schemas {
+			Convey("When we generate the types from "+name, func() {
+				files := generateSchema(schema)
+
+				Convey("Then their properties should be optional fields noting the dependency", func() {
+					So(printType(types["#"]), ShouldEqual, "type root struct {\n"+
+						"// BillingAddress only applies when credit_card is set.\n"+
+						"BillingAddress string `json:\"billing_address,omitempty\"`\n"+
+						"CreditCard string `json:\"credit_card,omitempty\"`\n"+
+						"Name string `json:\"name,omitempty\"`\n"+
+						"}\n")
+				})
+
+				Convey("Then they should round-trip", func() {
+					program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	err := json.Unmarshal([]byte(` + "`" + `{"credit_card": "4111", "billing_address": "1 Main St"}` + "`" + `), &r)
+	out, _ := json.Marshal(r)
+	fmt.Println(err, string(out))
+}
+`
+					out, err := runGenerated(files, program)
+					So(err, ShouldBeNil)
+					So(out, ShouldEqual, `<nil> {"billing_address":"1 Main St","credit_card":"4111"}`+"\n")
+				})
+			})
+		}
+	})
+}
+
+func TestPropertyNames(t *testing.T) {
+	Convey("Given maps with propertyNames", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"labels": {"type": "object", "additionalProperties": {"type": "string"}, "propertyNames": {"pattern": "^[a-z]+$"}},
+				"limits": {"$ref": "#/definitions/limits"}
+			},
+			"definitions": {
+				"limits": {"type": "object", "additionalProperties": {"type": "integer"}, "propertyNames": {"enum": ["cpu", "memory"]}}
+			}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the map types should get a Validate method", func() {
+				So(files["Limits.go"], ShouldContainSubstring, "func (t Limits) Validate() error")
+			})
+
+			Convey("Then Validate should check the keys", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(root{Labels: map[string]Label{"app": "web"}, Limits: Limits{"cpu": 2}}.Validate())
+	fmt.Println(root{Labels: map[string]Label{"App": "web"}}.Validate())
+	fmt.Println(root{Limits: Limits{"disk": 2}}.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\n"+
+					"labels: keys must match pattern ^[a-z]+$\n"+
+					`keys must be one of "cpu", "memory"`+"\n")
+			})
+		})
+	})
+}
+
+func TestNot(t *testing.T) {
+	Convey("Given a schema with not schemas", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"username": {"type": "string", "not": {"enum": ["admin", "root"]}},
+				"slug": {"type": "string", "not": {"pattern": "^_"}},
+				"port": {"type": "integer", "not": {"const": 22}},
+				"email": {"type": "string"},
+				"phone": {"type": "string"}
+			},
+			"not": {"required": ["email", "phone"]}
+		}`
+		*validate = true
+		defer func() { *validate = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then Validate should reject what they match", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(root{Username: "alice", Slug: "a", Port: 8080, Email: "a@example.com"}.Validate())
+	fmt.Println(root{Username: "root"}.Validate())
+	fmt.Println(root{Slug: "_hidden"}.Validate())
+	fmt.Println(root{Port: 22}.Validate())
+	fmt.Println(root{Email: "a@example.com", Phone: "555"}.Validate())
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil>\n"+
+					`username: must not be one of "admin", "root"`+"\n"+
+					"slug: must not match pattern ^_\n"+
+					"port: must not be 22\n"+
+					"email: not allowed with phone\n")
+			})
+		})
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	Convey("Given a schema with defaults", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "default": "anon"},
+				"retries": {"type": "integer", "default": 3},
+				"quota": {"type": "integer", "default": 1e20},
+				"verbose": {"type": "boolean", "default": true},
+				"level": {"type": "string", "enum": ["info", "debug"], "default": "debug"},
+				"tags": {"type": "array", "items": {"type": "string"}, "default": []},
+				"hosts": {"type": "array", "items": {"type": "string"}, "default": ["localhost"]},
+				"avatar": {"type": "string", "contentEncoding": "base64", "default": "aGk="},
+				"limits": {"$ref": "#/definitions/limits"}
+			},
+			"definitions": {
+				"limits": {"type": "object", "properties": {"max": {"type": "number", "default": 1.5}}}
+			}
+		}`
+		*defaults = true
+		defer func() { *defaults = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then the structs should get New functions", func() {
+				So(files["root.go"], ShouldContainSubstring, "func newRoot() root {")
+				So(files["Limits.go"], ShouldContainSubstring, "// NewLimits returns a new Limits with the defaults of its schema set.")
+			})
+
+			Convey("Then the defaults that can't be set should be left out", func() {
+				So(files["root.go"], ShouldNotContainSubstring, "t.Hosts")
+				So(files["root.go"], ShouldNotContainSubstring, "t.Quota")
+			})
+
+			Convey("Then the New functions should set the defaults", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	r := newRoot()
+	fmt.Println(r.Name, r.Retries, r.Verbose, r.Level, r.Tags != nil, r.Hosts == nil, string(r.Avatar), r.Limits.Max)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "anon 3 true debug true true hi 1.5\n")
+			})
+		})
+	})
+}
+
+func TestDeprecated(t *testing.T) {
+	Convey("Given a schema with deprecated properties and definitions", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"username": {"type": "string", "deprecated": true},
+				"email": {"type": "string"},
+				"profile": {"$ref": "#/definitions/profile"}
+			},
+			"definitions": {
+				"profile": {"type": "object", "description": "A user profile.", "deprecated": true, "properties": {"bio": {"type": "string"}}}
+			}
+		}`
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then deprecated fields should get a Deprecated comment", func() {
+				So(files["root.go"], ShouldContainSubstring, "// Deprecated: The schema marks this property as deprecated.\n\tUsername string")
+				So(strings.Count(files["root.go"], "Deprecated:"), ShouldEqual, 1)
+			})
+
+			Convey("Then deprecated types should get a Deprecated paragraph after their description", func() {
+				So(files["Profile.go"], ShouldContainSubstring, "// A user profile.\n//\n// Deprecated: The schema marks this type as deprecated.\ntype Profile struct")
+			})
+		})
+	})
+}
+
+func TestOutputExamples(t *testing.T) {
+	Convey("Given a schema with examples", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string"},
+				"address": {"$ref": "#/definitions/address"}
+			},
+			"examples": [{"name": "Ann", "address": {"city": "Oslo"}}, {"name": "Bo"}],
+			"definitions": {
+				"address": {"type": "object", "properties": {"city": {"type": "string"}}, "examples": [{"city": "Rome"}]},
+				"point": {"type": "object", "properties": {"x": {"type": "integer"}}, "examples": [{"x": "one"}]}
+			}
+		}`
+		*outputExamples = true
+		defer func() { *outputExamples = false }()
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then there should be an examples file with a function for each type with examples", func() {
+				So(files, ShouldContainKey, "root_examples.go")
+				So(files["root_examples.go"], ShouldContainSubstring, "// AddressExamples returns the examples of the schema of Address, or an error if one\n// doesn't fit the type.\nfunc AddressExamples() ([]Address, error) {")
+				So(files["root_examples.go"], ShouldContainSubstring, "func rootExamples() ([]root, error) {")
+			})
+
+			Convey("Then the functions should return the examples, or an error if they don't fit", func() {
+				program := `package main
+
+import "fmt"
+
+func main() {
+	roots, err := rootExamples()
+	fmt.Println(err, len(roots), roots[0].Name, roots[0].Address.City, roots[1].Name)
+	addresses, err := AddressExamples()
+	fmt.Println(err, addresses[0].City)
+	points, err := PointExamples()
+	fmt.Println(err != nil, points == nil)
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil> 2 Ann Oslo Bo\n<nil> Rome\ntrue true\n")
+			})
+		})
+	})
+}
+
+func TestBase64(t *testing.T) {
+	Convey("Given a schema with base64 strings", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"avatar": {"type": "string", "contentEncoding": "base64"},
+				"key": {"type": "string", "format": "byte"},
+				"note": {"type": "string", "contentEncoding": "quoted-printable"}
+			}
+		}`
+
+		Convey("When we generate them with --proto-tags", func() {
+			*protoTags = true
+			defer func() { *protoTags = false }()
+			types := processSchema(schema)
+
+			Convey("Then they should be single values rather than repeated", func() {
+				So(printType(types["root"]), ShouldContainSubstring, `protobuf:"bytes,1,opt,name=avatar"`)
+			})
+		})
+
+		Convey("When we generate them with --equal", func() {
+			*equalMethods = true
+			defer func() { *equalMethods = false }()
+			files := generateSchema(schema)
+
+			Convey("Then they should be compared with bytes.Equal", func() {
+				So(files["root.go"], ShouldContainSubstring, "if !bytes.Equal(t.Avatar, o.Avatar) {")
+			})
+		})
+
+		Convey("When we generate the types", func() {
+			files := generateSchema(schema)
+
+			Convey("Then they should be byte slices", func() {
+				So(files["root.go"], ShouldContainSubstring, "Avatar []byte `json:\"avatar,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, "Key    []byte `json:\"key,omitempty\"`")
+				So(files["root.go"], ShouldContainSubstring, "Note   string `json:\"note,omitempty\"`")
+			})
+
+			Convey("Then they should be decoded when unmarshaling", func() {
+				program := `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	var r root
+	err := json.Unmarshal([]byte(` + "`" + `{"avatar": "aGVsbG8=", "key": "a2V5"}` + "`" + `), &r)
+	fmt.Println(err, string(r.Avatar), string(r.Key))
+	out, _ := json.Marshal(r)
+	fmt.Println(string(out))
+}
+`
+				out, err := runGenerated(files, program)
+				So(err, ShouldBeNil)
+				So(out, ShouldEqual, "<nil> hello key\n"+`{"avatar":"aGVsbG8=","key":"a2V5"}`+"\n")
+			})
+		})
+	})
+}
@@ -0,0 +1,27 @@
+package gen
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goGenerateArg returns arg as go generate reads it back: quoted if it's
+// empty or has spaces or quotes in it, and with $ escaped, as go generate
+// expands environment variables.
+func goGenerateArg(arg string) string {
+	if arg == "" || strings.IndexFunc(arg, func(r rune) bool { return unicode.IsSpace(r) || r == '"' }) >= 0 {
+		arg = strconv.Quote(arg)
+	}
+	return strings.Replace(arg, "$", "$DOLLAR", -1)
+}
+
+// goGenerateDirective returns the //go:generate directive that runs
+// schematyper again with the arguments of this run, for --emit-go-generate.
+func goGenerateDirective() string {
+	words := []string{"//go:generate", "schematyper"}
+	for _, arg := range options.Args {
+		words = append(words, goGenerateArg(arg))
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// maxInferredEnum is the most distinct strings a value can have and still be
+// inferred as an enum with --infer.
+const maxInferredEnum = 5
+
+// inferredValue accumulates what the samples show about a value.
+type inferredValue struct {
+	// kinds holds the JSON types of the value, with integer and number apart
+	kinds stringset.StringSet
+
+	// objects is the number of objects seen, and props the values of their
+	// properties, whose counts are the number of objects they're in
+	objects    int
+	props      map[string]*inferredValue
+	propCounts map[string]int
+
+	items *inferredValue
+
+	strings map[string]int
+}
+
+func newInferredValue() *inferredValue {
+	return &inferredValue{
+		kinds:      stringset.New(),
+		props:      make(map[string]*inferredValue),
+		propCounts: make(map[string]int),
+		strings:    make(map[string]int),
+	}
+}
+
+// add merges a sample value, decoded with UseNumber, into iv.
+func (iv *inferredValue) add(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		iv.kinds.Add(typeNull)
+	case bool:
+		iv.kinds.Add(typeBoolean)
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			iv.kinds.Add(typeInteger)
+		} else {
+			iv.kinds.Add(typeNumber)
+		}
+	case string:
+		iv.kinds.Add(typeString)
+		iv.strings[v]++
+	case []interface{}:
+		iv.kinds.Add(typeArray)
+		if iv.items == nil {
+			iv.items = newInferredValue()
+		}
+		for _, item := range v {
+			iv.items.add(item)
+		}
+	case map[string]interface{}:
+		iv.kinds.Add(typeObject)
+		iv.objects++
+		for name, propValue := range v {
+			if iv.props[name] == nil {
+				iv.props[name] = newInferredValue()
+			}
+			iv.props[name].add(propValue)
+			iv.propCounts[name]++
+		}
+	}
+}
+
+// isEnum returns true if the strings of iv are a small set whose values each
+// repeat, which makes them more likely to be an enum than arbitrary text.
+func (iv *inferredValue) isEnum() bool {
+	if len(iv.strings) < 2 || len(iv.strings) > maxInferredEnum {
+		return false
+	}
+	for _, count := range iv.strings {
+		if count < 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// schema returns the schema of iv. Integers and numbers widen to number, and
+// other conflicting types to no type, which is generated as interface{}.
+func (iv *inferredValue) schema() metaSchema {
+	var s metaSchema
+
+	kinds := stringset.New(iv.kinds.Slice()...)
+	nullable := kinds.Has(typeNull)
+	kinds.Remove(typeNull)
+	if kinds.Has(typeInteger) && kinds.Has(typeNumber) {
+		kinds.Remove(typeInteger)
+	}
+	if len(kinds) != 1 {
+		return s
+	}
+	jsonType := kinds.Sorted()[0]
+	if nullable {
+		s.Type = []interface{}{jsonType, typeNull}
+	} else {
+		s.Type = jsonType
+	}
+
+	switch jsonType {
+	case typeObject:
+		s.Properties = make(map[string]metaSchema, len(iv.props))
+		names, _ := stringset.FromMapKeys(iv.props)
+		for _, name := range names.Sorted() {
+			prop := iv.props[name]
+			s.Properties[name] = prop.schema()
+			// a property is required if every object has it and it's never null
+			if iv.propCounts[name] == iv.objects && !prop.kinds.Has(typeNull) {
+				s.Required = append(s.Required, metaStringArrayItem(name))
+			}
+		}
+	case typeArray:
+		if iv.items != nil && len(iv.items.kinds) > 0 {
+			s.Items = iv.items.schema()
+		}
+	case typeString:
+		if iv.isEnum() {
+			values, _ := stringset.FromMapKeys(iv.strings)
+			for _, value := range values.Sorted() {
+				s.Enum = append(s.Enum, value)
+			}
+		}
+	}
+	return s
+}
+
+// decodeSamples returns the JSON values in data, which is either an array of
+// samples or samples one after another, like one per line.
+func decodeSamples(data []byte) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var samples []interface{}
+	for {
+		var sample interface{}
+		if err := dec.Decode(&sample); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	if len(samples) == 1 {
+		if array, ok := samples[0].([]interface{}); ok {
+			samples = array
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples")
+	}
+	return samples, nil
+}
+
+// inferSchema returns a schema that the JSON samples in data match, for
+// generating types from examples with --infer.
+func inferSchema(data []byte) (*metaSchema, error) {
+	samples, err := decodeSamples(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root := newInferredValue()
+	for _, sample := range samples {
+		root.add(sample)
+	}
+
+	// round-trip the schema so items are decoded like those of a parsed schema
+	schemaJSON, err := json.Marshal(root.schema())
+	if err != nil {
+		return nil, err
+	}
+	logf("inferred schema: %s", schemaJSON)
+	var s metaSchema
+	if err := json.Unmarshal(schemaJSON, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
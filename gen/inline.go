@@ -0,0 +1,94 @@
+package gen
+
+import (
+	"bytes"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// nestingDepth returns how many levels gt is nested below the root or the
+// definition it's in: 1 for the object of a property of the root, and so on.
+func (gt goType) nestingDepth() int {
+	depth := 0
+	for p := gt.path; p != rootPath && !isDefinitionPath(p); depth++ {
+		t, ok := types[p]
+		if !ok {
+			break
+		}
+		p = t.parentPath
+	}
+	return depth
+}
+
+// hasMethods returns true if gt, a struct, gets any methods, which an
+// anonymous struct can't have.
+func (gt goType) hasMethods(validated map[string]bool) bool {
+	if validated[gt.path] || *equalMethods || *merge || *isZero || *accessors != accessorsNone {
+		return true
+	}
+	if (*redactSecrets && gt.hasSecrets()) || gt.preservesUnknown() || ((*outputTest || *outputExamples) && len(gt.examples) > 0) {
+		return true
+	}
+	for _, sf := range gt.Fields {
+		if sf.Pattern != "" || sf.Catchall || sf.Variant || sf.Position > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// markInline sets Inline on the nested structs that are written as anonymous
+// structs with --inline-depth: those at most that deep that don't have
+// methods and that are the type of a single field, which isn't embedded.
+func markInline(validated map[string]bool) {
+	if *inlineDepth <= 0 {
+		return
+	}
+
+	// types that other types are declared as, or that are embedded or
+	// recursive, need a name
+	fieldRefs := make(map[string]int)
+	named := stringset.New()
+	for _, gt := range types {
+		named.Add(gt.TypeRef)
+		for _, sf := range gt.Fields {
+			if sf.Embedded || sf.Recursive {
+				named.Add(sf.TypeRef)
+			} else {
+				fieldRefs[sf.TypeRef]++
+			}
+		}
+	}
+
+	var inline []string
+	for path, gt := range types {
+		if gt.TypePrefix != typeStruct || gt.Alias || path == rootPath || isDefinitionPath(path) || named.Has(path) || fieldRefs[path] != 1 {
+			continue
+		}
+		if depth := gt.nestingDepth(); depth > *inlineDepth || gt.hasMethods(validated) {
+			continue
+		}
+		inline = append(inline, path)
+	}
+	for _, path := range inline {
+		gt := types[path]
+		gt.Inline = true
+		types[path] = gt
+	}
+}
+
+// inlineString returns gt, an inline struct, as an anonymous struct type.
+func (gt goType) inlineString() string {
+	var buf bytes.Buffer
+	buf.WriteString("struct {\n")
+	gt.printFields(&buf, stringset.New())
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// inlineImports adds the packages used by the fields of gt, an inline
+// struct, to imports.
+func (gt goType) inlineImports(imports stringset.StringSet) {
+	var buf bytes.Buffer
+	gt.printFields(&buf, imports)
+}
@@ -0,0 +1,110 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// hasIsZero returns true if gt gets an IsZero method with --iszero, which it
+// does if it's a struct without a field of the same name.
+func (gt goType) hasIsZero() bool {
+	if gt.TypePrefix != typeStruct {
+		return false
+	}
+	for _, sf := range gt.Fields {
+		if sf.Name == "IsZero" {
+			return false
+		}
+	}
+	return true
+}
+
+// zeroCheck returns an expression that is true if expr, a value of the Go type
+// typeStr, is the zero value. Types other than the basic ones, such as those
+// from --format-map, are checked with reflection.
+func zeroCheck(expr, typeStr string, imports stringset.StringSet) string {
+	switch {
+	case typeStr == typeString:
+		return expr + ` == ""`
+	case typeStr == typeBool:
+		return "!" + expr
+	case typeStr == typeInt || typeStr == typeFloat64:
+		return expr + " == 0"
+	case typeStr == typeTime:
+		// the zero instant in another location isn't the zero struct
+		return expr + ".IsZero()"
+	case typeStr == typeEmptyInterface || strings.HasPrefix(typeStr, "*") ||
+		strings.HasPrefix(typeStr, "[]") || strings.HasPrefix(typeStr, "map["):
+		return expr + " == nil"
+	default:
+		imports.Add("reflect")
+		return "reflect.ValueOf(" + expr + ").IsZero()"
+	}
+}
+
+// namedZeroCheck returns an expression that is true if expr, a value of the
+// generated type gt, is the zero value.
+func namedZeroCheck(expr string, gt goType, imports stringset.StringSet) string {
+	switch {
+	case *isZero && gt.hasIsZero():
+		return expr + ".IsZero()"
+	case gt.TypePrefix == typeStruct:
+		imports.Add("reflect")
+		return "reflect.ValueOf(" + expr + ").IsZero()"
+	case gt.TypePrefix == typeTime && !gt.Alias:
+		// a type declared as time.Time doesn't have its methods
+		imports.Add("time")
+		return "time.Time(" + expr + ").IsZero()"
+	}
+
+	baseType, ok := types[gt.TypeRef]
+	if ok && gt.TypePrefix == "" {
+		return namedZeroCheck(expr, baseType, imports)
+	}
+	typeStr := gt.TypePrefix
+	if ok {
+		typeStr += baseType.Name
+	}
+	return zeroCheck(expr, typeStr, imports)
+}
+
+// printIsZero writes an IsZero method for gt that returns true if each of its
+// fields is the zero value, calling IsZero on nested structs.
+func (gt goType) printIsZero(buf *bytes.Buffer, imports stringset.StringSet) {
+	if !gt.hasIsZero() {
+		return
+	}
+
+	sort.Stable(gt.Fields)
+	var checks []string
+	for _, sf := range gt.Fields {
+		typeStr, _ := sf.typeString()
+		expr := "t." + sf.Name
+		if sf.Embedded {
+			expr = "t." + typeStr
+		}
+
+		refType, isRef := types[sf.TypeRef]
+		switch {
+		case sf.NullSlice:
+			checks = append(checks, expr+".IsZero()")
+		case isRef && sf.TypePrefix == "" && !strings.HasPrefix(typeStr, "*"):
+			checks = append(checks, namedZeroCheck(expr, refType, imports))
+		default:
+			checks = append(checks, zeroCheck(expr, typeStr, imports))
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, "true")
+	}
+
+	buf.WriteString("\n")
+	buf.WriteString("// IsZero returns true if each field of t is the zero value.\n")
+	buf.WriteString(fmt.Sprintf("func (t %s) IsZero() bool {\n", gt.Name))
+	buf.WriteString("return " + strings.Join(checks, " &&\n") + "\n")
+	buf.WriteString("}\n")
+}
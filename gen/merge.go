@@ -0,0 +1,127 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// The values of --merge-values.
+const (
+	mergeValuesNonZero = "nonzero"
+	mergeValuesAlways  = "always"
+)
+
+// hasMerge returns true if gt gets a Merge method with --merge, which it does
+// if it's a struct without a field of the same name.
+func (gt goType) hasMerge() bool {
+	if gt.TypePrefix != typeStruct || gt.Alias {
+		return false
+	}
+	for _, sf := range gt.Fields {
+		if sf.Name == "Merge" {
+			return false
+		}
+	}
+	return true
+}
+
+// negateCheck returns an expression that is true if check, an expression
+// from zeroCheck, is false.
+func negateCheck(check string) string {
+	for _, op := range []string{" == nil", " == 0", ` == ""`} {
+		if strings.HasSuffix(check, op) {
+			return strings.TrimSuffix(check, op) + " !" + op[2:]
+		}
+	}
+	if strings.HasPrefix(check, "!") {
+		return check[1:]
+	}
+	return "!" + check
+}
+
+// mergeStatement returns the statement that merges the field sf of o into t.
+// Fields of generated structs are merged in turn, pointers, slices and maps
+// are copied if they aren't nil, and other values if they aren't the zero
+// value or, with --merge-values=always, regardless.
+func (sf structField) mergeStatement(imports stringset.StringSet) string {
+	typeStr, _ := sf.typeString()
+	name := sf.Name
+	if sf.Embedded {
+		name = typeStr
+	}
+	dst, src := "t."+name, "o."+name
+
+	if sf.NullSlice {
+		return fmt.Sprintf("if %s.Set {\n%s = %s\n}\n", src, dst, src)
+	}
+
+	refType, isRef := types[sf.TypeRef]
+	if isRef && refType.hasMerge() {
+		switch typeStr {
+		case refType.Name:
+			return fmt.Sprintf("%s.Merge(%s)\n", dst, src)
+		case "*" + refType.Name:
+			// merged into a copy rather than aliasing o's value
+			return fmt.Sprintf("if %s != nil {\nif %s == nil {\n%s = new(%s)\n}\n%s.Merge(*%s)\n}\n",
+				src, dst, dst, refType.Name, dst, src)
+		}
+	}
+
+	var check string
+	if isRef && sf.TypePrefix == "" && !strings.HasPrefix(typeStr, "*") {
+		check = namedZeroCheck(src, refType, imports)
+	} else {
+		check = zeroCheck(src, typeStr, imports)
+	}
+	if *mergeValues == mergeValuesAlways && !strings.HasSuffix(check, " == nil") {
+		return fmt.Sprintf("%s = %s\n", dst, src)
+	}
+	return fmt.Sprintf("if %s {\n%s = %s\n}\n", negateCheck(check), dst, src)
+}
+
+// printMerge writes a Merge method for gt that copies the fields of another
+// value that are set over those of the receiver, for partial updates.
+func (gt goType) printMerge(buf *bytes.Buffer, imports stringset.StringSet) {
+	if !gt.hasMerge() {
+		return
+	}
+
+	sort.Stable(gt.Fields)
+	buf.WriteString("\n")
+	if *mergeValues == mergeValuesAlways {
+		buf.WriteString("// Merge copies the fields of o over those of t, except for pointers, slices\n")
+		buf.WriteString("// and maps that are nil. Nested structs are merged field by field.\n")
+	} else {
+		buf.WriteString("// Merge copies the fields of o that are set over those of t: pointers, slices\n")
+		buf.WriteString("// and maps that aren't nil and other values that aren't the zero value.\n")
+		buf.WriteString("// Nested structs are merged field by field.\n")
+	}
+	buf.WriteString(fmt.Sprintf("func (t *%s) Merge(o %s) {\n", gt.Name, gt.Name))
+	if gt.union == unionOneOf {
+		gt.printVariantClears(buf)
+	}
+	for _, sf := range gt.Fields {
+		buf.WriteString(sf.mergeStatement(imports))
+	}
+	buf.WriteString("}\n")
+}
+
+// printVariantClears writes the statements that clear the variants of t, a
+// oneOf union, other than the one o holds, if it holds one, so that Merge
+// replaces the variant t holds rather than leaving two set. A variant both
+// hold is merged as usual.
+func (gt goType) printVariantClears(buf *bytes.Buffer) {
+	for _, sf := range gt.Fields {
+		fmt.Fprintf(buf, "if o.%s != nil {\n", sf.Name)
+		for _, other := range gt.Fields {
+			if other.Name != sf.Name {
+				fmt.Fprintf(buf, "t.%s = nil\n", other.Name)
+			}
+		}
+		buf.WriteString("}\n")
+	}
+}
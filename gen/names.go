@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"sort"
+	"strconv"
+)
+
+// typeDepth returns the number of types that path is nested in.
+func typeDepth(path string) int {
+	depth := 0
+	seen := map[string]bool{path: true}
+	for gt := types[path]; gt.parentPath != "" && !seen[gt.parentPath]; gt = types[gt.parentPath] {
+		seen[gt.parentPath] = true
+		depth++
+	}
+	return depth
+}
+
+// allocationOrder returns the paths of the types in the order they're named
+// in: the root type, then the definitions and then the other types,
+// shallowest first, each by path.
+func allocationOrder() []string {
+	paths := make([]string, 0, len(types))
+	for path := range types {
+		paths = append(paths, path)
+	}
+	rank := func(path string) int {
+		switch {
+		case path == rootPath:
+			return 0
+		case isDefinitionPath(path):
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		pi, pj := paths[i], paths[j]
+		if ri, rj := rank(pi), rank(pj); ri != rj {
+			return ri < rj
+		}
+		if rank(pi) == 2 {
+			if di, dj := typeDepth(pi), typeDepth(pj); di != dj {
+				return di < dj
+			}
+		}
+		return pi < pj
+	})
+	return paths
+}
+
+// allocateNames gives every type a unique name. The types are named in the
+// order of allocationOrder, so the names don't depend on the order the schema
+// was processed in and a type keeps its name if it's the first to want it.
+// A type whose name is taken is named after its parent, then its
+// grandparent and so on, like with --nested-names=path, and failing that is
+// numbered.
+func allocateNames() {
+	typesByName = make(stringSetMap)
+	for _, path := range allocationOrder() {
+		gt := types[path]
+		origName := gt.origTypeName
+		name := gt.Name
+		seen := map[string]bool{path: true}
+		for parentPath := gt.parentPath; typesByName.has(name) && !seen[parentPath]; parentPath = types[parentPath].parentPath {
+			seen[parentPath] = true
+			parent := types[parentPath]
+			if parent.origTypeName == "" {
+				break
+			}
+			origName = parent.origTypeName + "-" + origName
+			name = generateTypeName(origName)
+		}
+		for i := 2; typesByName.has(name); i++ {
+			name = generateTypeName(origName + "-" + strconv.Itoa(i))
+		}
+		if name != gt.Name {
+			// children named after this type use its new name
+			gt.origTypeName = origName
+			gt.Name = name
+			types[path] = gt
+		}
+		typesByName.addTo(name, path)
+	}
+}
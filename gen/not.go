@@ -0,0 +1,159 @@
+package gen
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// notConstraints holds what the not schema of a scalar forbids: matching a
+// pattern, or being one of some values.
+type notConstraints struct {
+	Pattern string
+	Values  []interface{}
+}
+
+// getNotConstraints returns what s, a not schema, forbids a scalar, or nil if
+// it does anything else, which isn't checked.
+func getNotConstraints(s *metaSchema) *notConstraints {
+	rest := *s
+	n := &notConstraints{Pattern: rest.Pattern, Values: rest.Enum}
+	if rest.Const != nil {
+		n.Values = append(n.Values, rest.Const)
+	}
+	rest.Pattern, rest.Enum, rest.Const, rest.Type, rest.Title, rest.Description = "", nil, nil, nil, "", ""
+	if !isEmptyMetaSchema(&rest) || (n.Pattern == "" && len(n.Values) == 0) {
+		return nil
+	}
+	if n.Pattern != "" {
+		if _, err := regexp.Compile(n.Pattern); err != nil {
+			log.Printf("Ignoring not pattern %q: %s\n", n.Pattern, err)
+			return nil
+		}
+	}
+	return n
+}
+
+// appliesTo returns true if n can be checked on a value of the Go type ts.
+func (n *notConstraints) appliesTo(ts string) bool {
+	if n == nil || (n.Pattern != "" && ts != typeString) {
+		return false
+	}
+	for _, val := range n.Values {
+		if _, ok := valueLiteral(val, ts); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// getNotRequired returns the properties that the not schema of s, the schema
+// of a struct with the given fields, forbids to be set together, by their
+// fields, or nil if it does anything else.
+func getNotRequired(s *metaSchema, fields structFields) []dependentRequirement {
+	rest := *s.Not
+	rest.Required = nil
+	if jsonType, _ := rest.Type.(string); jsonType == typeObject {
+		rest.Type = nil
+	}
+	if !isEmptyMetaSchema(&rest) || len(s.Not.Required) == 0 {
+		return nil
+	}
+
+	var forbidden []dependentRequirement
+	seen := stringset.New()
+	for _, req := range s.Not.Required {
+		name := string(req)
+		if seen.Has(name) {
+			continue
+		}
+		seen.Add(name)
+		sf, ok := fieldByProperty(fields, name)
+		if !ok {
+			return nil
+		}
+		if _, ok := sf.absentCheck(); !ok {
+			return nil
+		}
+		forbidden = append(forbidden, dependentRequirement{propertyName: name, field: sf.Name})
+	}
+	return forbidden
+}
+
+// warnIgnoredNot warns that the not schema of s, at path, isn't checked by
+// Validate, unless checked is set.
+func warnIgnoredNot(s *metaSchema, checked bool, path string) {
+	if s.Not != nil && !checked && (*validate || *validateAll) {
+		log.Printf("Ignoring not of %s: only an enum, const or pattern of a scalar, or the required properties of an object, are checked\n", path)
+	}
+}
+
+// writeNotChecks writes the checks that expr, a value of the Go type given
+// by typePrefix, doesn't match what n forbids.
+func (w validateWriter) writeNotChecks(n notConstraints, expr, typePrefix, propName, fieldName string) {
+	if n.Pattern != "" {
+		w.imports.Add("regexp")
+		patternVar := generateIdentifier(w.typeName+" "+fieldName+" not pattern", false)
+		fmt.Fprintf(w.decls, "var %s = regexp.MustCompile(%q)\n", patternVar, n.Pattern)
+		fmt.Fprintf(w.buf, "if %s.MatchString(%s) {\n", patternVar, expr)
+		w.writeFail(propName, fmt.Sprintf("must not match pattern %s", n.Pattern))
+		w.buf.WriteString("}\n")
+	}
+	if len(n.Values) == 0 {
+		return
+	}
+
+	// a switch can't have the same case twice
+	var literals []string
+	seen := stringset.New()
+	for _, val := range n.Values {
+		literal, _ := valueLiteral(val, typePrefix)
+		if !seen.Has(literal) {
+			seen.Add(literal)
+			literals = append(literals, literal)
+		}
+	}
+	msg := "must not be " + literals[0]
+	if len(literals) > 1 {
+		msg = "must not be one of " + strings.Join(literals, ", ")
+	}
+	fmt.Fprintf(w.buf, "switch %s {\ncase %s:\n", expr, strings.Join(literals, ", "))
+	w.writeFail(propName, msg)
+	w.buf.WriteString("}\n")
+}
+
+// writeNotRequired writes the check that the properties of gt, a struct,
+// that its not schema forbids to be set together aren't. It's skipped if gt
+// doesn't have all of their fields, like a --split-rw variant that leaves
+// some out.
+func (w validateWriter) writeNotRequired(gt goType) {
+	if len(gt.notRequired) == 0 {
+		return
+	}
+	fields := make(map[string]structField)
+	for _, sf := range gt.Fields {
+		fields[sf.Name] = sf
+	}
+
+	var conds, others []string
+	for i, forbidden := range gt.notRequired {
+		sf, ok := fields[forbidden.field]
+		if !ok {
+			return
+		}
+		conds = append(conds, sf.presentCheck())
+		if i > 0 {
+			others = append(others, forbidden.propertyName)
+		}
+	}
+	msg := "not allowed"
+	if len(others) > 0 {
+		msg = "not allowed with " + strings.Join(others, " and ")
+	}
+	fmt.Fprintf(w.buf, "if %s {\n", strings.Join(conds, " && "))
+	w.writeFail(gt.notRequired[0].propertyName, msg)
+	w.buf.WriteString("}\n")
+}
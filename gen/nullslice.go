@@ -0,0 +1,70 @@
+package gen
+
+import (
+	"bytes"
+	"strings"
+)
+
+// nullSliceTypeName returns the name of the generic wrapper used for nullable
+// arrays with --null-slices.
+func nullSliceTypeName() string {
+	return generateIdentifier("null slice", *packageName != "main")
+}
+
+// nullSliceType returns the wrapper type for a field whose slice type is
+// sliceType.
+func nullSliceType(sliceType string) string {
+	return nullSliceTypeName() + "[" + strings.TrimPrefix(sliceType, "[]") + "]"
+}
+
+// hasNullSlices returns true if any of the types has a field that uses the
+// wrapper.
+func hasNullSlices(typesSlice goTypes) bool {
+	for _, gt := range typesSlice {
+		for _, sf := range gt.Fields {
+			if sf.NullSlice {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// printNullSlice returns the file name and body of a file declaring the
+// wrapper, which unlike a plain slice keeps an absent array, null and an empty
+// array apart when unmarshaling and marshaling. It relies on the omitzero tag
+// option, so the generated code needs Go 1.24 or later.
+func printNullSlice() (fileName string, body []byte) {
+	name := nullSliceTypeName()
+
+	var buf bytes.Buffer
+	buf.WriteString("// " + name + " is a JSON array that may be absent, null or a list. Set is false\n")
+	buf.WriteString("// if the array is absent; otherwise a nil Value is null.\n")
+	buf.WriteString("type " + name + "[T any] struct {\n")
+	buf.WriteString("Value []T\n")
+	buf.WriteString("Set bool\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// IsZero returns true if the array is absent, so omitzero leaves it out.\n")
+	buf.WriteString("func (s " + name + "[T]) IsZero() bool {\n")
+	buf.WriteString("return !s.Set\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("func (s " + name + "[T]) MarshalJSON() ([]byte, error) {\n")
+	buf.WriteString("if s.Value == nil {\n")
+	buf.WriteString("return []byte(\"null\"), nil\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return json.Marshal(s.Value)\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("func (s *" + name + "[T]) UnmarshalJSON(data []byte) error {\n")
+	buf.WriteString("s.Set = true\n")
+	buf.WriteString("if string(data) == \"null\" {\n")
+	buf.WriteString("s.Value = nil\n")
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return json.Unmarshal(data, &s.Value)\n")
+	buf.WriteString("}\n")
+
+	return name + ".go", buf.Bytes()
+}
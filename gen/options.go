@@ -0,0 +1,246 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Options holds the settings for generating types. Each corresponds to the
+// schematyper flag of the same name, and the zero value of each is the
+// flag's default.
+type Options struct {
+	// PackageName is the package of the generated files; default is "main"
+	PackageName string
+	// RootTypeName is the name of the root type; default is generated from
+	// the name of SchemaFile, or Root if it's empty
+	RootTypeName string
+	// Prefix is the prefix for non-root types
+	Prefix string
+	// SchemaVersion is the JSON Schema version to interpret the schema as:
+	// draft-04, draft-06, draft-07, 2019-09, or 2020-12; default is detected
+	// from $schema, or draft-07
+	SchemaVersion string
+	// RootPointer is the JSON pointer of the schema to generate the root type
+	// from, e.g. #/components/schemas/User
+	RootPointer string
+	// SchemaFile is the path of the schema, which refs to other files are
+	// resolved against; they aren't followed if it's empty
+	SchemaFile string
+	// Args are the arguments schematyper is run with, named in the header of
+	// each generated file and in the --emit-go-generate directive
+	Args []string
+	// Comments is a JSON object mapping schema paths to descriptions, as in
+	// a --comments-from file
+	Comments []byte
+	// FormatMappers maps string formats to the Go types used for them. They
+	// take precedence over the built-in ones, like time.Time for date-time.
+	FormatMappers map[string]FormatMapping
+	// DecimalType is the Go type used for the decimal and currency formats,
+	// given as for NewFormatMapping; default is float64
+	DecimalType string
+
+	EnumNaming    string // type-value (default), value, or upper-snake
+	NestedNames   string // leaf (default) or path
+	TypeOrder     string // alpha (default), definition, or dependency
+	MergeValues   string // nonzero (default) or always
+	NullType      string // none (default) or sql
+	TagCase       string // schema (default) or camel
+	Accessors     string // none (default), zero, or commaok
+	NolintLinters string // default is "all"
+	InlineDepth   int
+
+	PrefixRoot       bool
+	PrefixConstants  bool
+	PtrForOmit       bool
+	Goimports        bool
+	Nolint           bool
+	EmitGoGenerate   bool
+	EnumHelpers      bool
+	Aliases          bool
+	CollapseWrappers bool
+	TypesList        bool
+	OutputTest       bool
+	OutputExamples   bool
+	Defaults         bool
+	IsZero           bool
+	Equal            bool
+	Merge            bool
+	NullSlices       bool
+	SplitRW          bool
+	PreserveUnknown  bool
+	FixedArrays      bool
+	UniqueSets       bool
+	FieldDocLinks    bool
+	RedactSecrets    bool
+	Verbose          bool
+	Strict           bool
+	Validate         bool
+	ValidateAll      bool
+	WrapErrors       bool
+	ValidatorTags    bool
+	ProtoTags        bool
+	// Infer treats the schema as JSON samples, in an array or one after
+	// another, and generates types from a schema inferred from them
+	Infer bool
+}
+
+// FormatMapping is the Go type used for values of a format.
+type FormatMapping struct {
+	// GoType is the type as it's written in the generated code, like
+	// "mypkg.Color" or "string".
+	GoType string
+	// Import is the import path of the package of GoType, if it's in one.
+	// It's needed when the package name isn't the last element of the path.
+	Import string
+}
+
+// NewFormatMapping returns the mapping to goType, which is either a
+// predeclared type or a package-qualified type like "net/netip.Addr". The
+// package name is taken to be the last element of the import path.
+func NewFormatMapping(goType string) FormatMapping {
+	dot := strings.LastIndex(goType, ".")
+	if dot < 0 {
+		return FormatMapping{GoType: goType}
+	}
+
+	pkgPath, typeName := goType[:dot], goType[dot+1:]
+	pkgName := pkgPath[strings.LastIndex(pkgPath, "/")+1:]
+	return FormatMapping{GoType: pkgName + "." + typeName, Import: pkgPath}
+}
+
+// options are the settings for the types being generated. The variables below
+// point into them, so they read like the flags they're set from.
+var options = defaultOptions()
+
+var (
+	packageName      = &options.PackageName
+	rootTypeName     = &options.RootTypeName
+	typeNamesPrefix  = &options.Prefix
+	schemaVersion    = &options.SchemaVersion
+	schemaFile       = &options.SchemaFile
+	prefixRoot       = &options.PrefixRoot
+	prefixConstants  = &options.PrefixConstants
+	ptrForOmit       = &options.PtrForOmit
+	runGoimports     = &options.Goimports
+	nolint           = &options.Nolint
+	nolintLinters    = &options.NolintLinters
+	emitGoGenerate   = &options.EmitGoGenerate
+	enumNaming       = &options.EnumNaming
+	enumHelpers      = &options.EnumHelpers
+	aliases          = &options.Aliases
+	collapseWrappers = &options.CollapseWrappers
+	nestedNames      = &options.NestedNames
+	typeOrder        = &options.TypeOrder
+	typesList        = &options.TypesList
+	outputTest       = &options.OutputTest
+	outputExamples   = &options.OutputExamples
+	defaults         = &options.Defaults
+	isZero           = &options.IsZero
+	equalMethods     = &options.Equal
+	merge            = &options.Merge
+	mergeValues      = &options.MergeValues
+	nullSlices       = &options.NullSlices
+	nullType         = &options.NullType
+	splitRW          = &options.SplitRW
+	preserveUnknown  = &options.PreserveUnknown
+	tagCase          = &options.TagCase
+	fixedArrays      = &options.FixedArrays
+	uniqueSets       = &options.UniqueSets
+	inlineDepth      = &options.InlineDepth
+	fieldDocLinks    = &options.FieldDocLinks
+	accessors        = &options.Accessors
+	redactSecrets    = &options.RedactSecrets
+	verbose          = &options.Verbose
+	strict           = &options.Strict
+	validate         = &options.Validate
+	validateAll      = &options.ValidateAll
+	wrapErrors       = &options.WrapErrors
+	validatorTags    = &options.ValidatorTags
+	protoTags        = &options.ProtoTags
+)
+
+// defaultOptions returns the options with the defaults of the flags.
+func defaultOptions() Options {
+	var o Options
+	o.setDefaults()
+	return o
+}
+
+// setDefaults sets the options that are left empty to their defaults.
+func (o *Options) setDefaults() {
+	setDefault := func(opt *string, value string) {
+		if *opt == "" {
+			*opt = value
+		}
+	}
+	setDefault(&o.PackageName, "main")
+	setDefault(&o.NolintLinters, "all")
+	setDefault(&o.EnumNaming, enumNamingTypeValue)
+	setDefault(&o.NestedNames, nestedNamesLeaf)
+	setDefault(&o.TypeOrder, typeOrderAlpha)
+	setDefault(&o.MergeValues, mergeValuesNonZero)
+	setDefault(&o.NullType, nullTypeNone)
+	setDefault(&o.TagCase, tagCaseSchema)
+	setDefault(&o.Accessors, accessorsNone)
+	if o.ValidateAll {
+		o.Validate = true
+	}
+}
+
+// check returns an error if an option that takes one of a set of values has
+// another.
+func (o *Options) check() error {
+	enums := []struct {
+		name, value string
+		allowed     []string
+	}{
+		{"SchemaVersion", o.SchemaVersion, append([]string{""}, schemaVersions...)},
+		{"EnumNaming", o.EnumNaming, []string{enumNamingTypeValue, enumNamingValue, enumNamingUpperSnake}},
+		{"NestedNames", o.NestedNames, []string{nestedNamesLeaf, nestedNamesPath}},
+		{"TypeOrder", o.TypeOrder, []string{typeOrderAlpha, typeOrderDefinition, typeOrderDependency}},
+		{"MergeValues", o.MergeValues, []string{mergeValuesNonZero, mergeValuesAlways}},
+		{"NullType", o.NullType, []string{nullTypeNone, nullTypeSQL}},
+		{"TagCase", o.TagCase, []string{tagCaseSchema, tagCaseCamel}},
+		{"Accessors", o.Accessors, []string{accessorsNone, accessorsZero, accessorsCommaOK}},
+	}
+	for _, enum := range enums {
+		valid := false
+		for _, allowed := range enum.allowed {
+			valid = valid || enum.value == allowed
+		}
+		if !valid {
+			return fmt.Errorf("invalid %s %q", enum.name, enum.value)
+		}
+	}
+	return nil
+}
+
+// formatMappers are the format mappings of the run in progress: the
+// FormatMappers of options, along with DecimalType's.
+var formatMappers map[string]FormatMapping
+
+// setFormatMappers sets formatMappers from options.
+func setFormatMappers() {
+	formatMappers = make(map[string]FormatMapping)
+	if options.DecimalType != "" {
+		for _, format := range decimalFormats {
+			formatMappers[format] = NewFormatMapping(options.DecimalType)
+		}
+	}
+	for format, m := range options.FormatMappers {
+		formatMappers[format] = m
+	}
+}
+
+// formatMapping returns the mapping registered for format, if any, and makes
+// sure the package of its type is imported where it's used.
+func formatMapping(format string) (FormatMapping, bool) {
+	m, ok := formatMappers[format]
+	if !ok {
+		return m, false
+	}
+	if dot := strings.LastIndex(m.GoType, "."); dot >= 0 && m.Import != "" {
+		importPaths[m.GoType[:dot]] = m.Import
+	}
+	return m, true
+}
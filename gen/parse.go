@@ -0,0 +1,214 @@
+package gen
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// GoType is a type generated from a schema, for tools that render or inspect
+// the types themselves rather than using the generated source.
+type GoType struct {
+	// Name is the name of the type
+	Name string
+	// Path is the JSON pointer to the schema the type was generated from
+	Path string
+	// Type is the Go type that the type is declared as, like "struct",
+	// "string" or "[]Address"
+	Type string
+	// Comment is the doc comment of the type, without comment markers
+	Comment string
+	// Alias is true if the type is declared as an alias of Type
+	Alias bool
+	// Deprecated is true if the schema is marked deprecated
+	Deprecated bool
+	// Nullable is true if the schema allows null
+	Nullable bool
+	// EnumValues holds the values of an enum type
+	EnumValues []string
+	// Fields holds the fields of a struct type
+	Fields []StructField
+}
+
+// StructField is a field of a generated struct type.
+type StructField struct {
+	// Name is the name of the field
+	Name string
+	// PropertyName is the name of the property in the schema
+	PropertyName string
+	// JSONName is the key of the property in the field's json tag
+	JSONName string
+	// Type is the Go type of the field, like "string", "*Address" or
+	// "map[string]Label", or the name of the type for an embedded field
+	Type string
+	// Pointer is true if Type is a pointer that makes the field optional
+	Pointer bool
+
+	// Required, Nullable, ReadOnly, WriteOnly and Deprecated are as given by
+	// the schema, and Embedded is true for the structs of allOf schemas
+	Required   bool
+	Nullable   bool
+	Embedded   bool
+	ReadOnly   bool
+	WriteOnly  bool
+	Deprecated bool
+}
+
+// resetState clears the package-level processing state between runs.
+func resetState() {
+	types = make(map[string]goType)
+	deferredTypes = make(map[string]deferredType)
+	typesByName = make(stringSetMap)
+	transitiveRefs = make(map[string]string)
+	recursiveAnchors = make(map[string]string)
+	propertySchemas = make(map[string]*metaSchema)
+	encounterOrder = make(map[string]int)
+	origTypeNames = make(map[string]string)
+	importPaths = defaultImportPaths()
+	sidecarComments = make(map[string]sidecarComment)
+	rootPath = "#"
+	pointedDefinitions = stringset.New()
+}
+
+// parse processes the schema s into types, with the root type named by
+// --root-type.
+func parse(s *metaSchema) error {
+	setSchemaVersion(s.Schema)
+	setVocabulary(s.Vocabulary)
+	if _, err := processType(s, *rootTypeName, s.Description, rootPath, ""); err != nil {
+		return err
+	}
+	if err := processDeferred(); err != nil {
+		return err
+	}
+	allocateNames()
+	if *splitRW {
+		if err := splitReadWrite(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exported returns gt as a GoType.
+func (gt goType) exported() GoType {
+	exported := GoType{
+		Name:       gt.Name,
+		Path:       gt.path,
+		Type:       gt.underlyingString(),
+		Comment:    gt.Comment,
+		Alias:      gt.Alias,
+		Deprecated: gt.Deprecated,
+		Nullable:   gt.Nullable,
+		EnumValues: gt.enumValues,
+	}
+	sort.Stable(gt.Fields)
+	for _, sf := range gt.Fields {
+		typeStr, isPtr := sf.typeString()
+		exported.Fields = append(exported.Fields, StructField{
+			Name:         sf.Name,
+			PropertyName: sf.PropertyName,
+			JSONName:     sf.jsonName(),
+			Type:         typeStr,
+			Pointer:      isPtr,
+			Required:     sf.Required,
+			Nullable:     sf.Nullable,
+			Embedded:     sf.Embedded,
+			ReadOnly:     sf.ReadOnly,
+			WriteOnly:    sf.WriteOnly,
+			Deprecated:   sf.Deprecated,
+		})
+	}
+	return exported
+}
+
+// runMu serializes runs, which share the package-level state.
+var runMu sync.Mutex
+
+// run resets the package-level state for opts and processes schema, a JSON
+// schema or JSON samples with Infer, into types.
+func run(schema []byte, opts Options) error {
+	opts.setDefaults()
+	if err := opts.check(); err != nil {
+		return err
+	}
+	options = opts
+	resetState()
+	setFormatMappers()
+
+	if options.Comments != nil {
+		comments, err := loadComments(options.Comments)
+		if err != nil {
+			return fmt.Errorf("parsing comments: %s", err)
+		}
+		sidecarComments = comments
+	}
+
+	if *rootTypeName == "" {
+		name, exported := "root", true
+		if *schemaFile != "" {
+			name = strings.Split(filepath.Base(*schemaFile), ".")[0]
+			exported = *packageName != "main"
+		}
+		*rootTypeName = generateIdentifier(name, exported)
+	}
+
+	var s metaSchema
+	if options.Infer {
+		inferred, err := inferSchema(schema)
+		if err != nil {
+			return fmt.Errorf("inferring schema: %s", err)
+		}
+		return parse(inferred)
+	}
+	doc, err := decodeSchema(schema, &s)
+	if err != nil {
+		return err
+	}
+	if options.RootPointer != "" {
+		return parseFromPointer(doc, options.RootPointer)
+	}
+	return parse(&s)
+}
+
+// Parse returns the types generated from schema, a JSON schema, in the order
+// given by TypeOrder, without rendering them.
+func Parse(schema []byte, opts Options) ([]GoType, error) {
+	runMu.Lock()
+	defer runMu.Unlock()
+	defer func(prev Options) { options = prev }(options)
+
+	if err := run(schema, opts); err != nil {
+		return nil, err
+	}
+
+	typesSlice := make(goTypes, 0, len(types))
+	for _, gt := range types {
+		typesSlice = append(typesSlice, gt)
+	}
+	sort.Stable(typesSlice)
+	orderTypes(typesSlice, *typeOrder)
+
+	parsed := make([]GoType, 0, len(typesSlice))
+	for _, gt := range typesSlice {
+		parsed = append(parsed, gt.exported())
+	}
+	return parsed, nil
+}
+
+// Generate returns the formatted source of each file generated from schema,
+// a JSON schema, keyed by file name.
+func Generate(schema []byte, opts Options) (map[string][]byte, error) {
+	runMu.Lock()
+	defer runMu.Unlock()
+	defer func(prev Options) { options = prev }(options)
+
+	if err := run(schema, opts); err != nil {
+		return nil, err
+	}
+	return renderTypes()
+}
@@ -0,0 +1,182 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// multiPatterns returns the sorted patterns of the patternProperties of s if
+// it has several and no properties of its own, so that it's generated as a
+// struct with a map for each pattern. It returns nil if any of the patterns
+// can't be compiled, leaving s a plain map.
+func multiPatterns(s *metaSchema) []string {
+	if len(s.PatternProperties) < 2 || len(s.Properties) > 0 {
+		return nil
+	}
+
+	patterns, _ := stringset.FromMapKeys(s.PatternProperties)
+	for _, pattern := range patterns.Sorted() {
+		if _, err := regexp.Compile(pattern); err != nil {
+			log.Printf("Ignoring patternProperties: can't compile %q: %s\n", pattern, err)
+			return nil
+		}
+	}
+	return patterns.Sorted()
+}
+
+// singlePattern returns the pattern of the patternProperties of s and its
+// schema if it has only one, and no properties or additionalProperties, so
+// that it's generated as a map of the values matching the pattern, with keys
+// checked against it by Validate. It returns a nil schema otherwise, or if the
+// pattern can't be compiled.
+func singlePattern(s *metaSchema) (string, *metaSchema) {
+	if len(s.PatternProperties) != 1 || len(s.Properties) > 0 {
+		return "", nil
+	}
+	if hasAddl, _ := parseAdditionalProperties(s.AdditionalProperties); hasAddl {
+		return "", nil
+	}
+	for pattern, valueSchema := range s.PatternProperties {
+		if _, err := regexp.Compile(pattern); err != nil {
+			log.Printf("Ignoring patternProperties: can't compile %q: %s\n", pattern, err)
+			return "", nil
+		}
+		valueSchema := valueSchema
+		return pattern, &valueSchema
+	}
+	return "", nil
+}
+
+// isEmptyMetaSchema returns true if s is the empty schema {}, which allows
+// any value, so a map of the values matching a pattern with it is a
+// map[string]interface{}.
+func isEmptyMetaSchema(s *metaSchema) bool {
+	return reflect.DeepEqual(*s, metaSchema{})
+}
+
+// escapePointerToken escapes a key for use in a schema path.
+func escapePointerToken(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// addPatternFields adds to gt, the type of an object with several
+// patternProperties, a map field for the properties matching each pattern and,
+// unless additionalProperties forbids them, one for those matching none. It
+// returns the path or ref of a schema that needs to be processed first if
+// there is one.
+func (gt *goType) addPatternFields(s *metaSchema, patterns []string, path string) (waitingOn string, err error) {
+	fieldNames := stringset.New()
+	addField := func(sf structField, valueSchema *metaSchema, valuePath string) (string, error) {
+		valueName := strings.TrimSuffix(sf.Name, "Properties") + " property"
+		baseName := sf.Name
+		for n := 2; fieldNames.Has(sf.Name); n++ {
+			sf.Name = fmt.Sprintf("%s%d", baseName, n)
+		}
+		fieldNames.Add(sf.Name)
+
+		gotType, err := processAdditionalProperties(valueSchema, valueName, valueSchema.Description, valuePath, path)
+		if err != nil || gotType == "" {
+			return refOrPath(valueSchema, valuePath), err
+		}
+		sf.TypePrefix, sf.TypeRef = "map[string]", gotType
+		gt.Fields = append(gt.Fields, sf)
+		return "", nil
+	}
+
+	patternSchemas := getTypeSchemas(s.PatternProperties)
+	for i, pattern := range patterns {
+		sf := structField{Name: generateFieldName(pattern), Pattern: pattern, Required: true}
+		if sf.Name == "" {
+			sf.Name = fmt.Sprintf("Pattern%d", i+1)
+		}
+		sf.Name += "Properties"
+		valuePath := path + "/patternProperties/" + escapePointerToken(pattern)
+		if waitingOn, err := addField(sf, patternSchemas[pattern], valuePath); waitingOn != "" || err != nil {
+			return waitingOn, err
+		}
+	}
+
+	hasAddlProps, addlPropsSchema := parseAdditionalProperties(s.AdditionalProperties)
+	if s.AdditionalProperties != nil && !hasAddlProps {
+		return "", nil
+	}
+	sf := structField{Name: "AdditionalProperties", Catchall: true, Required: true}
+	if addlPropsSchema == nil {
+		sf.TypePrefix = "map[string]interface{}"
+		gt.Fields = append(gt.Fields, sf)
+		return "", nil
+	}
+	return addField(sf, addlPropsSchema, path+"/additionalProperties")
+}
+
+// printPatternJSON writes the methods that marshal a type with pattern fields
+// as a single object, and that route the properties of an object to the first
+// field, in field order, whose pattern they match when unmarshaling. Properties that match no
+// pattern go to the catch-all field, or are dropped if there isn't one.
+func (gt goType) printPatternJSON(buf *bytes.Buffer, imports stringset.StringSet) {
+	var patternFields []structField
+	var catchall *structField
+	for i, sf := range gt.Fields {
+		if sf.Pattern != "" {
+			patternFields = append(patternFields, sf)
+		} else if sf.Catchall {
+			catchall = &gt.Fields[i]
+		}
+	}
+	if len(patternFields) == 0 {
+		return
+	}
+
+	imports.Add("encoding/json")
+	imports.Add("regexp")
+
+	// fields with a lower precedence are added first, so a property matching
+	// several patterns is marshaled from the field it's unmarshaled into
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "func (t %s) MarshalJSON() ([]byte, error) {\n", gt.Name)
+	buf.WriteString("m := make(map[string]interface{})\n")
+	if catchall != nil {
+		fmt.Fprintf(buf, "for k, v := range t.%s {\nm[k] = v\n}\n", catchall.Name)
+	}
+	for i := len(patternFields) - 1; i >= 0; i-- {
+		fmt.Fprintf(buf, "for k, v := range t.%s {\nm[k] = v\n}\n", patternFields[i].Name)
+	}
+	buf.WriteString("return json.Marshal(m)\n")
+	buf.WriteString("}\n\n")
+
+	var decls bytes.Buffer
+	fmt.Fprintf(buf, "func (t *%s) UnmarshalJSON(data []byte) error {\n", gt.Name)
+	buf.WriteString("var raw map[string]json.RawMessage\n")
+	buf.WriteString("if err := json.Unmarshal(data, &raw); err != nil {\nreturn err\n}\n")
+	fmt.Fprintf(buf, "*t = %s{}\n", gt.Name)
+	buf.WriteString("for k, v := range raw {\n")
+	buf.WriteString("switch {\n")
+	writeCase := func(sf structField) {
+		mapType, _ := sf.typeString()
+		fmt.Fprintf(buf, "var value %s\n", strings.TrimPrefix(mapType, "map[string]"))
+		buf.WriteString("if err := json.Unmarshal(v, &value); err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(buf, "if t.%s == nil {\nt.%s = make(%s)\n}\n", sf.Name, sf.Name, mapType)
+		fmt.Fprintf(buf, "t.%s[k] = value\n", sf.Name)
+	}
+	for _, sf := range patternFields {
+		patternVar := generateIdentifier(gt.Name+" "+sf.Name+" pattern", false)
+		fmt.Fprintf(&decls, "var %s = regexp.MustCompile(%q)\n", patternVar, sf.Pattern)
+		fmt.Fprintf(buf, "case %s.MatchString(k):\n", patternVar)
+		writeCase(sf)
+	}
+	if catchall != nil {
+		buf.WriteString("default:\n")
+		writeCase(*catchall)
+	}
+	buf.WriteString("}\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n\n")
+	buf.Write(decls.Bytes())
+}
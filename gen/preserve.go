@@ -0,0 +1,76 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// preservesUnknown returns true if gt keeps the properties that aren't its
+// fields with --preserve-unknown. Only plain structs do: the methods of an
+// embedded struct would be promoted and take over marshaling the struct that
+// embeds it, and a struct with pattern fields keeps every property already.
+func (gt goType) preservesUnknown() bool {
+	if !*preserveUnknown || gt.TypePrefix != typeStruct || gt.Alias {
+		return false
+	}
+	for _, sf := range gt.Fields {
+		if sf.Embedded || sf.Pattern != "" || sf.Catchall || sf.Variant || sf.Position > 0 {
+			return false
+		}
+	}
+	for _, other := range types {
+		for _, sf := range other.Fields {
+			if sf.Embedded && sf.TypeRef == gt.path {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// printPreserveJSON writes the methods that keep the properties that aren't
+// fields of gt when it's unmarshaled, and add them back when it's marshaled.
+func (gt goType) printPreserveJSON(buf *bytes.Buffer, imports stringset.StringSet) {
+	if !gt.preservesUnknown() {
+		return
+	}
+	imports.Add("encoding/json")
+
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "func (t %s) MarshalJSON() ([]byte, error) {\n", gt.Name)
+	fmt.Fprintf(buf, "type plain %s\n", gt.Name)
+	buf.WriteString("known, err := json.Marshal(plain(t))\n")
+	buf.WriteString("if err != nil || len(t.raw) == 0 {\nreturn known, err\n}\n")
+	buf.WriteString("var fields map[string]json.RawMessage\n")
+	buf.WriteString("if err := json.Unmarshal(known, &fields); err != nil {\nreturn nil, err\n}\n")
+	buf.WriteString("for k, v := range t.raw {\n")
+	buf.WriteString("if _, ok := fields[k]; !ok {\nfields[k] = v\n}\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return json.Marshal(fields)\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "func (t *%s) UnmarshalJSON(data []byte) error {\n", gt.Name)
+	fmt.Fprintf(buf, "type plain %s\n", gt.Name)
+	buf.WriteString("if err := json.Unmarshal(data, (*plain)(t)); err != nil {\nreturn err\n}\n")
+	buf.WriteString("var raw map[string]json.RawMessage\n")
+	buf.WriteString("if err := json.Unmarshal(data, &raw); err != nil {\nreturn err\n}\n")
+	buf.WriteString("for _, k := range []string{")
+	propNames := stringset.New()
+	for _, sf := range gt.Fields {
+		propNames.Add(sf.jsonName())
+	}
+	for i, propName := range propNames.Sorted() {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "%q", propName)
+	}
+	buf.WriteString("} {\n")
+	buf.WriteString("delete(raw, k)\n")
+	buf.WriteString("}\n")
+	buf.WriteString("t.raw = raw\n")
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,77 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numberProtoFields gives each of fields, those of the struct at path, a
+// number for its protobuf tag with --proto-tags. Numbers from x-proto-field
+// are kept, and the other fields are numbered in order with the numbers left
+// over. Embedded and pattern fields aren't numbered.
+func numberProtoFields(fields structFields, path string) error {
+	taken := make(map[int]string)
+	for _, sf := range fields {
+		if sf.ProtoField == 0 {
+			continue
+		}
+		if other, ok := taken[sf.ProtoField]; ok {
+			return fmt.Errorf("%s: properties %q and %q both have x-proto-field %d", path, other, sf.PropertyName, sf.ProtoField)
+		}
+		taken[sf.ProtoField] = sf.PropertyName
+	}
+
+	next := 1
+	for i, sf := range fields {
+		if sf.ProtoField != 0 || sf.Embedded || sf.Pattern != "" || sf.Catchall {
+			continue
+		}
+		for taken[next] != "" {
+			next++
+		}
+		fields[i].ProtoField = next
+		taken[next] = sf.PropertyName
+	}
+	return nil
+}
+
+// protoWireType returns the protobuf wire type of values of the Go type kind.
+func protoWireType(kind string) string {
+	switch kind {
+	case typeInt, typeBool:
+		return "varint"
+	case typeFloat64:
+		return "fixed64"
+	default:
+		return "bytes"
+	}
+}
+
+// protoTag returns the protobuf tag of sf, like
+// protobuf:"bytes,1,opt,name=foo". Repeated numbers are packed, and maps
+// get the tags of their keys and values too.
+func (sf structField) protoTag() string {
+	kind := sf.underlyingKind()
+	elem := structField{TypeRef: sf.TypeRef}.underlyingKind()
+
+	var opts []string
+	switch {
+	case kind == "[]":
+		opts = []string{protoWireType(elem), strconv.Itoa(sf.ProtoField), "rep"}
+		if protoWireType(elem) != "bytes" {
+			opts = append(opts, "packed")
+		}
+	case kind == "map[string]":
+		opts = []string{"bytes", strconv.Itoa(sf.ProtoField), "rep"}
+	default:
+		opts = []string{protoWireType(kind), strconv.Itoa(sf.ProtoField), "opt"}
+	}
+	opts = append(opts, "name="+sf.PropertyName)
+
+	tag := `protobuf:"` + strings.Join(opts, ",") + `"`
+	if kind == "map[string]" {
+		tag += ` protobuf_key:"bytes,1,opt,name=key" protobuf_val:"` + protoWireType(elem) + `,2,opt,name=value"`
+	}
+	return tag
+}
@@ -0,0 +1,111 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// requestSuffix is added to the path and name of a struct's request
+	// variant.
+	requestSuffix = "Request"
+	// responseSuffix is added to the path and name of a struct's response
+	// variant.
+	responseSuffix = "Response"
+)
+
+// isVariant returns true if path is that of a variant added by addVariants.
+func isVariant(path string) bool {
+	return strings.Contains(path, "@")
+}
+
+// needsVariant returns the paths of the struct types that need a variant
+// without some of their fields: those with a field that omit returns true for,
+// and those with a field, possibly a slice or map, of a type that needs one.
+// Variants don't get variants of their own.
+func needsVariant(omit func(structField) bool) map[string]bool {
+	needed := make(map[string]bool)
+	for path, gt := range types {
+		if gt.TypePrefix != typeStruct || isVariant(path) {
+			continue
+		}
+		for _, sf := range gt.Fields {
+			if omit(sf) {
+				needed[path] = true
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for path, gt := range types {
+			if needed[path] || gt.TypePrefix != typeStruct || isVariant(path) {
+				continue
+			}
+			for _, sf := range gt.Fields {
+				if needed[sf.TypeRef] {
+					needed[path] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return needed
+}
+
+// addVariants adds a variant of each struct type that needs one, named with
+// suffix, without the fields that omit returns true for. Since a variant is
+// generated from the fields that are left, the properties it omits aren't
+// required by its tags or checked by its Validate method either. Fields of
+// types that have a variant refer to it instead.
+func addVariants(suffix, desc string, omit func(structField) bool) error {
+	needed := needsVariant(omit)
+	paths := make([]string, 0, len(needed))
+	for path := range needed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		gt := types[path]
+		variant := gt
+		variant.Name = gt.Name + suffix
+		variant.path = path + "@" + suffix
+		if typesByName.has(variant.Name) {
+			return fmt.Errorf("%s: can't name the variant without %s properties %s, another type has that name", path, desc, variant.Name)
+		}
+		variant.Comment = fmt.Sprintf("%s is %s without its %s properties.", variant.Name, gt.Name, desc)
+
+		variant.Fields = nil
+		for _, sf := range gt.Fields {
+			if omit(sf) {
+				continue
+			}
+			if needed[sf.TypeRef] {
+				sf.TypeRef += "@" + suffix
+			}
+			variant.Fields = append(variant.Fields, sf)
+		}
+
+		types[variant.path] = variant
+		typesByName.addTo(variant.Name, variant.path)
+	}
+	return nil
+}
+
+// splitReadWrite adds the request and response variants of the struct types
+// with --split-rw.
+func splitReadWrite() error {
+	err := addVariants(requestSuffix, "readOnly", func(sf structField) bool {
+		return sf.ReadOnly
+	})
+	if err != nil {
+		return err
+	}
+	return addVariants(responseSuffix, "writeOnly", func(sf structField) bool {
+		return sf.WriteOnly
+	})
+}
@@ -0,0 +1,63 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+const redacted = "[REDACTED]"
+
+// hasSecrets returns true if any of gt's fields are secret.
+func (gt goType) hasSecrets() bool {
+	for _, sf := range gt.Fields {
+		if sf.Secret {
+			return true
+		}
+	}
+	return false
+}
+
+// printString writes a String method for gt that prints its fields like %+v
+// but with the values of secret fields redacted, so logging a value with %v
+// doesn't leak them.
+func (gt goType) printString(buf *bytes.Buffer, imports stringset.StringSet) {
+	if gt.TypePrefix != typeStruct || !gt.hasSecrets() {
+		return
+	}
+
+	for _, sf := range gt.Fields {
+		if sf.Name == "String" {
+			return
+		}
+	}
+
+	sort.Stable(gt.Fields)
+	var format []string
+	var args []string
+	for _, sf := range gt.Fields {
+		name := sf.Name
+		if sf.Embedded {
+			name = types[sf.TypeRef].Name
+		}
+		if sf.Secret {
+			format = append(format, name+":"+redacted)
+			continue
+		}
+		format = append(format, name+":%v")
+		args = append(args, "t."+name)
+	}
+
+	imports.Add("fmt")
+	buf.WriteString("\n")
+	buf.WriteString("// String returns t with the values of its secret fields redacted.\n")
+	buf.WriteString(fmt.Sprintf("func (t %s) String() string {\n", gt.Name))
+	buf.WriteString(fmt.Sprintf("return fmt.Sprintf(%q", "{"+strings.Join(format, " ")+"}"))
+	for _, arg := range args {
+		buf.WriteString(", " + arg)
+	}
+	buf.WriteString(")\n}\n")
+}
@@ -0,0 +1,110 @@
+package gen
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// propertyNames adds the property names of the fields of gt, including those
+// of the structs it embeds, to names.
+func (gt goType) propertyNames(names stringset.StringSet) {
+	for _, sf := range gt.Fields {
+		if sf.Embedded {
+			types[sf.TypeRef].propertyNames(names)
+			continue
+		}
+		names.Add(sf.PropertyName)
+	}
+}
+
+// allOfRequired returns the names required by s or by any of its allOf
+// schemas, since they all describe the same object, whichever of them has the
+// property. The allOf schemas that are $refs are left out; their types are
+// shared, so they can't depend on where they're used.
+func allOfRequired(s *metaSchema) stringset.StringSet {
+	required := stringset.New()
+	for _, req := range s.Required {
+		required.Add(string(req))
+	}
+	for i := range s.AllOf {
+		if s.AllOf[i].Ref != "" {
+			continue
+		}
+		for _, req := range allOfRequired(&s.AllOf[i]).Slice() {
+			required.Add(req)
+		}
+	}
+	return required
+}
+
+// definedProperties returns the names of the properties of s, including those
+// of its allOf schemas that aren't $refs.
+func definedProperties(s *metaSchema) stringset.StringSet {
+	names, _ := stringset.FromMapKeys(s.Properties)
+	for i := range s.AllOf {
+		if s.AllOf[i].Ref != "" {
+			continue
+		}
+		for _, name := range definedProperties(&s.AllOf[i]).Slice() {
+			names.Add(name)
+		}
+	}
+	return names
+}
+
+// requireDefined returns the required names of s, an allOf schema, together
+// with those of required that are properties of s, so a property is required
+// even if it's another allOf schema or the parent that requires it.
+func requireDefined(s *metaSchema, required stringset.StringSet) metaStringArray {
+	own := stringset.New()
+	for _, req := range s.Required {
+		own.Add(string(req))
+	}
+	reqs := append(metaStringArray(nil), s.Required...)
+	defined := definedProperties(s)
+	for _, req := range required.Sorted() {
+		if defined.Has(req) && !own.Has(req) {
+			reqs = append(reqs, metaStringArrayItem(req))
+		}
+	}
+	return reqs
+}
+
+// checkRequired warns about the names in required that aren't properties of
+// gt, the struct generated from s, and with --strict returns an error instead.
+// Names matching one of its patternProperties are properties too.
+func checkRequired(gt goType, s *metaSchema, required stringset.StringSet) error {
+	names := stringset.New()
+	gt.propertyNames(names)
+
+	var missing []string
+	for _, req := range required.Sorted() {
+		if names.Has(req) {
+			continue
+		}
+		matched := false
+		for pattern := range s.PatternProperties {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(req) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			missing = append(missing, fmt.Sprintf("%q", req))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%s: required %s not in properties", gt.path, strings.Join(missing, ", "))
+	if *strict {
+		return fmt.Errorf("%s", msg)
+	}
+	log.Println("Warning:", msg)
+	return nil
+}
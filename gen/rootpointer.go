@@ -0,0 +1,145 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// rootPath is the path of the schema that the root type is generated from.
+// It's "#" unless --root-pointer selects a schema inside the document.
+var rootPath = "#"
+
+// pointedDefinitions holds the paths of the schemas outside the root that
+// --root-pointer pulled in because the root refers to them. They're treated
+// like definitions.
+var pointedDefinitions = stringset.New()
+
+// normalizePointer returns the JSON pointer ptr in the "#/a/b" form used for
+// schema paths, accepting it with or without the leading "#".
+func normalizePointer(ptr string) string {
+	ptr = strings.TrimPrefix(ptr, "#")
+	ptr = strings.TrimSuffix(ptr, "/")
+	return "#" + ptr
+}
+
+// lookupPointer returns the value at the JSON pointer ptr in doc.
+func lookupPointer(doc interface{}, ptr string) (interface{}, error) {
+	node := doc
+	ptr = strings.TrimPrefix(normalizePointer(ptr), "#")
+	if ptr == "" {
+		return node, nil
+	}
+
+	for _, token := range strings.Split(ptr[1:], "/") {
+		token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%s: no %q", ptr, token)
+			}
+			node = child
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("%s: no index %q", ptr, token)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%s: %q isn't an object or array", ptr, token)
+		}
+	}
+	return node, nil
+}
+
+// collectRefs adds to refs the local $refs in node and, in turn, those in the
+// schemas they refer to.
+func collectRefs(doc, node interface{}, refs stringset.StringSet) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok && strings.HasPrefix(ref, "#") && !refs.Has(ref) {
+			refs.Add(ref)
+			target, err := lookupPointer(doc, ref)
+			if err != nil {
+				return err
+			}
+			if err := collectRefs(doc, target, refs); err != nil {
+				return err
+			}
+		}
+		for key, child := range n {
+			if key == "$ref" {
+				continue
+			}
+			if err := collectRefs(doc, child, refs); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if err := collectRefs(doc, child, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// within returns true if path is that of base or of a schema inside it.
+func within(path, base string) bool {
+	return path == base || strings.HasPrefix(path, base+"/")
+}
+
+// parseFromPointer processes the schema at the JSON pointer ptr in doc into
+// the root type rather than doc itself, along with the schemas it refers to.
+// Nothing else in doc is processed.
+func parseFromPointer(doc interface{}, ptr string) error {
+	rootPath = normalizePointer(ptr)
+	node, err := lookupPointer(doc, rootPath)
+	if err != nil {
+		return err
+	}
+
+	refs := stringset.New()
+	if err := collectRefs(doc, node, refs); err != nil {
+		return err
+	}
+
+	// schemas inside the root or inside another referenced schema are
+	// processed along with it
+	sortedRefs := refs.Sorted()
+	for _, ref := range sortedRefs {
+		if within(ref, rootPath) || within(rootPath, ref) {
+			continue
+		}
+		nested := false
+		for _, other := range sortedRefs {
+			if other != ref && within(ref, other) {
+				nested = true
+				break
+			}
+		}
+		if nested {
+			continue
+		}
+
+		target, _ := lookupPointer(doc, ref)
+		s := getTypeSchema(target)
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		pointedDefinitions.Add(ref)
+		if _, err := processType(s, name, s.Description, ref, parentSchemaPath(ref)); err != nil {
+			return err
+		}
+	}
+
+	s := getTypeSchema(node)
+	if s.Schema == "" {
+		if root, ok := doc.(map[string]interface{}); ok {
+			s.Schema, _ = root["$schema"].(string)
+		}
+	}
+	return parse(s)
+}
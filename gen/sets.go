@@ -0,0 +1,86 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// setKeyType returns the Go type of the items of an array with the schema s
+// if it's generated as a set with --unique-sets: it has uniqueItems and its
+// items are plain strings or numbers, without an enum or constraints that
+// would need a type of their own.
+func setKeyType(s *metaSchema) (string, bool) {
+	if !*uniqueSets || !s.UniqueItems {
+		return "", false
+	}
+	items, ok := s.Items.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	itemSchema := getTypeSchema(items)
+	if itemSchema.Ref != "" {
+		return "", false
+	}
+	jsonType, _ := itemSchema.Type.(string)
+	ts := getTypeString(jsonType, itemSchema)
+	switch ts {
+	case typeString, typeInt, typeFloat64:
+	default:
+		return "", false
+	}
+	if hasEnum(itemSchema, ts) || getConstraints(itemSchema).appliesTo(ts) {
+		return "", false
+	}
+	return ts, true
+}
+
+// printSetMethods writes the methods of gt, a set: Add and Has, and the
+// methods that marshal it as a JSON array of its items, sorted so the output
+// is deterministic, and that unmarshal an array into it, failing if an item
+// is repeated, as uniqueItems doesn't allow.
+func (gt goType) printSetMethods(buf *bytes.Buffer, imports stringset.StringSet) {
+	if !gt.set {
+		return
+	}
+	keyType := strings.TrimSuffix(strings.TrimPrefix(gt.TypePrefix, "map["), "]struct{}")
+	imports.Add("encoding/json")
+	imports.Add("fmt")
+	imports.Add("sort")
+
+	buf.WriteString("\n")
+	buf.WriteString("// Add adds v to the set, making it if it's nil.\n")
+	fmt.Fprintf(buf, "func (t *%s) Add(v %s) {\n", gt.Name, keyType)
+	fmt.Fprintf(buf, "if *t == nil {\n*t = make(%s)\n}\n", gt.Name)
+	buf.WriteString("(*t)[v] = struct{}{}\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// Has returns true if v is in the set.\n")
+	fmt.Fprintf(buf, "func (t %s) Has(v %s) bool {\n", gt.Name, keyType)
+	buf.WriteString("_, ok := t[v]\nreturn ok\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "func (t %s) MarshalJSON() ([]byte, error) {\n", gt.Name)
+	buf.WriteString("if t == nil {\nreturn []byte(\"null\"), nil\n}\n")
+	fmt.Fprintf(buf, "items := make([]%s, 0, len(t))\n", keyType)
+	buf.WriteString("for v := range t {\nitems = append(items, v)\n}\n")
+	buf.WriteString("sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })\n")
+	buf.WriteString("return json.Marshal(items)\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "func (t *%s) UnmarshalJSON(data []byte) error {\n", gt.Name)
+	fmt.Fprintf(buf, "var items []%s\n", keyType)
+	buf.WriteString("if err := json.Unmarshal(data, &items); err != nil {\nreturn err\n}\n")
+	buf.WriteString("if items == nil {\n*t = nil\nreturn nil\n}\n")
+	fmt.Fprintf(buf, "*t = make(%s, len(items))\n", gt.Name)
+	buf.WriteString("for _, v := range items {\n")
+	buf.WriteString("if _, ok := (*t)[v]; ok {\n")
+	fmt.Fprintf(buf, "return fmt.Errorf(%q, v)\n", "duplicate item %v")
+	buf.WriteString("}\n")
+	buf.WriteString("(*t)[v] = struct{}{}\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,99 @@
+package gen
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// The values of --null-type.
+const (
+	nullTypeNone = "none"
+	nullTypeSQL  = "sql"
+)
+
+// sqlNullTypes maps scalar Go types to the database/sql types wrapped for them
+// when they're nullable with --null-type=sql.
+var sqlNullTypes = map[string]string{
+	typeString:  "sql.NullString",
+	typeInt:     "sql.NullInt64",
+	typeFloat64: "sql.NullFloat64",
+	typeBool:    "sql.NullBool",
+	typeTime:    "sql.NullTime",
+}
+
+// sqlNullTypeName returns the name of the wrapper generated for sqlType, one
+// of the sqlNullTypes, like NullInt64 for sql.NullInt64.
+func sqlNullTypeName(sqlType string) string {
+	return generateIdentifier(strings.TrimPrefix(sqlType, "sql."), *packageName != "main")
+}
+
+// isSQLNullType returns true if typePrefix is the wrapper of one of the
+// sqlNullTypes, which keep track of null themselves, so fields of them are
+// never pointers.
+func isSQLNullType(typePrefix string) bool {
+	for _, sqlType := range sqlNullTypes {
+		if typePrefix == sqlNullTypeName(sqlType) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedSQLNullTypes returns the sqlNullTypes whose wrappers are the types of
+// fields of typesSlice, sorted.
+func usedSQLNullTypes(typesSlice goTypes) []string {
+	var used []string
+	for _, sqlType := range sqlNullTypes {
+		name := sqlNullTypeName(sqlType)
+	search:
+		for _, gt := range typesSlice {
+			for _, sf := range gt.Fields {
+				if sf.TypePrefix == name {
+					used = append(used, sqlType)
+					break search
+				}
+			}
+		}
+	}
+	sort.Strings(used)
+	return used
+}
+
+// printSQLNull returns the file name and body of a file declaring the wrapper
+// of sqlType, which embeds it so that it can still be scanned from and written
+// to a database, and is marshaled to JSON as its value or null, which
+// encoding/json doesn't do for the database/sql types.
+func printSQLNull(sqlType string) (fileName string, body []byte) {
+	name := sqlNullTypeName(sqlType)
+	embedded := strings.TrimPrefix(sqlType, "sql.")
+	valueField := strings.TrimPrefix(embedded, "Null")
+
+	var buf bytes.Buffer
+	buf.WriteString("// " + name + " is a " + sqlType + " that is marshaled to JSON as its value, or\n")
+	buf.WriteString("// null if it isn't valid.\n")
+	buf.WriteString("type " + name + " struct {\n")
+	buf.WriteString(sqlType + "\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("func (n " + name + ") MarshalJSON() ([]byte, error) {\n")
+	buf.WriteString("if !n.Valid {\n")
+	buf.WriteString("return []byte(\"null\"), nil\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return json.Marshal(n." + valueField + ")\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("func (n *" + name + ") UnmarshalJSON(data []byte) error {\n")
+	buf.WriteString("n." + embedded + " = " + sqlType + "{}\n")
+	buf.WriteString("if string(data) == \"null\" {\n")
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+	buf.WriteString("if err := json.Unmarshal(data, &n." + valueField + "); err != nil {\n")
+	buf.WriteString("return err\n")
+	buf.WriteString("}\n")
+	buf.WriteString("n.Valid = true\n")
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+
+	return name + ".go", buf.Bytes()
+}
@@ -0,0 +1,59 @@
+package gen
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	tagCaseSchema = "schema"
+	tagCaseCamel  = "camel"
+)
+
+// acronymWordRe finds an acronym followed by a word, like the "LS" and "Ver"
+// of "TLSVersion", which camelCaseToWords leaves together.
+var acronymWordRe = regexp.MustCompile(`(\p{Lu})(\p{Lu}\p{Ll})`)
+
+// lowerCamel converts a property name like "user_name", "UserName" or
+// "userID" to lowerCamelCase, like "userName" and "userId". Acronyms are
+// treated as words, so "URL" becomes "url".
+func lowerCamel(name string) string {
+	spaced := camelCaseToWords(acronymWordRe.ReplaceAllString(dashedToWords(name), "$1 $2"))
+	words := strings.Fields(spaced)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = strings.Title(word)
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+// tagName returns the key of a property in the JSON tag of its field, which
+// is the property name unless --tag-case changes it.
+func tagName(propName string) string {
+	if *tagCase == tagCaseCamel {
+		if camel := lowerCamel(propName); camel != "" {
+			return camel
+		}
+	}
+	return propName
+}
+
+// jsonName returns the key sf is marshaled as.
+func (sf structField) jsonName() string {
+	if sf.JSONName != "" {
+		return sf.JSONName
+	}
+	return sf.PropertyName
+}
+
+// tag returns the JSON tag of sf, with omitOption if it isn't empty.
+func (sf structField) tag(omitOption string) string {
+	tag := `json:"` + sf.jsonName()
+	if omitOption != "" {
+		tag += "," + omitOption
+	}
+	return tag + `"`
+}
@@ -0,0 +1,61 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// printTests returns the file name and body of a test file for the generated
+// types, a starting point that users can extend. It checks that the zero value
+// of each type marshals, and that each example in the schemas unmarshals into
+// its type and marshals again.
+func printTests(typesSlice goTypes) (fileName string, body []byte) {
+	var buf bytes.Buffer
+	buf.WriteString("func TestZeroValuesMarshal(t *testing.T) {\n")
+	buf.WriteString("values := map[string]interface{}{\n")
+	for _, gt := range typesSlice {
+		fmt.Fprintf(&buf, "%q: new(%s),\n", gt.Name, gt.Name)
+	}
+	buf.WriteString("}\n")
+	buf.WriteString("for name, v := range values {\n")
+	buf.WriteString("if _, err := json.Marshal(v); err != nil {\n")
+	buf.WriteString("t.Errorf(\"%s: %s\", name, err)\n")
+	buf.WriteString("}\n")
+	buf.WriteString("}\n")
+	buf.WriteString("}\n")
+
+	var examples bytes.Buffer
+	for _, gt := range typesSlice {
+		for _, example := range gt.examples {
+			exampleJSON, err := json.Marshal(example)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&examples, "{%q, %q, func() interface{} { return new(%s) }},\n", gt.Name, exampleJSON, gt.Name)
+		}
+	}
+	if examples.Len() > 0 {
+		buf.WriteString("\nfunc TestExamplesRoundTrip(t *testing.T) {\n")
+		buf.WriteString("tests := []struct {\n")
+		buf.WriteString("name    string\n")
+		buf.WriteString("example string\n")
+		buf.WriteString("value   func() interface{}\n")
+		buf.WriteString("}{\n")
+		buf.Write(examples.Bytes())
+		buf.WriteString("}\n")
+		buf.WriteString("for _, tt := range tests {\n")
+		buf.WriteString("v := tt.value()\n")
+		buf.WriteString("if err := json.Unmarshal([]byte(tt.example), v); err != nil {\n")
+		buf.WriteString("t.Errorf(\"%s: unmarshaling %s: %s\", tt.name, tt.example, err)\n")
+		buf.WriteString("continue\n")
+		buf.WriteString("}\n")
+		buf.WriteString("if _, err := json.Marshal(v); err != nil {\n")
+		buf.WriteString("t.Errorf(\"%s: marshaling %s: %s\", tt.name, tt.example, err)\n")
+		buf.WriteString("}\n")
+		buf.WriteString("}\n")
+		buf.WriteString("}\n")
+	}
+
+	return *rootTypeName + "_schematype_test.go", buf.Bytes()
+}
@@ -0,0 +1,240 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// sameItemSchema returns the schema of every item of a tuple, given by the
+// list of items, if they all have the same one, so that it's generated as a
+// Go array of that length.
+func sameItemSchema(items []interface{}) (*metaSchema, bool) {
+	first, _ := json.Marshal(items[0])
+	for _, item := range items[1:] {
+		other, _ := json.Marshal(item)
+		if !bytes.Equal(first, other) {
+			return nil, false
+		}
+	}
+	return getTypeSchema(items[0]), true
+}
+
+// setFixedArray makes gt, the type of a tuple whose items all have the schema
+// itemSchema, a Go array of n of them. A plain scalar doesn't need a type of
+// its own. It returns the path of the item type if it needs to be processed
+// first.
+func (gt *goType) setFixedArray(itemSchema *metaSchema, n int, path string) (waitingOn string, err error) {
+	prefix := fmt.Sprintf("[%d]", n)
+	jsonType, _ := itemSchema.Type.(string)
+	ts := getTypeString(jsonType, itemSchema)
+	if itemSchema.Ref == "" && scalarJSONType(ts) != "" && !hasEnum(itemSchema, ts) && !getConstraints(itemSchema).appliesTo(ts) {
+		gt.TypePrefix = prefix + ts
+		return "", nil
+	}
+
+	itemPath := path + "/items/0"
+	gotType, err := processType(itemSchema, singularize(gt.origTypeName), itemSchema.Description, itemPath, path)
+	if err != nil || gotType == "" {
+		return itemPath, err
+	}
+	gt.TypePrefix = prefix
+	gt.TypeRef = gotType
+	return "", nil
+}
+
+// setItemType sets the type of sf, a field of gt, a tuple, holding an item
+// with the schema itemSchema at itemPath, which refers to the type at typeRef
+// if it's a $ref. A type generated for the item is named name. It returns
+// itemPath if the item needs to be processed first.
+func (gt *goType) setItemType(sf *structField, itemSchema *metaSchema, typeRef, name, itemPath, path string) (waitingOn string, err error) {
+	jsonType, _ := itemSchema.Type.(string)
+	ts := getTypeString(jsonType, itemSchema)
+	switch {
+	case typeRef != "":
+		sf.TypeRef = typeRef
+	case isEmptyMetaSchema(itemSchema):
+		sf.TypePrefix = typeEmptyInterface
+	case scalarJSONType(ts) != "" && !hasEnum(itemSchema, ts):
+		// a plain scalar doesn't need a type of its own
+		sf.TypePrefix = ts
+		sf.constraints = getConstraints(itemSchema)
+	default:
+		gotType, err := processType(itemSchema, name, itemSchema.Description, itemPath, path)
+		if err != nil || gotType == "" {
+			return itemPath, err
+		}
+		sf.TypeRef = gotType
+	}
+	return "", nil
+}
+
+// resolveItemRef returns the path of the type that itemSchema, an item of a
+// tuple, refers to if it's a $ref, and whether it's been processed yet.
+func resolveItemRef(itemSchema *metaSchema, path string) (string, bool) {
+	resolveRecursiveRef(itemSchema, path)
+	if itemSchema.Ref == "" {
+		return "", true
+	}
+	return resolveRef(itemSchema.Ref)
+}
+
+// tupleAdditionalItems returns whether s, a tuple, allows items after those
+// with positions, and their schema if they have one. Without additionalItems
+// any items may follow, the same as with true.
+func tupleAdditionalItems(s *metaSchema) (hasAddlItems bool, addlItemsSchema *metaSchema) {
+	if s.AdditionalItems == nil {
+		return true, nil
+	}
+	return parseAdditionalProperties(s.AdditionalItems)
+}
+
+// addTupleFields adds to gt, the type of a tuple, a field for the item at each
+// position, named after the item's title or the type it refers to, or
+// numbered. The items after the first minItems may be missing, so their
+// fields are pointers. Unless additionalItems is false, the items after
+// those go in a slice field after them, so they aren't lost. It returns the ref or path of an item that needs
+// to be processed first if there is one.
+func (gt *goType) addTupleFields(s *metaSchema, items []interface{}, path string) (waitingOn string, err error) {
+	minItems := 0
+	if n := positiveInt(s.MinItems); n != nil {
+		minItems = *n
+	}
+
+	fieldNames := stringset.New()
+	for i, item := range items {
+		itemSchema := getTypeSchema(item)
+		itemPath := fmt.Sprintf("%s/items/%d", path, i)
+		sf := structField{Position: i + 1, Required: i < minItems}
+
+		typeRef, ok := resolveItemRef(itemSchema, path)
+		if !ok {
+			return itemSchema.Ref, nil
+		}
+
+		var name string
+		switch {
+		case itemSchema.Title != "":
+			name = itemSchema.Title
+		case typeRef != "":
+			name = types[typeRef].origTypeName
+		}
+		if sf.Name = generateFieldName(name); sf.Name == "" {
+			sf.Name = fmt.Sprintf("Item%d", i+1)
+		}
+		baseName := sf.Name
+		for n := 2; fieldNames.Has(sf.Name); n++ {
+			sf.Name = fmt.Sprintf("%s%d", baseName, n)
+		}
+		fieldNames.Add(sf.Name)
+
+		if waitingOn, err := gt.setItemType(&sf, itemSchema, typeRef, gt.origTypeName+" "+sf.Name, itemPath, path); waitingOn != "" || err != nil {
+			return waitingOn, err
+		}
+		gt.Fields = append(gt.Fields, sf)
+	}
+
+	hasAddlItems, addlItemsSchema := tupleAdditionalItems(s)
+	if hasAddlItems {
+		sf := structField{Name: "AdditionalItems", Position: len(items) + 1, RestItems: true}
+		for n := 2; fieldNames.Has(sf.Name); n++ {
+			sf.Name = fmt.Sprintf("AdditionalItems%d", n)
+		}
+		if addlItemsSchema == nil {
+			sf.TypePrefix = typeEmptyInterface
+		} else {
+			typeRef, ok := resolveItemRef(addlItemsSchema, path)
+			if !ok {
+				return addlItemsSchema.Ref, nil
+			}
+			if waitingOn, err := gt.setItemType(&sf, addlItemsSchema, typeRef, gt.origTypeName+" additional item", path+"/additionalItems", path); waitingOn != "" || err != nil {
+				return waitingOn, err
+			}
+		}
+		sf.TypePrefix = "[]" + sf.TypePrefix
+		sf.constraints = constraints{}
+		gt.Fields = append(gt.Fields, sf)
+	}
+	gt.tuple = true
+	return "", nil
+}
+
+// tupleFields returns the fields of gt, a tuple, that hold the items with
+// positions, in the order of the items, and the one holding the items after
+// them if there is one.
+func (gt goType) tupleFields() (fields []structField, rest *structField) {
+	for i, sf := range gt.Fields {
+		switch {
+		case sf.RestItems:
+			rest = &gt.Fields[i]
+		case sf.Position > 0:
+			fields = append(fields, sf)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Position < fields[j].Position })
+	return fields, rest
+}
+
+// printTupleJSON writes the methods that marshal a tuple as an array of its
+// items, up to the first one that's missing, followed by any additional items
+// if none is, and that unmarshal an array into them, failing if it's missing
+// any of the first minItems.
+func (gt goType) printTupleJSON(buf *bytes.Buffer, imports stringset.StringSet) {
+	if !gt.tuple {
+		return
+	}
+	fields, rest := gt.tupleFields()
+	imports.Add("encoding/json")
+
+	var required []string
+	for _, sf := range fields {
+		if sf.Required {
+			required = append(required, "t."+sf.Name)
+		}
+	}
+
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "func (t %s) MarshalJSON() ([]byte, error) {\n", gt.Name)
+	fmt.Fprintf(buf, "items := []interface{}{%s}\n", strings.Join(required, ", "))
+	for _, sf := range fields[len(required):] {
+		fmt.Fprintf(buf, "if t.%s == nil {\nreturn json.Marshal(items)\n}\n", sf.Name)
+		fmt.Fprintf(buf, "items = append(items, t.%s)\n", sf.Name)
+	}
+	if rest != nil {
+		fmt.Fprintf(buf, "for _, v := range t.%s {\nitems = append(items, v)\n}\n", rest.Name)
+	}
+	buf.WriteString("return json.Marshal(items)\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "func (t *%s) UnmarshalJSON(data []byte) error {\n", gt.Name)
+	fmt.Fprintf(buf, "*t = %s{}\n", gt.Name)
+	buf.WriteString("if string(data) == \"null\" {\nreturn nil\n}\n")
+	buf.WriteString("var items []json.RawMessage\n")
+	buf.WriteString("if err := json.Unmarshal(data, &items); err != nil {\nreturn err\n}\n")
+	if len(required) > 0 {
+		imports.Add("errors")
+		fmt.Fprintf(buf, "if len(items) < %d {\n", len(required))
+		fmt.Fprintf(buf, "return errors.New(%q)\n", fmt.Sprintf("array has fewer than %d items", len(required)))
+		buf.WriteString("}\n")
+	}
+	for i, sf := range fields {
+		if !sf.Required {
+			fmt.Fprintf(buf, "if len(items) < %d {\nreturn nil\n}\n", i+1)
+		}
+		fmt.Fprintf(buf, "if err := json.Unmarshal(items[%d], &t.%s); err != nil {\nreturn err\n}\n", i, sf.Name)
+	}
+	if rest != nil {
+		typeStr, _ := rest.typeString()
+		fmt.Fprintf(buf, "for _, item := range items[%d:] {\n", len(fields))
+		fmt.Fprintf(buf, "var v %s\n", strings.TrimPrefix(typeStr, "[]"))
+		buf.WriteString("if err := json.Unmarshal(item, &v); err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(buf, "t.%s = append(t.%s, v)\n", rest.Name, rest.Name)
+		buf.WriteString("}\n")
+	}
+	buf.WriteString("return nil\n")
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,78 @@
+package gen
+
+import "sort"
+
+// The orders that --type-order accepts.
+const (
+	typeOrderAlpha      = "alpha"
+	typeOrderDefinition = "definition"
+	typeOrderDependency = "dependency"
+)
+
+// encounterOrder holds the order in which processType first reached the schema
+// at each path.
+var encounterOrder = make(map[string]int)
+
+// orderTypes sorts typesSlice, which is sorted by name, into the given order.
+// In definition order the root type comes first, and in dependency order it
+// usually comes last.
+func orderTypes(typesSlice goTypes, order string) {
+	switch order {
+	case typeOrderDefinition:
+		sort.SliceStable(typesSlice, func(i, j int) bool {
+			if typesSlice[i].path == rootPath || typesSlice[j].path == rootPath {
+				return typesSlice[i].path == rootPath
+			}
+			return encounterOrder[typesSlice[i].path] < encounterOrder[typesSlice[j].path]
+		})
+	case typeOrderDependency:
+		copy(typesSlice, dependencyOrder(typesSlice))
+	}
+}
+
+// dependencyOrder returns typesSlice in an order where each type comes after
+// the types it refers to, taking them by name where there's a choice. Types in
+// a cycle come after the rest, by name.
+func dependencyOrder(typesSlice goTypes) goTypes {
+	refs := func(gt goType) []string {
+		deps := []string{gt.TypeRef}
+		for _, sf := range gt.Fields {
+			deps = append(deps, sf.TypeRef)
+		}
+		return deps
+	}
+
+	done := make(map[string]bool, len(typesSlice))
+	sorted := make(goTypes, 0, len(typesSlice))
+	for len(sorted) < len(typesSlice) {
+		added := false
+		for _, gt := range typesSlice {
+			if done[gt.path] {
+				continue
+			}
+			ready := true
+			for _, ref := range refs(gt) {
+				if _, ok := types[ref]; ok && ref != gt.path && !done[ref] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[gt.path] = true
+				sorted = append(sorted, gt)
+				added = true
+				break
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	for _, gt := range typesSlice {
+		if !done[gt.path] {
+			sorted = append(sorted, gt)
+		}
+	}
+	return sorted
+}
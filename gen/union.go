@@ -0,0 +1,318 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// The keywords of the schemas that a union holds the value as.
+const (
+	unionOneOf = "oneOf"
+	unionAnyOf = "anyOf"
+)
+
+// unionVariants returns the keyword and the schemas of s if it's generated as
+// a union of them: a struct with a pointer field for each, set to the value if
+// it matches that schema. A oneOf takes precedence over an anyOf. That's
+// unless s has properties of its own, in which case its oneOf or anyOf only
+// constrains them.
+func unionVariants(s *metaSchema, jsonType string) (string, metaSchemaArray) {
+	if len(s.Properties) > 0 || len(s.PatternProperties) > 0 || len(s.AllOf) > 0 {
+		return "", nil
+	}
+	if jsonType != "" && jsonType != typeObject {
+		return "", nil
+	}
+	if len(s.OneOf) > 0 {
+		return unionOneOf, s.OneOf
+	}
+	if len(s.AnyOf) > 0 {
+		return unionAnyOf, s.AnyOf
+	}
+	return "", nil
+}
+
+// isNullSchema returns true if s only allows null, which makes a union
+// nullable rather than adding a variant.
+func isNullSchema(s *metaSchema) bool {
+	t, ok := s.Type.(string)
+	return ok && t == typeNull
+}
+
+// nullableVariant returns the schema besides null of s if s is a union of
+// just that schema and null, the usual way of making a $ref nullable. It's
+// generated as that schema's type, nullable, rather than as a union of one
+// variant. The schema gets the description of s if it has none of its own.
+func nullableVariant(s *metaSchema) (*metaSchema, bool) {
+	_, variants := unionVariants(s, "")
+	if s.Type != nil || len(variants) != 2 {
+		return nil, false
+	}
+	for i := range variants {
+		if !isNullSchema(&variants[i]) {
+			continue
+		}
+		variant := variants[1-i]
+		if isNullSchema(&variant) {
+			return nil, false
+		}
+		if variant.Description == "" {
+			variant.Description = s.Description
+		}
+		return &variant, true
+	}
+	return nil, false
+}
+
+// constString returns the string that s requires a value to be, with const or
+// an enum of one value.
+func constString(s *metaSchema) (string, bool) {
+	if val, ok := s.Const.(string); ok {
+		return val, true
+	}
+	if vals := enumStrings(s); len(vals) == 1 && len(s.Enum) == 1 {
+		return vals[0], true
+	}
+	return "", false
+}
+
+// variantTags returns the name of the property that tells the variants apart,
+// if there is one, and its value for each variant: a property that every
+// variant is an object requiring, with a different string const in each. The
+// properties of a $ref variant are looked up in the schemas of the type it
+// refers to, which is typeRefs[i].
+func variantTags(variants metaSchemaArray, typeRefs []string) (string, []string) {
+	propSchema := func(i int, name string) (*metaSchema, bool) {
+		if typeRefs[i] == "" {
+			s, ok := variants[i].Properties[name]
+			for _, req := range variants[i].Required {
+				if string(req) == name {
+					return &s, ok
+				}
+			}
+			return nil, false
+		}
+		for _, sf := range types[typeRefs[i]].Fields {
+			if sf.PropertyName == name && sf.Required {
+				s, ok := propertySchemas[typeRefs[i]+"/properties/"+name]
+				return s, ok
+			}
+		}
+		return nil, false
+	}
+
+	var names []string
+	if typeRefs[0] == "" {
+		propNames, _ := stringset.FromMapKeys(variants[0].Properties)
+		names = propNames.Sorted()
+	} else {
+		for _, sf := range types[typeRefs[0]].Fields {
+			names = append(names, sf.PropertyName)
+		}
+	}
+
+names:
+	for _, name := range names {
+		tags := make([]string, len(variants))
+		seen := stringset.New()
+		for i := range variants {
+			s, ok := propSchema(i, name)
+			if !ok {
+				continue names
+			}
+			tag, ok := constString(s)
+			if !ok || seen.Has(tag) {
+				continue names
+			}
+			seen.Add(tag)
+			tags[i] = tag
+		}
+		return name, tags
+	}
+	return "", nil
+}
+
+// addVariantFields adds to gt, the type of a union, a field for each
+// of its variants, named after the variant's title, its tag, the type it
+// refers to or its JSON type. A variant that only allows null makes gt
+// nullable instead. It returns the ref of a variant that needs to be
+// processed first if there is one.
+func (gt *goType) addVariantFields(variants metaSchemaArray, keyword, path string) (waitingOn string, err error) {
+	var nonNull metaSchemaArray
+	var variantPaths []string
+	for i, variant := range variants {
+		if isNullSchema(&variant) {
+			gt.Nullable = true
+			continue
+		}
+		nonNull = append(nonNull, variant)
+		variantPaths = append(variantPaths, fmt.Sprintf("%s/%s/%d", path, keyword, i))
+	}
+	if len(nonNull) == 0 {
+		return "", nil
+	}
+
+	// the variants that are refs are resolved first, to look for a tag in
+	// the types they refer to
+	typeRefs := make([]string, len(nonNull))
+	for i := range nonNull {
+		variant := &nonNull[i]
+		resolveRecursiveRef(variant, path)
+		if variant.Ref == "" {
+			continue
+		}
+		ref, ok := resolveRef(variant.Ref)
+		if !ok {
+			return variant.Ref, nil
+		}
+		typeRefs[i] = ref
+	}
+	tagName, tags := variantTags(nonNull, typeRefs)
+	gt.unionTag = tagName
+
+	fieldNames := stringset.New()
+	for i := range nonNull {
+		variant := &nonNull[i]
+		sf := structField{Variant: true, Required: true}
+		jsonType, _ := variant.Type.(string)
+
+		var name string
+		switch {
+		case variant.Title != "":
+			name = variant.Title
+		case tagName != "":
+			name = tags[i]
+		case typeRefs[i] != "":
+			name = types[typeRefs[i]].origTypeName
+		case jsonType != "":
+			name = jsonType
+		}
+		if sf.Name = generateFieldName(name); sf.Name == "" {
+			sf.Name = fmt.Sprintf("Variant%d", i+1)
+		}
+		baseName := sf.Name
+		for n := 2; fieldNames.Has(sf.Name); n++ {
+			sf.Name = fmt.Sprintf("%s%d", baseName, n)
+		}
+		fieldNames.Add(sf.Name)
+		if tagName != "" {
+			sf.TagValue = tags[i]
+		}
+
+		ts := getTypeString(jsonType, variant)
+		switch {
+		case typeRefs[i] != "":
+			sf.TypeRef = typeRefs[i]
+		case scalarJSONType(ts) != "" && !hasEnum(variant, ts):
+			// a plain scalar doesn't need a type of its own
+			sf.TypePrefix = ts
+			sf.constraints = getConstraints(variant)
+		default:
+			gotType, err := processType(variant, gt.origTypeName+" "+sf.Name, variant.Description, variantPaths[i], path)
+			if err != nil || gotType == "" {
+				return variantPaths[i], err
+			}
+			sf.TypeRef = gotType
+		}
+		gt.Fields = append(gt.Fields, sf)
+	}
+	gt.union = keyword
+	return "", nil
+}
+
+// printUnionJSON writes the methods that marshal a union as the variant that's
+// set, the first if there are several, and that unmarshal a value into the
+// variant it matches. With a tag, that's the variant whose tag value the value
+// has; otherwise the value is decoded as each variant in turn, without unknown
+// properties, and must match exactly one of a oneOf, or any number of an anyOf,
+// each of which is set.
+func (gt goType) printUnionJSON(buf *bytes.Buffer, imports stringset.StringSet) {
+	if gt.union == "" {
+		return
+	}
+	var variants []structField
+	for _, sf := range gt.Fields {
+		if sf.Variant {
+			variants = append(variants, sf)
+		}
+	}
+
+	imports.Add("encoding/json")
+	imports.Add("errors")
+
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "func (t %s) MarshalJSON() ([]byte, error) {\n", gt.Name)
+	buf.WriteString("switch {\n")
+	for _, sf := range variants {
+		fmt.Fprintf(buf, "case t.%s != nil:\nreturn json.Marshal(t.%s)\n", sf.Name, sf.Name)
+	}
+	buf.WriteString("}\n")
+	buf.WriteString("return []byte(\"null\"), nil\n")
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "func (t *%s) UnmarshalJSON(data []byte) error {\n", gt.Name)
+	fmt.Fprintf(buf, "*t = %s{}\n", gt.Name)
+	buf.WriteString("if string(data) == \"null\" {\nreturn nil\n}\n")
+
+	if gt.unionTag != "" {
+		imports.Add("fmt")
+		fmt.Fprintf(buf, "var tag struct {\nValue *string `json:%q`\n}\n", gt.unionTag)
+		buf.WriteString("if err := json.Unmarshal(data, &tag); err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(buf, "if tag.Value == nil {\nreturn errors.New(%q)\n}\n", "missing "+gt.unionTag)
+		buf.WriteString("switch *tag.Value {\n")
+		for _, sf := range variants {
+			typeStr, _ := sf.typeString()
+			fmt.Fprintf(buf, "case %q:\n", sf.TagValue)
+			fmt.Fprintf(buf, "t.%s = new(%s)\n", sf.Name, strings.TrimPrefix(typeStr, "*"))
+			fmt.Fprintf(buf, "return json.Unmarshal(data, t.%s)\n", sf.Name)
+		}
+		buf.WriteString("}\n")
+		fmt.Fprintf(buf, "return fmt.Errorf(%q, *tag.Value)\n", "unknown "+gt.unionTag+" %q")
+		buf.WriteString("}\n")
+		return
+	}
+
+	imports.Add("bytes")
+	buf.WriteString("decode := func(v interface{}) bool {\n")
+	buf.WriteString("dec := json.NewDecoder(bytes.NewReader(data))\n")
+	buf.WriteString("dec.DisallowUnknownFields()\n")
+	buf.WriteString("return dec.Decode(v) == nil\n")
+	buf.WriteString("}\n")
+	if gt.union == unionAnyOf {
+		buf.WriteString("matched := false\n")
+		for _, sf := range variants {
+			typeStr, _ := sf.typeString()
+			fmt.Fprintf(buf, "if v := new(%s); decode(v) {\n", strings.TrimPrefix(typeStr, "*"))
+			fmt.Fprintf(buf, "t.%s = v\n", sf.Name)
+			buf.WriteString("matched = true\n")
+			buf.WriteString("}\n")
+		}
+		buf.WriteString("if !matched {\n")
+		fmt.Fprintf(buf, "return errors.New(%q)\n", "value matches none of the "+gt.union+" schemas")
+		buf.WriteString("}\n")
+		buf.WriteString("return nil\n")
+		buf.WriteString("}\n")
+		return
+	}
+
+	imports.Add("strings")
+	buf.WriteString("var matched []string\n")
+	for _, sf := range variants {
+		typeStr, _ := sf.typeString()
+		fmt.Fprintf(buf, "if v := new(%s); decode(v) {\n", strings.TrimPrefix(typeStr, "*"))
+		fmt.Fprintf(buf, "t.%s = v\n", sf.Name)
+		fmt.Fprintf(buf, "matched = append(matched, %q)\n", sf.Name)
+		buf.WriteString("}\n")
+	}
+	buf.WriteString("switch len(matched) {\n")
+	buf.WriteString("case 0:\n")
+	fmt.Fprintf(buf, "return errors.New(%q)\n", "value matches none of the "+gt.union+" schemas")
+	buf.WriteString("case 1:\nreturn nil\n")
+	buf.WriteString("}\n")
+	fmt.Fprintf(buf, "*t = %s{}\n", gt.Name)
+	fmt.Fprintf(buf, "return errors.New(%q + strings.Join(matched, \", \"))\n", "value matches more than one of the "+gt.union+" schemas: ")
+	buf.WriteString("}\n")
+}
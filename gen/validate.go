@@ -0,0 +1,631 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// constraints holds the validation keywords of a schema that are checked by
+// the generated Validate methods.
+type constraints struct {
+	MinLength        *int
+	MaxLength        *int
+	Pattern          string
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum bool
+	ExclusiveMaximum bool
+	MinItems         *int
+	MaxItems         *int
+	Format           string
+	// KeyPattern is the pattern that the keys of a map must match
+	KeyPattern string
+	// KeyNames holds what propertyNames requires of the keys of a map, or is
+	// nil if it requires nothing that's checked
+	KeyNames *keyNames
+	// Not holds what the not schema forbids a scalar, or is nil if it forbids
+	// nothing that's checked
+	Not *notConstraints
+}
+
+// keyNames holds the pattern, and the values, that the propertyNames schema
+// of an object requires its keys to match or be one of.
+type keyNames struct {
+	Pattern string
+	Enum    []string
+}
+
+// getKeyNames returns what s, a propertyNames schema, requires of the keys of
+// an object, or nil if it's nothing that's checked.
+func getKeyNames(s *metaSchema) *keyNames {
+	names := &keyNames{Pattern: s.Pattern}
+	// a switch can't have the same case twice
+	seen := stringset.New()
+	for _, val := range enumStrings(s) {
+		if !seen.Has(val) {
+			seen.Add(val)
+			names.Enum = append(names.Enum, val)
+		}
+	}
+	if val, ok := s.Const.(string); ok {
+		names.Enum = []string{val}
+	}
+	if names.Pattern != "" {
+		if _, err := regexp.Compile(names.Pattern); err != nil {
+			log.Printf("Ignoring propertyNames pattern %q: %s\n", names.Pattern, err)
+			names.Pattern = ""
+		}
+	}
+	if names.Pattern == "" && len(names.Enum) == 0 {
+		return nil
+	}
+	return names
+}
+
+func positiveInt(v interface{}) *int {
+	switch v := v.(type) {
+	case float64:
+		n := int(v)
+		return &n
+	case *metaPositiveInteger:
+		if v == nil {
+			return nil
+		}
+		n := int(*v)
+		return &n
+	default:
+		return nil
+	}
+}
+
+// exclusiveBound returns the bound given by an inclusive bound and an
+// exclusiveMinimum or exclusiveMaximum, and whether it's exclusive. Before
+// draft-06 exclusive is a boolean modifying bound, and since then it's a
+// number that's a bound of its own; a value of the other form is ignored.
+func exclusiveBound(bound *float64, exclusive interface{}, isMax bool) (*float64, bool) {
+	if !numericExclusiveBounds() {
+		isExclusive, _ := exclusive.(bool)
+		return bound, isExclusive && bound != nil
+	}
+
+	excl, ok := exclusive.(float64)
+	if !ok {
+		return bound, false
+	}
+	// when both are given the stricter one applies
+	if bound != nil && ((isMax && *bound < excl) || (!isMax && *bound > excl)) {
+		return bound, false
+	}
+	return &excl, true
+}
+
+func getConstraints(s *metaSchema) constraints {
+	c := constraints{
+		MinLength: positiveInt(s.MinLength),
+		MaxLength: positiveInt(s.MaxLength),
+		Pattern:   s.Pattern,
+		MinItems:  positiveInt(s.MinItems),
+		MaxItems:  positiveInt(s.MaxItems),
+	}
+	c.Minimum, c.ExclusiveMinimum = exclusiveBound(s.Minimum, s.ExclusiveMinimum, false)
+	c.Maximum, c.ExclusiveMaximum = exclusiveBound(s.Maximum, s.ExclusiveMaximum, true)
+
+	if validatedFormats.Has(s.Format) {
+		c.Format = s.Format
+	}
+	c.KeyPattern, _ = singlePattern(s)
+	if s.PropertyNames != nil {
+		c.KeyNames = getKeyNames(s.PropertyNames)
+	}
+	if s.Not != nil {
+		c.Not = getNotConstraints(s.Not)
+	}
+
+	// JSON Schema patterns are ECMA 262 regexes; skip any that Go can't compile
+	// rather than generating code that panics on init
+	if c.Pattern != "" {
+		if _, err := regexp.Compile(c.Pattern); err != nil {
+			log.Printf("Ignoring pattern %q: %s\n", c.Pattern, err)
+			c.Pattern = ""
+		}
+	}
+
+	return c
+}
+
+// validatedFormats are the string formats that are checked by Validate when
+// their type isn't overridden with --format-map.
+var validatedFormats = stringset.New("ipv4", "ipv6", "hostname")
+
+// hostnamePattern matches an RFC 1123 host name.
+const hostnamePattern = `^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`
+
+func isSliceType(typePrefix string) bool {
+	return typePrefix == "[]" || typePrefix == "[]*" || typePrefix == typeEmptyInterfaceSlice
+}
+
+// appliesTo returns true if any of the constraints can be checked on a value
+// of the given type.
+func (c constraints) appliesTo(typePrefix string) bool {
+	switch {
+	case typePrefix == typeString:
+		return c.MinLength != nil || c.MaxLength != nil || c.Pattern != "" || c.Format != "" || c.Not.appliesTo(typePrefix)
+	case typePrefix == typeInt || typePrefix == typeFloat64:
+		return c.Minimum != nil || c.Maximum != nil || c.Not.appliesTo(typePrefix)
+	case typePrefix == typeBool:
+		return c.Not.appliesTo(typePrefix)
+	case isSliceType(typePrefix):
+		return c.MinItems != nil || c.MaxItems != nil
+	case strings.HasPrefix(typePrefix, "map[string]"):
+		return c.KeyPattern != "" || c.KeyNames != nil
+	default:
+		return false
+	}
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// hasOwnChecks returns true if the type or any of its fields has constraints
+// that apply to it directly, or an if/then/else, dependentRequired or not to
+// check.
+func (gt goType) hasOwnChecks() bool {
+	if gt.constraints.appliesTo(gt.TypePrefix) || gt.conditional != nil || len(gt.dependentRequired) > 0 || len(gt.notRequired) > 0 {
+		return true
+	}
+	for _, sf := range gt.Fields {
+		if !sf.Embedded && sf.constraints.appliesTo(sf.TypePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findValidatedTypes returns the paths of the types that need a Validate
+// method: those with constraints of their own and those that contain, directly
+// or through slices and maps, a type that needs one.
+func findValidatedTypes() map[string]bool {
+	validated := make(map[string]bool)
+	for path, gt := range types {
+		if gt.hasOwnChecks() {
+			validated[path] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for path, gt := range types {
+			if validated[path] {
+				continue
+			}
+
+			needed := validated[gt.TypeRef]
+			for _, sf := range gt.Fields {
+				if validated[sf.TypeRef] {
+					needed = true
+				}
+			}
+
+			if needed {
+				validated[path] = true
+				changed = true
+			}
+		}
+	}
+
+	return validated
+}
+
+// validateWriter writes the body of a Validate method.
+type validateWriter struct {
+	buf       *bytes.Buffer
+	decls     *bytes.Buffer
+	imports   stringset.StringSet
+	validated map[string]bool
+	typeName  string
+	// all is set with --validate-all, to collect every violation in errs
+	// rather than returning the first
+	all bool
+}
+
+func (w validateWriter) writeFail(propName, msg string) {
+	if propName != "" {
+		msg = propName + ": " + msg
+	}
+	w.imports.Add("errors")
+	if w.all {
+		fmt.Fprintf(w.buf, "errs = append(errs, errors.New(%q))\n", msg)
+		return
+	}
+	fmt.Fprintf(w.buf, "return errors.New(%q)\n", msg)
+}
+
+// writeNestedFail writes the handling of err from a nested Validate call.
+// With --wrap-errors, err is prefixed with the path given by the format
+// prefix and its args, unless prefix is empty.
+func (w validateWriter) writeNestedFail(prefix string, args ...string) {
+	if !*wrapErrors || prefix == "" {
+		if w.all {
+			w.buf.WriteString("errs.add(err)\n")
+			return
+		}
+		w.buf.WriteString("return err\n")
+		return
+	}
+
+	if w.all && len(args) == 0 {
+		fmt.Fprintf(w.buf, "errs.addPrefixed(%q, err)\n", strings.ReplaceAll(prefix, "%%", "%"))
+		return
+	}
+	w.imports.Add("fmt")
+	if w.all {
+		fmt.Fprintf(w.buf, "errs.addPrefixed(fmt.Sprintf(%q, %s), err)\n", prefix, strings.Join(args, ", "))
+		return
+	}
+	args = append(args, "err")
+	fmt.Fprintf(w.buf, "return fmt.Errorf(%q, %s)\n", prefix+"%w", strings.Join(args, ", "))
+}
+
+// pathSeparator returns what goes between the path of a value of the
+// generated type at path and the errors of its Validate method with
+// --wrap-errors: a dot before the property names of a struct's errors,
+// nothing before the indexes of a slice's or map's, and a colon before the
+// messages of a scalar's.
+func pathSeparator(path string) string {
+	gt := types[path]
+	switch {
+	case gt.TypePrefix == typeStruct || gt.wrappedField != nil:
+		return "."
+	case gt.TypePrefix == "":
+		if _, ok := types[gt.TypeRef]; ok {
+			return pathSeparator(gt.TypeRef)
+		}
+	case isSliceType(gt.TypePrefix) || strings.HasPrefix(gt.TypePrefix, "map["):
+		return ""
+	}
+	return ": "
+}
+
+// writeConstraintChecks writes the checks of c against expr, a value of the
+// Go type given by typePrefix.
+func (w validateWriter) writeConstraintChecks(c constraints, expr, typePrefix, propName, fieldName string) {
+	if c.Not.appliesTo(typePrefix) {
+		w.writeNotChecks(*c.Not, expr, typePrefix, propName, fieldName)
+	}
+	switch {
+	case typePrefix == typeString:
+		if c.MinLength != nil || c.MaxLength != nil {
+			w.imports.Add("unicode/utf8")
+		}
+		if c.MinLength != nil {
+			fmt.Fprintf(w.buf, "if utf8.RuneCountInString(%s) < %d {\n", expr, *c.MinLength)
+			w.writeFail(propName, fmt.Sprintf("length must be at least %d", *c.MinLength))
+			w.buf.WriteString("}\n")
+		}
+		if c.MaxLength != nil {
+			fmt.Fprintf(w.buf, "if utf8.RuneCountInString(%s) > %d {\n", expr, *c.MaxLength)
+			w.writeFail(propName, fmt.Sprintf("length must be at most %d", *c.MaxLength))
+			w.buf.WriteString("}\n")
+		}
+		if c.Pattern != "" {
+			w.imports.Add("regexp")
+			patternVar := generateIdentifier(w.typeName+" "+fieldName+" pattern", false)
+			fmt.Fprintf(w.decls, "var %s = regexp.MustCompile(%q)\n", patternVar, c.Pattern)
+			fmt.Fprintf(w.buf, "if !%s.MatchString(%s) {\n", patternVar, expr)
+			w.writeFail(propName, fmt.Sprintf("must match pattern %s", c.Pattern))
+			w.buf.WriteString("}\n")
+		}
+		switch c.Format {
+		case "ipv4", "ipv6":
+			w.imports.Add("net/netip")
+			check, desc := "Is4", "an IPv4"
+			if c.Format == "ipv6" {
+				check, desc = "Is6", "an IPv6"
+			}
+			fmt.Fprintf(w.buf, "if addr, err := netip.ParseAddr(%s); err != nil || !addr.%s() {\n", expr, check)
+			w.writeFail(propName, fmt.Sprintf("must be %s address", desc))
+			w.buf.WriteString("}\n")
+		case "hostname":
+			w.imports.Add("regexp")
+			patternVar := generateIdentifier(w.typeName+" "+fieldName+" hostname pattern", false)
+			fmt.Fprintf(w.decls, "var %s = regexp.MustCompile(%q)\n", patternVar, hostnamePattern)
+			fmt.Fprintf(w.buf, "if len(%s) > 253 || !%s.MatchString(%s) {\n", expr, patternVar, expr)
+			w.writeFail(propName, "must be a valid host name")
+			w.buf.WriteString("}\n")
+		}
+	case typePrefix == typeInt || typePrefix == typeFloat64:
+		if typePrefix == typeInt {
+			expr = "float64(" + expr + ")"
+		}
+		if c.Minimum != nil {
+			op, desc := "<", ">="
+			if c.ExclusiveMinimum {
+				op, desc = "<=", ">"
+			}
+			fmt.Fprintf(w.buf, "if %s %s %s {\n", expr, op, formatNumber(*c.Minimum))
+			w.writeFail(propName, fmt.Sprintf("must be %s %s", desc, formatNumber(*c.Minimum)))
+			w.buf.WriteString("}\n")
+		}
+		if c.Maximum != nil {
+			op, desc := ">", "<="
+			if c.ExclusiveMaximum {
+				op, desc = ">=", "<"
+			}
+			fmt.Fprintf(w.buf, "if %s %s %s {\n", expr, op, formatNumber(*c.Maximum))
+			w.writeFail(propName, fmt.Sprintf("must be %s %s", desc, formatNumber(*c.Maximum)))
+			w.buf.WriteString("}\n")
+		}
+	case isSliceType(typePrefix):
+		if c.MinItems != nil {
+			fmt.Fprintf(w.buf, "if len(%s) < %d {\n", expr, *c.MinItems)
+			w.writeFail(propName, fmt.Sprintf("must have at least %d items", *c.MinItems))
+			w.buf.WriteString("}\n")
+		}
+		if c.MaxItems != nil {
+			fmt.Fprintf(w.buf, "if len(%s) > %d {\n", expr, *c.MaxItems)
+			w.writeFail(propName, fmt.Sprintf("must have at most %d items", *c.MaxItems))
+			w.buf.WriteString("}\n")
+		}
+	case strings.HasPrefix(typePrefix, "map[string]"):
+		if c.KeyPattern != "" {
+			w.imports.Add("regexp")
+			patternVar := generateIdentifier(w.typeName+" "+fieldName+" key pattern", false)
+			fmt.Fprintf(w.decls, "var %s = regexp.MustCompile(%q)\n", patternVar, c.KeyPattern)
+			fmt.Fprintf(w.buf, "for k := range %s {\n", expr)
+			fmt.Fprintf(w.buf, "if !%s.MatchString(k) {\n", patternVar)
+			w.writeFail(propName, fmt.Sprintf("keys must match pattern %s", c.KeyPattern))
+			w.buf.WriteString("}\n}\n")
+		}
+		if c.KeyNames != nil {
+			w.writeKeyNamesChecks(*c.KeyNames, expr, propName, fieldName)
+		}
+	}
+}
+
+// writeKeyNamesChecks writes the checks that the keys of expr, a map, are
+// names that the propertyNames schema allows.
+func (w validateWriter) writeKeyNamesChecks(names keyNames, expr, propName, fieldName string) {
+	fmt.Fprintf(w.buf, "for k := range %s {\n", expr)
+	if names.Pattern != "" {
+		w.imports.Add("regexp")
+		patternVar := generateIdentifier(w.typeName+" "+fieldName+" property names pattern", false)
+		fmt.Fprintf(w.decls, "var %s = regexp.MustCompile(%q)\n", patternVar, names.Pattern)
+		fmt.Fprintf(w.buf, "if !%s.MatchString(k) {\n", patternVar)
+		w.writeFail(propName, fmt.Sprintf("keys must match pattern %s", names.Pattern))
+		w.buf.WriteString("}\n")
+	}
+	if len(names.Enum) > 0 {
+		quoted := make([]string, len(names.Enum))
+		for i, name := range names.Enum {
+			quoted[i] = strconv.Quote(name)
+		}
+		fmt.Fprintf(w.buf, "switch k {\ncase %s:\ndefault:\n", strings.Join(quoted, ", "))
+		w.writeFail(propName, "keys must be one of "+strings.Join(quoted, ", "))
+		w.buf.WriteString("}\n")
+	}
+	w.buf.WriteString("}\n")
+}
+
+// writeNestedChecks writes calls to the Validate methods of the generated
+// values in expr, which is at the path propName, or "" for the value itself.
+func (w validateWriter) writeNestedChecks(expr, typePrefix, typeRef, propName string) {
+	if !w.validated[typeRef] {
+		return
+	}
+
+	// the path goes in a format string
+	path := strings.ReplaceAll(propName, "%", "%%")
+	sep := strings.ReplaceAll(pathSeparator(typeRef), "%", "%%")
+	index := "_"
+	if *wrapErrors {
+		index = "i"
+		if typePrefix == "map[string]" {
+			index = "k"
+		}
+	}
+
+	switch typePrefix {
+	case "":
+		fmt.Fprintf(w.buf, "if err := %s.Validate(); err != nil {\n", expr)
+		if path != "" {
+			w.writeNestedFail(path + sep)
+		} else {
+			w.writeNestedFail("")
+		}
+		w.buf.WriteString("}\n")
+	case "[]*":
+		fmt.Fprintf(w.buf, "for %s, v := range %s {\n", index, expr)
+		w.buf.WriteString("if v == nil {\ncontinue\n}\n")
+		w.buf.WriteString("if err := v.Validate(); err != nil {\n")
+		w.writeNestedFail(path+"[%d]"+sep, index)
+		w.buf.WriteString("}\n}\n")
+	case "[]":
+		fmt.Fprintf(w.buf, "for %s, v := range %s {\n", index, expr)
+		w.buf.WriteString("if err := v.Validate(); err != nil {\n")
+		w.writeNestedFail(path+"[%d]"+sep, index)
+		w.buf.WriteString("}\n}\n")
+	case "map[string]":
+		fmt.Fprintf(w.buf, "for %s, v := range %s {\n", index, expr)
+		w.buf.WriteString("if err := v.Validate(); err != nil {\n")
+		w.writeNestedFail(path+"[%q]"+sep, index)
+		w.buf.WriteString("}\n}\n")
+	}
+}
+
+func (w validateWriter) writeFieldChecks(sf structField) {
+	if sf.Embedded {
+		// the fields of an embedded struct are the struct's own
+		w.writeNestedChecks("t."+types[sf.TypeRef].Name, "", sf.TypeRef, "")
+		return
+	}
+
+	hasChecks := sf.constraints.appliesTo(sf.TypePrefix)
+	if !hasChecks && !w.validated[sf.TypeRef] {
+		return
+	}
+
+	expr := "t." + sf.Name
+	if sf.NullSlice {
+		expr += ".Value"
+	}
+	typeStr, isPtr := sf.typeString()
+	switch {
+	case isPtr:
+		fmt.Fprintf(w.buf, "if %s != nil {\n", expr)
+		if sf.TypePrefix != "" {
+			expr = "*" + expr
+		}
+	case !sf.Required:
+		// an absent optional property leaves the zero value, which its
+		// constraints don't apply to
+		fmt.Fprintf(w.buf, "if %s {\n", sf.setCheck(expr, typeStr, w.imports))
+	}
+
+	if hasChecks {
+		w.writeConstraintChecks(sf.constraints, expr, sf.TypePrefix, sf.PropertyName, sf.Name)
+	}
+	w.writeNestedChecks(expr, sf.TypePrefix, sf.TypeRef, sf.PropertyName)
+
+	if isPtr || !sf.Required {
+		w.buf.WriteString("}\n")
+	}
+}
+
+// setCheck returns an expression that is true if expr, the value of sf of the
+// Go type typeStr that isn't a pointer, isn't the zero value.
+func (sf structField) setCheck(expr, typeStr string, imports stringset.StringSet) string {
+	if sf.NullSlice {
+		return expr + " != nil"
+	}
+	if refType, ok := types[sf.TypeRef]; ok && sf.TypePrefix == "" {
+		return negateCheck(namedZeroCheck(expr, refType, imports))
+	}
+	return negateCheck(zeroCheck(expr, typeStr, imports))
+}
+
+// printValidate writes a Validate method for gt, which checks the constraints
+// of its schema and calls Validate on any nested types that have one.
+func (gt goType) printValidate(buf *bytes.Buffer, imports stringset.StringSet, validated map[string]bool) {
+	w := validateWriter{
+		buf:       buf,
+		decls:     &bytes.Buffer{},
+		imports:   imports,
+		validated: validated,
+		typeName:  gt.Name,
+		all:       *validateAll,
+	}
+
+	if w.all {
+		fmt.Fprintf(buf, "// Validate returns a %s listing every constraint of its schema that t\n", validationErrorsTypeName())
+		buf.WriteString("// does not satisfy, or nil if there are none.\n")
+	} else {
+		buf.WriteString("// Validate returns an error if t does not satisfy the constraints of its schema.\n")
+	}
+	fmt.Fprintf(buf, "func (t %s) Validate() error {\n", gt.Name)
+	if w.all {
+		fmt.Fprintf(buf, "var errs %s\n", validationErrorsTypeName())
+	}
+
+	if gt.TypePrefix == typeStruct {
+		sort.Stable(gt.Fields)
+		for _, sf := range gt.Fields {
+			w.writeFieldChecks(sf)
+		}
+		if gt.conditional != nil {
+			w.writeConditionalChecks(gt)
+		}
+		w.writeDependentRequired(gt)
+		w.writeNotRequired(gt)
+	} else {
+		expr := "t"
+		if gt.TypePrefix == typeString {
+			expr = "string(t)"
+		}
+		propName := ""
+		if gt.wrappedField != nil {
+			propName = gt.wrappedField.PropertyName
+		}
+		if gt.constraints.appliesTo(gt.TypePrefix) {
+			w.writeConstraintChecks(gt.constraints, expr, gt.TypePrefix, propName, "")
+		}
+		// a type defined as another doesn't have its methods
+		nestedExpr := "t"
+		if refType, ok := types[gt.TypeRef]; ok && gt.TypePrefix == "" {
+			nestedExpr = refType.Name + "(t)"
+		}
+		w.writeNestedChecks(nestedExpr, gt.TypePrefix, gt.TypeRef, "")
+	}
+
+	if w.all {
+		buf.WriteString("if len(errs) > 0 {\nreturn errs\n}\n")
+	}
+	buf.WriteString("return nil\n}\n")
+
+	if w.decls.Len() > 0 {
+		buf.WriteString("\n")
+		buf.Write(w.decls.Bytes())
+	}
+}
+
+// validationErrorsTypeName returns the name of the error type returned by
+// Validate methods with --validate-all.
+func validationErrorsTypeName() string {
+	return generateIdentifier("validation errors", *packageName != "main")
+}
+
+// printValidationErrors returns the file name and body of a file declaring
+// the error type returned by Validate methods with --validate-all.
+func printValidationErrors() (fileName string, body []byte) {
+	name := validationErrorsTypeName()
+
+	var buf bytes.Buffer
+	buf.WriteString("// " + name + " lists the constraint violations found by a Validate method.\n")
+	buf.WriteString("type " + name + " []error\n\n")
+
+	buf.WriteString("func (e " + name + ") Error() string {\n")
+	buf.WriteString("msgs := make([]string, len(e))\n")
+	buf.WriteString("for i, err := range e {\n")
+	buf.WriteString("msgs[i] = err.Error()\n")
+	buf.WriteString("}\n")
+	buf.WriteString("return strings.Join(msgs, \"; \")\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// Unwrap returns the violations, for errors.Is and errors.As.\n")
+	buf.WriteString("func (e " + name + ") Unwrap() []error {\n")
+	buf.WriteString("return e\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("// add appends err, or the violations in it if it lists them itself.\n")
+	buf.WriteString("func (e *" + name + ") add(err error) {\n")
+	buf.WriteString("if errs, ok := err.(" + name + "); ok {\n")
+	buf.WriteString("*e = append(*e, errs...)\n")
+	buf.WriteString("return\n")
+	buf.WriteString("}\n")
+	buf.WriteString("*e = append(*e, err)\n")
+	buf.WriteString("}\n")
+
+	if *wrapErrors {
+		buf.WriteString("\n")
+		buf.WriteString("// addPrefixed adds err, or the violations in it, with the path prefix in\n")
+		buf.WriteString("// front of each.\n")
+		buf.WriteString("func (e *" + name + ") addPrefixed(prefix string, err error) {\n")
+		buf.WriteString("if errs, ok := err.(" + name + "); ok {\n")
+		buf.WriteString("for _, err := range errs {\n")
+		buf.WriteString("*e = append(*e, fmt.Errorf(\"%s%w\", prefix, err))\n")
+		buf.WriteString("}\n")
+		buf.WriteString("return\n")
+		buf.WriteString("}\n")
+		buf.WriteString("*e = append(*e, fmt.Errorf(\"%s%w\", prefix, err))\n")
+		buf.WriteString("}\n")
+	}
+
+	return name + ".go", buf.Bytes()
+}
@@ -0,0 +1,116 @@
+package gen
+
+import (
+	"strconv"
+	"strings"
+)
+
+// validatorFormats maps string formats to the go-playground/validator tags
+// that check them.
+var validatorFormats = map[string]string{
+	"email":     "email",
+	"hostname":  "hostname_rfc1123",
+	"ipv4":      "ipv4",
+	"ipv6":      "ipv6",
+	"uri":       "uri",
+	"uuid":      "uuid",
+	"date-time": "datetime=2006-01-02T15:04:05Z07:00",
+	"date":      "datetime=2006-01-02",
+}
+
+// underlyingKind returns the Go type underneath the generated types sf refers
+// to, like "string" for a field of a named string type, "[]" for a slice and
+// "map[string]" for a map. A []byte is a single value, so it's "[]byte".
+func (sf structField) underlyingKind() string {
+	if isSliceType(sf.TypePrefix) || sf.FixedLen > 0 || sf.NullSlice {
+		return "[]"
+	}
+	prefix, ref := sf.TypePrefix, sf.TypeRef
+	for prefix == "" {
+		refType, ok := types[ref]
+		if !ok {
+			return ""
+		}
+		prefix, ref = refType.TypePrefix, refType.TypeRef
+	}
+	if prefix == typeBytes {
+		return typeBytes
+	}
+	if strings.HasPrefix(prefix, "[]") {
+		return "[]"
+	}
+	if strings.HasPrefix(prefix, "map[") {
+		return "map[string]"
+	}
+	return prefix
+}
+
+// validatorTag returns the value of the validate tag of sf for
+// go-playground/validator with --validator-tags, or "" if it has nothing to
+// check. Patterns aren't included, since validator has no tag for them.
+func (sf structField) validatorTag() string {
+	if sf.Embedded || sf.Pattern != "" || sf.Catchall || sf.NullSlice {
+		return ""
+	}
+
+	kind := sf.underlyingKind()
+	var rules []string
+	c := sf.constraints
+	switch kind {
+	case typeString:
+		if c.MinLength != nil {
+			rules = append(rules, "min="+strconv.Itoa(*c.MinLength))
+		}
+		if c.MaxLength != nil {
+			rules = append(rules, "max="+strconv.Itoa(*c.MaxLength))
+		}
+		if tag, ok := validatorFormats[sf.format]; ok {
+			rules = append(rules, tag)
+		}
+	case typeInt, typeFloat64:
+		if c.Minimum != nil {
+			op := "gte="
+			if c.ExclusiveMinimum {
+				op = "gt="
+			}
+			rules = append(rules, op+formatNumber(*c.Minimum))
+		}
+		if c.Maximum != nil {
+			op := "lte="
+			if c.ExclusiveMaximum {
+				op = "lt="
+			}
+			rules = append(rules, op+formatNumber(*c.Maximum))
+		}
+	case "[]":
+		if c.MinItems != nil {
+			rules = append(rules, "min="+strconv.Itoa(*c.MinItems))
+		}
+		if c.MaxItems != nil {
+			rules = append(rules, "max="+strconv.Itoa(*c.MaxItems))
+		}
+	}
+
+	// values with spaces can't be listed in oneof
+	if refType, ok := types[sf.TypeRef]; ok && (sf.TypePrefix == "" || sf.TypePrefix == "*") && len(refType.enumValues) > 0 {
+		oneOf := "oneof=" + strings.Join(refType.enumValues, " ")
+		for _, value := range refType.enumValues {
+			if value == "" || strings.ContainsAny(value, " ,|") {
+				oneOf = ""
+			}
+		}
+		if oneOf != "" {
+			rules = append(rules, oneOf)
+		}
+	}
+
+	// validator's required rejects zero values, which are valid booleans and
+	// numbers, and omitempty skips the other rules for absent values
+	switch {
+	case sf.Required && kind != typeBool && kind != typeInt && kind != typeFloat64:
+		rules = append([]string{"required"}, rules...)
+	case !sf.Required && len(rules) > 0:
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	return strings.Join(rules, ",")
+}
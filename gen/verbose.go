@@ -0,0 +1,32 @@
+package gen
+
+import "log"
+
+// logf logs a message about processing the schema with -v.
+func logf(format string, args ...interface{}) {
+	if *verbose {
+		log.Printf(format, args...)
+	}
+}
+
+// logDecisions logs the Go type chosen for gt and for each of its fields.
+func (gt goType) logDecisions() {
+	if !*verbose {
+		return
+	}
+
+	typeStr := gt.TypePrefix
+	if baseType, ok := types[gt.TypeRef]; ok {
+		typeStr += baseType.Name
+	}
+	logf("%s: type %s %s", gt.path, gt.Name, typeStr)
+
+	for _, sf := range gt.Fields {
+		if sf.Embedded {
+			logf("%s: embeds %s", gt.path, types[sf.TypeRef].Name)
+			continue
+		}
+		fieldType, _ := sf.typeString()
+		logf("%s/properties/%s: field %s %s", gt.path, sf.PropertyName, sf.Name, fieldType)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/idubinskiy/schematyper/gen"
+	"github.com/idubinskiy/schematyper/stringset"
+)
+
+// opts are the options the flags other than those for reading and writing
+// files are parsed into.
+var opts gen.Options
+
+var (
+	outputDir    *string
+	formatMap    *map[string]string
+	commentsFrom *string
+	buildCheck   *bool
+	fileMode     *string
+	lineEndings  *string
+	inputFile    *string
+)
+
+func init() {
+	outputDir = kingpin.Flag("out-dir", "directory for output; default is current").Short('o').String()
+	kingpin.Flag("package", `package name for generated file; default is the package of the Go files in --out-dir, or "main"`).Default("main").IsSetByUser(&packageSet).StringVar(&opts.PackageName)
+	kingpin.Flag("root-type", `name of root type; default is generated from the filename`).StringVar(&opts.RootTypeName)
+	kingpin.Flag("prefix", `prefix for non-root types`).StringVar(&opts.Prefix)
+	kingpin.Flag("schema-version", "JSON Schema version to interpret the schema as: draft-04, draft-06, draft-07, 2019-09, or 2020-12; default is detected from $schema, or draft-07").PlaceHolder("VERSION").EnumVar(&opts.SchemaVersion, "draft-04", "draft-06", "draft-07", "2019-09", "2020-12")
+	kingpin.Flag("root-pointer", "JSON pointer of the schema to generate the root type from, e.g. #/components/schemas/User; only it and the schemas it refers to are generated").PlaceHolder("POINTER").StringVar(&opts.RootPointer)
+	kingpin.Flag("prefix-root", "apply --prefix to the root type as well").Default("false").BoolVar(&opts.PrefixRoot)
+	kingpin.Flag("prefix-constants", "apply --prefix to enum constants as well, including those named by value or x-enum-varnames").Default("false").BoolVar(&opts.PrefixConstants)
+	kingpin.Flag("ptr-for-omit", "use a pointer to a struct for an object property that is represented as a struct if the property is not required (i.e., has omitempty tag)").Default("false").BoolVar(&opts.PtrForOmit)
+	kingpin.Flag("goimports", "format output with goimports instead of gofmt").Default("false").BoolVar(&opts.Goimports)
+	kingpin.Flag("nolint", "mark generated files with a //nolint directive so linters skip them").BoolVar(&opts.Nolint)
+	kingpin.Flag("nolint-linters", "linters named in the --nolint directive, comma-separated").Default("all").StringVar(&opts.NolintLinters)
+	kingpin.Flag("emit-go-generate", "add a //go:generate directive that runs schematyper again with the same arguments to the root type's file").BoolVar(&opts.EmitGoGenerate)
+	kingpin.Flag("enum-naming", "naming of enum constants: type-value (StatusActive), value (Active), or upper-snake (STATUS_ACTIVE)").Default("type-value").EnumVar(&opts.EnumNaming, "type-value", "value", "upper-snake")
+	kingpin.Flag("enum-helpers", "generate a <Type>Values slice of the constants of each enum and an IsValid method that checks a value is one of them").BoolVar(&opts.EnumHelpers)
+	commentsFrom = kingpin.Flag("comments-from", "JSON file mapping schema paths to descriptions, used for types without one").ExistingFile()
+	kingpin.Flag("aliases", "generate definitions that are just a $ref or a primitive type as aliases (type X = Y)").Default("false").BoolVar(&opts.Aliases)
+	kingpin.Flag("collapse-wrappers", "generate inline objects with a single property as the type of that property, still marshaled as an object").Default("false").BoolVar(&opts.CollapseWrappers)
+	kingpin.Flag("nested-names", "naming of inline object types: leaf (from their property) or path (also from the properties they're nested in, e.g. AB for b in a)").Default("leaf").EnumVar(&opts.NestedNames, "leaf", "path")
+	kingpin.Flag("type-order", "order of the types in the --types-list slice and the --output-test file: alpha, definition (the order the generator reaches them), or dependency (types before the types that refer to them)").Default("alpha").EnumVar(&opts.TypeOrder, "alpha", "definition", "dependency")
+	kingpin.Flag("types-list", "generate a slice holding the zero value of each generated type").Default("false").BoolVar(&opts.TypesList)
+	kingpin.Flag("output-test", "generate a test that marshals each type's zero value and round-trips the schema's examples").BoolVar(&opts.OutputTest)
+	kingpin.Flag("output-examples", "generate a file with a <Type>Examples function for each type whose schema has examples, returning them as values of the type").BoolVar(&opts.OutputExamples)
+	kingpin.Flag("defaults", "generate a New<Type> function for each struct with properties that have defaults, which returns it with them set").BoolVar(&opts.Defaults)
+	kingpin.Flag("iszero", "generate IsZero methods for structs, for use with omitzero").Default("false").BoolVar(&opts.IsZero)
+	kingpin.Flag("equal", "generate Equal methods that compare structs, slices and maps field by field and element by element").BoolVar(&opts.Equal)
+	kingpin.Flag("merge", "generate Merge methods that copy the fields of another value of a struct that are set over it, for partial updates").BoolVar(&opts.Merge)
+	kingpin.Flag("merge-values", "which non-pointer fields Merge copies: nonzero (those that aren't the zero value) or always").Default("nonzero").EnumVar(&opts.MergeValues, "nonzero", "always")
+	kingpin.Flag("null-slices", "generate optional nullable arrays with a wrapper that tells absent, null and empty apart (needs Go 1.24)").Default("false").BoolVar(&opts.NullSlices)
+	kingpin.Flag("null-type", "type of nullable scalar properties: none (the same as if they weren't nullable) or sql (wrappers of database/sql's NullString, NullInt64, NullFloat64, NullBool or NullTime that are marshaled as the value or null)").Default("none").EnumVar(&opts.NullType, "none", "sql")
+	kingpin.Flag("split-rw", "generate <Type>Request and <Type>Response variants of each struct without its readOnly and writeOnly properties respectively").BoolVar(&opts.SplitRW)
+	kingpin.Flag("preserve-unknown", "keep the properties of an object that aren't in its struct, so they're marshaled again").BoolVar(&opts.PreserveUnknown)
+	kingpin.Flag("tag-case", "case of the keys in JSON tags: schema (the property names as they are) or camel (lowerCamelCase, e.g. userName for user_name)").Default("schema").EnumVar(&opts.TagCase, "schema", "camel")
+	kingpin.Flag("fixed-arrays", "generate required array properties with equal minItems and maxItems as Go arrays, e.g. [3]T, up to a length of 32").BoolVar(&opts.FixedArrays)
+	kingpin.Flag("unique-sets", "generate arrays with uniqueItems of strings or numbers as sets, map[T]struct{} types with Add and Has methods that are marshaled as sorted JSON arrays").BoolVar(&opts.UniqueSets)
+	kingpin.Flag("inline-depth", "generate nested objects up to this many levels below the root or a definition as anonymous structs rather than named types").Default("0").IntVar(&opts.InlineDepth)
+	kingpin.Flag("field-doc-links", "add a doc link to the type of fields whose type is, or is a slice or map of, a generated struct").BoolVar(&opts.FieldDocLinks)
+	kingpin.Flag("accessors", "generate getters for pointer fields: none, zero (returns the zero value if unset), or commaok (also returns whether it's set)").Default("none").EnumVar(&opts.Accessors, "none", "zero", "commaok")
+	kingpin.Flag("decimal-type", "Go type to use for the decimal and currency formats, e.g. github.com/shopspring/decimal.Decimal; default is float64").PlaceHolder("TYPE").StringVar(&opts.DecimalType)
+	formatMap = kingpin.Flag("format-map", "Go type to use for a string format, as format=type, e.g. ipv4=net/netip.Addr; can be repeated").PlaceHolder("FORMAT=TYPE").StringMap()
+	kingpin.Flag("redact-secrets", "generate String methods that redact writeOnly and x-go-secret fields").Default("false").BoolVar(&opts.RedactSecrets)
+	buildCheck = kingpin.Flag("build-check", "check that the generated files parse and that every identifier in them resolves").Default("false").Bool()
+	kingpin.Flag("verbose", "log how each schema is processed to stderr").Short('v').BoolVar(&opts.Verbose)
+	fileMode = kingpin.Flag("file-mode", "permissions of the output files, in octal").Default("0644").String()
+	lineEndings = kingpin.Flag("line-endings", "line endings of the output files: lf or crlf").Default(lineEndingsLF).Enum(lineEndingsLF, lineEndingsCRLF)
+	kingpin.Flag("strict", "fail on required names that aren't properties rather than warning about them").BoolVar(&opts.Strict)
+	kingpin.Flag("validate", "generate Validate methods that check the schema's constraints").Default("false").BoolVar(&opts.Validate)
+	kingpin.Flag("validate-all", "like --validate, but Validate returns every violation rather than the first").BoolVar(&opts.ValidateAll)
+	kingpin.Flag("wrap-errors", "prefix the errors of nested Validate calls with the path to the value, e.g. items[2].sku").BoolVar(&opts.WrapErrors)
+	kingpin.Flag("validator-tags", "add validate tags for github.com/go-playground/validator with the schema's constraints to struct fields").BoolVar(&opts.ValidatorTags)
+	kingpin.Flag("proto-tags", "add protobuf tags to struct fields, numbered by x-proto-field or else in field order").BoolVar(&opts.ProtoTags)
+	kingpin.Flag("infer", "treat the input file as JSON samples, in an array or one after another, and generate types from a schema inferred from them").BoolVar(&opts.Infer)
+	inputFile = kingpin.Arg("input", "file containing a valid JSON schema").Required().ExistingFile()
+}
+
+const (
+	lineEndingsLF   = "lf"
+	lineEndingsCRLF = "crlf"
+)
+
+// toCRLF returns src with CRLF line endings. Newlines in Go source only
+// appear as line endings, in comments and in raw string literals, whose
+// carriage returns are discarded by the compiler, so this doesn't change what
+// the source means.
+func toCRLF(src []byte) []byte {
+	return bytes.Replace(bytes.Replace(src, []byte("\r\n"), []byte("\n"), -1), []byte("\n"), []byte("\r\n"), -1)
+}
+
+func main() {
+	kingpin.Parse()
+	if !packageSet && *outputDir != "" {
+		pkg, err := inferPackageName(*outputDir)
+		if err != nil {
+			log.Fatalln("Error reading output directory:", err)
+		}
+		opts.PackageName = pkg
+	}
+
+	mode, err := strconv.ParseUint(*fileMode, 8, 32)
+	if err != nil {
+		log.Fatalf("Error parsing file mode %q: %s\n", *fileMode, err)
+	}
+
+	file, err := ioutil.ReadFile(*inputFile)
+	if err != nil {
+		log.Fatalln("Error reading file:", err)
+	}
+	opts.SchemaFile = *inputFile
+	opts.Args = os.Args[1:]
+
+	if len(*formatMap) > 0 {
+		opts.FormatMappers = make(map[string]gen.FormatMapping, len(*formatMap))
+		for format, goType := range *formatMap {
+			opts.FormatMappers[format] = gen.NewFormatMapping(goType)
+		}
+	}
+
+	if *commentsFrom != "" {
+		if opts.Comments, err = ioutil.ReadFile(*commentsFrom); err != nil {
+			log.Fatalln("Error reading comments file:", err)
+		}
+	}
+
+	files, err := gen.Generate(file, opts)
+	if err != nil {
+		log.Fatalln("Error generating types:", err)
+	}
+
+	outDir := ""
+	if *outputDir != "" {
+		outDir = *outputDir + "/"
+	}
+
+	fileNames, _ := stringset.FromMapKeys(files)
+	for _, fileName := range fileNames.Sorted() {
+		outputFileName := outDir + fileName
+		src := files[fileName]
+		if *lineEndings == lineEndingsCRLF {
+			src = toCRLF(src)
+		}
+		err = ioutil.WriteFile(outputFileName, src, os.FileMode(mode))
+		if err != nil {
+			log.Fatalf("Error writing to %s: %s\n", outputFileName, err)
+		}
+	}
+
+	if *buildCheck {
+		outputFiles := make(map[string][]byte, len(files))
+		for fileName, src := range files {
+			outputFiles[outDir+fileName] = src
+		}
+		if err = checkBuild(outputFiles); err != nil {
+			log.Fatalln("Generated code doesn't build:\n" + err.Error())
+		}
+	}
+}
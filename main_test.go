@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/idubinskiy/schematyper/gen"
+)
+
+func TestBuildCheck(t *testing.T) {
+	Convey("Given generated files", t, func() {
+		schema := `{
+			"type": "object",
+			"properties": {
+				"created": {"type": "string", "format": "date-time"},
+				"status": {"enum": ["active", "inactive"]},
+				"child": {"type": "object", "properties": {"name": {"type": "string"}}}
+			}
+		}`
+		files, err := gen.Generate([]byte(schema), gen.Options{RootTypeName: "root"})
+		So(err, ShouldBeNil)
+
+		Convey("When they are correct", func() {
+			Convey("Then the check should pass", func() {
+				So(checkBuild(files), ShouldBeNil)
+			})
+		})
+
+		Convey("When one refers to an undefined type", func() {
+			files["Broken.go"] = []byte("package main\n\ntype Broken struct {\n\tChild Missing\n}\n")
+
+			Convey("Then the check should point at the undefined name", func() {
+				err := checkBuild(files)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "Broken.go:4:8: undefined: Missing")
+			})
+		})
+
+		Convey("When a name is declared twice", func() {
+			files["Broken.go"] = []byte("package main\n\ntype Child string\n")
+
+			Convey("Then the check should report the redeclaration", func() {
+				err := checkBuild(files)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Child.go:")
+				So(err.Error(), ShouldContainSubstring, "Child redeclared")
+			})
+		})
+
+		Convey("When one has a syntax error", func() {
+			files["Broken.go"] = []byte("package main\n\ntype Broken struct {\n")
+
+			Convey("Then the check should report it", func() {
+				err := checkBuild(files)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "Broken.go:")
+			})
+		})
+	})
+}
+
+func TestLineEndings(t *testing.T) {
+	Convey("Given generated source with newlines in string literals", t, func() {
+		schema := `{"type": "object", "properties": {"code": {"type": "string", "pattern": "^a\\nb$"}}}`
+		files, err := gen.Generate([]byte(schema), gen.Options{RootTypeName: "root", Validate: true})
+		So(err, ShouldBeNil)
+		src := files["root.go"]
+		So(string(src), ShouldContainSubstring, `regexp.MustCompile("^a\\nb$")`)
+
+		Convey("When we convert it to CRLF", func() {
+			converted := toCRLF(src)
+
+			Convey("Then every line should end with CRLF", func() {
+				So(bytes.Count(converted, []byte("\r\n")), ShouldEqual, bytes.Count(src, []byte("\n")))
+				So(bytes.Count(converted, []byte("\n")), ShouldEqual, bytes.Count(src, []byte("\n")))
+			})
+
+			Convey("Then the string literals should be unchanged", func() {
+				So(string(converted), ShouldContainSubstring, `regexp.MustCompile("^a\\nb$")`)
+				So(string(bytes.Replace(converted, []byte("\r\n"), []byte("\n"), -1)), ShouldEqual, string(src))
+			})
+
+			Convey("Then converting it again should change nothing", func() {
+				So(toCRLF(converted), ShouldResemble, converted)
+			})
+		})
+	})
+}
+
+func TestInferPackageName(t *testing.T) {
+	newPackageDir := func() (root, pkgDir string) {
+		root, err := ioutil.TempDir("", "schematyper")
+		So(err, ShouldBeNil)
+		pkgDir = filepath.Join(root, "my-models")
+		So(os.Mkdir(pkgDir, 0755), ShouldBeNil)
+		return root, pkgDir
+	}
+
+	Convey("Given an output directory with Go files", t, func() {
+		root, pkgDir := newPackageDir()
+		defer os.RemoveAll(root)
+		So(ioutil.WriteFile(filepath.Join(pkgDir, "a_test.go"), []byte("package models_test\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(pkgDir, "models.go"), []byte("// Package models has the models.\npackage models\n"), 0644), ShouldBeNil)
+
+		Convey("When we infer the package name", func() {
+			pkg, err := inferPackageName(pkgDir)
+
+			Convey("Then it should be read from their package clause", func() {
+				So(err, ShouldBeNil)
+				So(pkg, ShouldEqual, "models")
+			})
+		})
+	})
+
+	Convey("Given an output directory without Go files", t, func() {
+		root, pkgDir := newPackageDir()
+		defer os.RemoveAll(root)
+
+		Convey("When we infer the package name", func() {
+			pkg, err := inferPackageName(pkgDir)
+
+			Convey("Then it should be named after the directory", func() {
+				So(err, ShouldBeNil)
+				So(pkg, ShouldEqual, "mymodels")
+			})
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// packageSet is true if --package is given, rather than left to default.
+var packageSet bool
+
+// inferPackageName returns the package of the Go files in dir, the directory
+// the types are generated into, so --package can be left out when adding to an
+// existing package. Files of an external test package are skipped. If there
+// are no Go files, the package is named after dir, or main if its name isn't
+// a valid package name.
+func inferPackageName(dir string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".go") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	fset := token.NewFileSet()
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err != nil {
+			continue
+		}
+		if pkg := f.Name.Name; !strings.HasSuffix(pkg, "_test") {
+			return pkg, nil
+		}
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	pkg := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, filepath.Base(absDir))
+	if !token.IsIdentifier(pkg) || token.IsKeyword(pkg) {
+		return "main", nil
+	}
+	return pkg, nil
+}